@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yt-mcp-server/service"
+)
+
+// whoamiArgs takes no arguments.
+type whoamiArgs struct{}
+
+// registerAccountTools registers the tools describing the caller's account.
+func (t *youtubeTools) registerAccountTools(registry *ToolRegistry) error {
+	return RegisterTool(registry, Tool{
+		Name:        "whoami",
+		Public:      true,
+		Description: "Shows which Google account the server acts as for you, the Google scopes granted and when the access token expires.",
+		InputSchema: map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+		OutputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"connected":    map[string]interface{}{"type": "boolean"},
+				"user_id":      map[string]interface{}{"type": "string"},
+				"email":        map[string]interface{}{"type": "string"},
+				"scopes":       map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+				"token_expiry": map[string]interface{}{"type": "string", "format": "date-time"},
+				"login_error":  map[string]interface{}{"type": "string"},
+				"client_id":    map[string]interface{}{"type": "string"},
+			},
+			"required": []string{"connected"},
+		},
+		Annotations: &ToolAnnotations{ReadOnlyHint: boolPtr(true), OpenWorldHint: boolPtr(false)},
+	}, t.whoami)
+}
+
+func (t *youtubeTools) whoami(ctx context.Context, args whoamiArgs) (interface{}, error) {
+	status := t.youtubeService.AccountStatus(ctx)
+	return &ToolOutput{Text: renderAccountStatus(status), Data: status}, nil
+}
+
+func renderAccountStatus(status *service.AccountStatus) string {
+	switch {
+	case status.UserID == "":
+		return "Not logged in with Google. Only public data can be read."
+	case status.LoginError != "":
+		return fmt.Sprintf("The Google login of %s can no longer be used: %s. Log in again to continue.", status.Email, status.LoginError)
+	case !status.Connected:
+		return fmt.Sprintf("Authenticated as %s, but no Google account is connected. Log in again to continue.", status.Email)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Connected as %s (Google account %s).", status.Email, status.UserID)
+	if len(status.Scopes) > 0 {
+		fmt.Fprintf(&b, "\nGranted scopes: %s", strings.Join(status.Scopes, ", "))
+	}
+	if status.TokenExpiry != nil {
+		fmt.Fprintf(&b, "\nAccess token expires: %s (refreshed automatically)", status.TokenExpiry.Format(time.RFC3339))
+	}
+	return b.String()
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/yt-mcp-server/service"
+)
+
+func storedToken(user string, scopes ...string) *service.StoredToken {
+	return &service.StoredToken{
+		Token:  &oauth2.Token{AccessToken: user + "-access", RefreshToken: user + "-refresh", Expiry: time.Now().Add(time.Hour)},
+		Email:  user + "@example.com",
+		Scopes: scopes,
+	}
+}
+
+func asPrincipal(user string) context.Context {
+	return service.WithPrincipal(context.Background(), &service.Principal{Subject: user, Email: user + "@example.com"})
+}
+
+func TestWhoamiReportsScopes(t *testing.T) {
+	store := &service.InMemoryTokenStore{}
+	store.SetToken("alice", storedToken("alice", service.ScopeReadOnly, service.ScopeManage))
+	store.SetToken("carol", storedToken("carol", service.ScopeReadOnly))
+	tools := newTestYouTubeTools(t, store, "key")
+
+	result, err := tools.whoami(asPrincipal("alice"), whoamiArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := result.(*ToolOutput)
+	status := output.Data.(*service.AccountStatus)
+	if !status.Connected || status.UserID != "alice" || !reflect.DeepEqual(status.Scopes, []string{service.ScopeReadOnly, service.ScopeManage}) {
+		t.Errorf("whoami = %+v, want alice connected with read and manage scopes", status)
+	}
+	if !strings.Contains(output.Text, "alice@example.com") || !strings.Contains(output.Text, service.ScopeManage) {
+		t.Errorf("whoami text %q does not name the account and its scopes", output.Text)
+	}
+
+	result, _ = tools.whoami(context.Background(), whoamiArgs{})
+	if output := result.(*ToolOutput); !strings.Contains(output.Text, "Not logged in") {
+		t.Errorf("whoami without a login = %q", output.Text)
+	}
+}
+
+func TestLogoutAndStatusEndpoints(t *testing.T) {
+	store := &service.InMemoryTokenStore{}
+	store.SetToken("alice", storedToken("alice", service.ScopeReadOnly))
+	store.SetToken("carol", storedToken("carol", service.ScopeReadOnly, service.ScopeManage))
+	quota, err := service.NewQuotaTracker(0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	google := service.NewGoogleOAuthService(store, "client", "secret", "http://localhost:8080/oauth/callback")
+	youtube := service.NewYouTubeService(google, "", quota, service.NewResponseCache(0))
+	handler := NewOAuthHandler(service.NewOAuthService("http://localhost:8080"), google, youtube)
+
+	status := func(user string) *service.AccountStatus {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.Status(w, httptest.NewRequest(http.MethodGet, "/oauth/status", nil).WithContext(asPrincipal(user)))
+		var status service.AccountStatus
+		if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+			t.Fatalf("status body %q: %v", w.Body.String(), err)
+		}
+		return &status
+	}
+	if got := status("carol"); !got.Connected || !reflect.DeepEqual(got.Scopes, []string{service.ScopeReadOnly, service.ScopeManage}) {
+		t.Errorf("carol's status = %+v, want carol's scopes", got)
+	}
+
+	// The request is cancelled so that no revocation reaches Google; the
+	// token is removed all the same.
+	ctx, cancel := context.WithCancel(asPrincipal("alice"))
+	cancel()
+	w := httptest.NewRecorder()
+	handler.Logout(w, httptest.NewRequest(http.MethodPost, "/oauth/logout", nil).WithContext(ctx))
+	var body map[string]bool
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || !body["logged_out"] || body["google_revoked"] {
+		t.Errorf("logout answered %s, want logged out without the revocation", w.Body.String())
+	}
+
+	if got := status("alice"); got.Connected || len(got.Scopes) != 0 {
+		t.Errorf("alice's status after logging out = %+v", got)
+	}
+	if got := status("carol"); !got.Connected {
+		t.Errorf("carol was logged out by alice: %+v", got)
+	}
+}
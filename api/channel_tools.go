@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/yt-mcp-server/service"
+)
+
+// registerChannelTools registers the channel lookup tools.
+func (t *youtubeTools) registerChannelTools(registry *ToolRegistry) error {
+	readOnly := &ToolAnnotations{ReadOnlyHint: boolPtr(true), OpenWorldHint: boolPtr(true)}
+
+	if err := RegisterTool(registry, Tool{
+		Name:        "get_channel",
+		Public:      true,
+		Cached:      true,
+		Description: "Gets details for a channel by ID, @handle or legacy username, or the authenticated user's own channel. Provide exactly one of channel_id, handle, username or mine.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": channelShape.withShapeArgs(map[string]interface{}{
+				"channel_id": map[string]interface{}{"type": "string", "description": "The channel ID (starts with UC).", "pattern": "^UC[A-Za-z0-9_-]{22}$"},
+				"handle":     map[string]interface{}{"type": "string", "description": "The channel handle, e.g. @GoogleDevelopers.", "pattern": "^@?[A-Za-z0-9._-]{3,30}$"},
+				"username":   map[string]interface{}{"type": "string", "description": "The legacy YouTube username.", "minLength": 1},
+				"mine":       map[string]interface{}{"type": "boolean", "description": "Set to true to get the authenticated user's channel."},
+			}),
+		},
+		OutputSchema: channelShape.itemSchema(),
+		Annotations:  readOnly,
+	}, t.getChannel); err != nil {
+		return err
+	}
+
+	if err := RegisterTool(registry, Tool{
+		Name:        "resolve_channel",
+		Public:      true,
+		Cached:      true,
+		Description: "Resolves an @handle or any youtube.com channel URL (/channel/, /@handle, /user/, /c/) to the channel ID and basic details.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": channelShape.withShapeArgs(map[string]interface{}{
+				"url": map[string]interface{}{"type": "string", "description": "The channel URL, @handle or ID.", "minLength": 1},
+			}),
+			"required": []string{"url"},
+		},
+		OutputSchema: channelShape.itemSchema(),
+		Annotations:  readOnly,
+	}, t.resolveChannel); err != nil {
+		return err
+	}
+
+	if err := RegisterTool(registry, Tool{
+		Name:        "list_channel_uploads",
+		Public:      true,
+		Cached:      true,
+		Description: "Lists a channel's uploaded videos, newest first, one page at a time, or up to max_total videos across pages.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": playlistItemShape.withShapeArgs(map[string]interface{}{
+				"channel_id": map[string]interface{}{"type": "string", "description": "The channel ID (use resolve_channel to get one from a URL or handle).", "pattern": "^UC[A-Za-z0-9_-]{22}$"},
+				"cursor":     cursorSchema,
+				"limit":      map[string]interface{}{"type": "integer", "description": "Optional: Max number of results per page (default: 25). Ignored when max_total is set.", "minimum": 1, "maximum": 50},
+				"max_total":  map[string]interface{}{"type": "integer", "description": "Optional: Follow pages until this many videos are collected (each page costs quota). Takes precedence over limit.", "minimum": 1, "maximum": 1000},
+			}),
+			"required": []string{"channel_id"},
+		},
+		OutputSchema: playlistItemShape.listSchema(),
+		Annotations:  readOnly,
+	}, t.listChannelUploads); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type getChannelArgs struct {
+	ChannelID string `json:"channel_id"`
+	Handle    string `json:"handle"`
+	Username  string `json:"username"`
+	Mine      bool   `json:"mine"`
+	shapeArgs
+}
+
+func (t *youtubeTools) getChannel(ctx context.Context, args getChannelArgs) (interface{}, error) {
+	set := 0
+	for _, given := range []bool{args.ChannelID != "", args.Handle != "", args.Username != "", args.Mine} {
+		if given {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, &InvalidArgumentsError{Err: errors.New("provide exactly one of channel_id, handle, username or mine")}
+	}
+	// The tool is public, so it may be called with the API key alone, which
+	// has no channel of its own.
+	if args.Mine && !t.youtubeService.HasUserAccess(ctx) {
+		return nil, &ToolError{Code: "login_required", Message: "mine needs a Google login to know whose channel to get. Look the channel up by channel_id, handle or username instead."}
+	}
+
+	channel, err := t.youtubeService.GetChannel(ctx, service.ChannelQuery{
+		ID:       args.ChannelID,
+		Handle:   args.Handle,
+		Username: args.Username,
+		Mine:     args.Mine,
+	})
+	if err != nil {
+		return nil, channelError(err)
+	}
+	return channelShape.one(newChannelSummary(channel), args.shapeArgs)
+}
+
+type resolveChannelArgs struct {
+	URL string `json:"url"`
+	shapeArgs
+}
+
+func (t *youtubeTools) resolveChannel(ctx context.Context, args resolveChannelArgs) (interface{}, error) {
+	channel, err := t.youtubeService.ResolveChannel(ctx, args.URL)
+	if err != nil {
+		return nil, channelError(err)
+	}
+	// Resolving is mostly about the ID, so default to the minimal view.
+	if args.Verbosity == "" {
+		args.Verbosity = verbosityMinimal
+	}
+	return channelShape.one(newChannelSummary(channel), args.shapeArgs)
+}
+
+type listChannelUploadsArgs struct {
+	ChannelID string `json:"channel_id"`
+	Cursor    string `json:"cursor"`
+	Limit     int64  `json:"limit"`
+	MaxTotal  int64  `json:"max_total"`
+	shapeArgs
+}
+
+func (t *youtubeTools) listChannelUploads(ctx context.Context, args listChannelUploadsArgs) (interface{}, error) {
+	if args.Limit == 0 {
+		args.Limit = 25
+	}
+
+	scope := newCursorScope("list_channel_uploads", args.ChannelID)
+	page, err := pageRequest(scope, args.Cursor, args.Limit, args.MaxTotal, 50)
+	if err != nil {
+		return nil, err
+	}
+
+	uploads, err := t.youtubeService.ListChannelUploads(ctx, args.ChannelID, page)
+	if err != nil {
+		return nil, channelError(err)
+	}
+	return playlistItemList(uploads, args.shapeArgs, scope)
+}
+
+func channelError(err error) error {
+	if errors.Is(err, service.ErrInvalidChannelRef) {
+		return &InvalidArgumentsError{Err: err}
+	}
+	if errors.Is(err, service.ErrChannelNotFound) {
+		return &ToolError{Code: string(service.ErrCodeNotFound), Message: "No channel matches the given reference."}
+	}
+	return apiError(err)
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yt-mcp-server/service"
+)
+
+func newTestYouTubeTools(t *testing.T, store service.TokenStore, apiKey string) *youtubeTools {
+	t.Helper()
+	quota, err := service.NewQuotaTracker(0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	google := service.NewGoogleOAuthService(store, "client", "secret", "http://localhost:8080/oauth/callback")
+	return &youtubeTools{youtubeService: service.NewYouTubeService(google, apiKey, quota, service.NewResponseCache(0))}
+}
+
+func TestGetChannelMineNeedsLogin(t *testing.T) {
+	tools := newTestYouTubeTools(t, &service.InMemoryTokenStore{}, "key")
+
+	for name, ctx := range map[string]context.Context{
+		"API key only":  context.Background(),
+		"without token": service.WithPrincipal(context.Background(), &service.Principal{Subject: "bob"}),
+	} {
+		_, err := tools.getChannel(ctx, getChannelArgs{Mine: true})
+		var toolErr *ToolError
+		if !errors.As(err, &toolErr) || toolErr.Code != "login_required" {
+			t.Errorf("%s: err = %v, want a login_required tool error", name, err)
+		}
+	}
+}
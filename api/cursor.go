@@ -0,0 +1,68 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// cursorSchema is the input schema fragment for MCP-level pagination.
+var cursorSchema = map[string]interface{}{
+	"type":        "string",
+	"description": "Optional: nextCursor from a previous call, to continue where it left off.",
+}
+
+// cursorScope identifies the result set a cursor belongs to: the tool that
+// issued it and a hash of the arguments that select the results. A cursor
+// is only accepted by a call with the same scope, so that it cannot be
+// replayed against an unrelated query.
+type cursorScope string
+
+// newCursorScope returns the scope of a call to tool whose results are
+// selected by the given argument values, in a fixed order.
+func newCursorScope(tool string, identity ...string) cursorScope {
+	h := sha256.New()
+	for _, part := range append([]string{tool}, identity...) {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return cursorScope(tool + ":" + hex.EncodeToString(h.Sum(nil)[:8]))
+}
+
+func (s cursorScope) tool() string {
+	tool, _, _ := strings.Cut(string(s), ":")
+	return tool
+}
+
+// encodeCursor wraps a YouTube page token in an opaque MCP cursor bound to
+// scope.
+func encodeCursor(scope cursorScope, pageToken string) string {
+	if pageToken == "" {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(string(scope) + "|" + pageToken))
+}
+
+// decodeCursor recovers the YouTube page token from a cursor issued for
+// scope. An invalid cursor, or one issued by another tool or for other
+// arguments, is reported as invalid arguments.
+func decodeCursor(scope cursorScope, cursor string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	issued, pageToken, ok := strings.Cut(string(data), "|")
+	if err != nil || !ok || pageToken == "" {
+		return "", &InvalidArgumentsError{Err: errors.New("cursor is not a value returned as nextCursor")}
+	}
+	if issued := cursorScope(issued); issued != scope {
+		if issued.tool() != scope.tool() {
+			return "", &InvalidArgumentsError{Err: fmt.Errorf("cursor was returned by %s, not %s", issued.tool(), scope.tool())}
+		}
+		return "", &InvalidArgumentsError{Err: errors.New("cursor was returned for different arguments; repeat the original arguments or drop the cursor")}
+	}
+	return pageToken, nil
+}
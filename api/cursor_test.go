@@ -0,0 +1,56 @@
+package api
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	scope := newCursorScope("search_videos", "golang", "")
+	cursor := encodeCursor(scope, "CAoQAA")
+	if cursor == "" {
+		t.Fatal("encodeCursor returned an empty cursor")
+	}
+	pageToken, err := decodeCursor(scope, cursor)
+	if err != nil {
+		t.Fatalf("decodeCursor: %v", err)
+	}
+	if pageToken != "CAoQAA" {
+		t.Errorf("page token = %q, want CAoQAA", pageToken)
+	}
+
+	if encodeCursor(scope, "") != "" {
+		t.Error("the last page must not get a cursor")
+	}
+	if pageToken, err := decodeCursor(scope, ""); err != nil || pageToken != "" {
+		t.Errorf("decodeCursor(\"\") = %q, %v; want the first page", pageToken, err)
+	}
+}
+
+func TestCursorRejectsOtherScopes(t *testing.T) {
+	issued := newCursorScope("search_videos", "golang", "")
+	cursor := encodeCursor(issued, "CAoQAA")
+
+	tests := []struct {
+		name   string
+		scope  cursorScope
+		cursor string
+	}{
+		{"other query", newCursorScope("search_videos", "rust", ""), cursor},
+		{"other channel", newCursorScope("search_videos", "golang", "UC_x5XG1OV2P6uZZ5FSM9Ttw"), cursor},
+		{"arguments shifted", newCursorScope("search_videos", "golan", "g"), cursor},
+		{"other tool", newCursorScope("get_video_comments", "golang", ""), cursor},
+		{"not base64", issued, "!!!"},
+		{"bare page token", issued, "Q0FvUUFB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeCursor(tt.scope, tt.cursor)
+			var invalid *InvalidArgumentsError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("decodeCursor error = %v, want *InvalidArgumentsError", err)
+			}
+		})
+	}
+}
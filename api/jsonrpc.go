@@ -0,0 +1,220 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+)
+
+// rpcEnvelope is the raw shape of an incoming JSON-RPC message, kept loose so
+// that requests, notifications and client responses can be told apart and
+// validated before they are turned into an MCPRequest.
+type rpcEnvelope struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	Result  json.RawMessage `json:"result"`
+	Error   json.RawMessage `json:"error"`
+}
+
+// parseMessages decodes a body holding either a single JSON-RPC message or a
+// batch. Valid requests and notifications are returned in reqs; messages that
+// are not valid requests yield an Invalid Request response in rejected.
+// Responses sent by the client are accepted and dropped, since the server
+// never issues requests of its own. A non-nil fatal response means the body
+// could not be used at all and should be sent back on its own.
+func parseMessages(body []byte) (reqs []*MCPRequest, rejected []*MCPResponse, batch bool, fatal *MCPResponse) {
+	body = bytes.TrimSpace(body)
+	if !json.Valid(body) {
+		return nil, nil, false, errorResponse(nil, -32700, "Parse error", nil)
+	}
+
+	var raws []json.RawMessage
+	if len(body) > 0 && body[0] == '[' {
+		batch = true
+		if err := json.Unmarshal(body, &raws); err != nil {
+			return nil, nil, true, errorResponse(nil, -32700, "Parse error", nil)
+		}
+		if len(raws) == 0 {
+			return nil, nil, true, errorResponse(nil, -32600, "Invalid Request", "empty batch")
+		}
+	} else {
+		raws = []json.RawMessage{body}
+	}
+
+	for _, raw := range raws {
+		req, errResp := parseMessage(raw)
+		if errResp != nil {
+			rejected = append(rejected, errResp)
+		} else if req != nil {
+			reqs = append(reqs, req)
+		}
+	}
+	return reqs, rejected, batch, nil
+}
+
+// parseMessage validates a single message. It returns (nil, nil) for
+// responses from the client.
+func parseMessage(raw json.RawMessage) (*MCPRequest, *MCPResponse) {
+	var env rpcEnvelope
+	if err := json.Unmarshal(raw, &env); err != nil {
+		return nil, errorResponse(nil, -32600, "Invalid Request", "message must be a JSON object")
+	}
+
+	id, idErr := parseID(env.ID)
+	if env.JSONRPC != "2.0" {
+		return nil, errorResponse(id, -32600, "Invalid Request", `jsonrpc must be "2.0"`)
+	}
+
+	if env.Method == "" {
+		if len(env.ID) > 0 && (len(env.Result) > 0 || len(env.Error) > 0) {
+			return nil, nil
+		}
+		return nil, errorResponse(id, -32600, "Invalid Request", "method is required")
+	}
+
+	if idErr != "" {
+		return nil, errorResponse(nil, -32600, "Invalid Request", idErr)
+	}
+
+	req := &MCPRequest{
+		JSONRPC:      env.JSONRPC,
+		ID:           id,
+		Method:       env.Method,
+		notification: len(env.ID) == 0,
+	}
+	if len(env.Params) > 0 && !bytes.Equal(env.Params, []byte("null")) {
+		if c := env.Params[0]; c != '{' && c != '[' {
+			return nil, errorResponse(id, -32600, "Invalid Request", "params must be an object or array")
+		}
+		var params interface{}
+		decoder := json.NewDecoder(bytes.NewReader(env.Params))
+		decoder.UseNumber()
+		if err := decoder.Decode(&params); err != nil {
+			return nil, errorResponse(id, -32600, "Invalid Request", "params must be an object or array")
+		}
+		req.Params = params
+	}
+	return req, nil
+}
+
+// parseID converts a raw JSON-RPC id to the value echoed back in responses.
+// MCP only allows strings and numbers; numbers are kept as json.Number so
+// they round-trip exactly.
+func parseID(raw json.RawMessage) (interface{}, string) {
+	if len(raw) == 0 {
+		return nil, ""
+	}
+	switch c := raw[0]; {
+	case c == '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, "id must be a string or number"
+		}
+		return s, ""
+	case c == '-' || (c >= '0' && c <= '9'):
+		return json.Number(raw), ""
+	case bytes.Equal(raw, []byte("null")):
+		return nil, "id must not be null"
+	default:
+		return nil, "id must be a string or number"
+	}
+}
+
+// handleRequests dispatches every request and returns the responses for
+// those that expect one. initialize is handled first, on its own, since the
+// rest of the batch depends on it; everything else runs concurrently.
+func (h *MCPHandler) handleRequests(ctx context.Context, reqs []*MCPRequest) []*MCPResponse {
+	responses := make([]*MCPResponse, len(reqs))
+
+	for i, req := range reqs {
+		if req.Method == "initialize" {
+			responses[i] = h.handleRequest(ctx, req)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		if req.Method == "initialize" {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i] = h.handleRequest(ctx, req)
+		}()
+	}
+	wg.Wait()
+
+	var out []*MCPResponse
+	for _, resp := range responses {
+		if resp != nil {
+			out = append(out, resp)
+		}
+	}
+	return out
+}
+
+// handleRequest dispatches a single request, returning nil for
+// notifications and for requests the client cancelled meanwhile.
+func (h *MCPHandler) handleRequest(ctx context.Context, req *MCPRequest) *MCPResponse {
+	if req.IsNotification() {
+		h.handleNotification(ctx, req)
+		return nil
+	}
+
+	// initialize must not be cancelled.
+	if inflight := inflightFromContext(ctx); inflight != nil && req.Method != "initialize" {
+		var done func()
+		ctx, done = inflight.start(ctx, req.ID)
+		defer done()
+	}
+	resp := h.dispatch(ctx, req)
+	if requestCancelled(ctx) {
+		return nil
+	}
+	return resp
+}
+
+// handleNotification processes a notification from the client. Notifications
+// never get a reply, not even an error.
+func (h *MCPHandler) handleNotification(ctx context.Context, req *MCPRequest) {
+	switch req.Method {
+	case "notifications/initialized":
+		// Nothing to do: the session is usable as soon as initialize is answered.
+	case "notifications/cancelled":
+		h.handleCancelled(ctx, req)
+	default:
+		log.Printf("Ignoring unknown notification %q", req.Method)
+	}
+}
+
+// cancelledParams are the params of notifications/cancelled.
+type cancelledParams struct {
+	RequestID json.RawMessage `json:"requestId"`
+	Reason    string          `json:"reason"`
+}
+
+// handleCancelled stops the in-flight request named by a
+// notifications/cancelled. Requests that already finished, or are unknown,
+// are ignored, as the notification may cross the response on the wire.
+func (h *MCPHandler) handleCancelled(ctx context.Context, req *MCPRequest) {
+	var params cancelledParams
+	if err := h.decodeParams(req.Params, &params); err != nil {
+		log.Printf("Ignoring malformed notifications/cancelled: %v", err)
+		return
+	}
+	id, idErr := parseID(params.RequestID)
+	if idErr != "" || id == nil {
+		log.Printf("Ignoring notifications/cancelled without a valid requestId")
+		return
+	}
+
+	inflight := inflightFromContext(ctx)
+	if inflight != nil && inflight.cancel(id) {
+		log.Printf("Cancelled request %s: %s", requestKey(id), params.Reason)
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMessages(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantFatal    int
+		wantReqs     int
+		wantRejected []int
+		wantBatch    bool
+	}{
+		{name: "single request", body: `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, wantReqs: 1},
+		{name: "not json", body: `{"jsonrpc":`, wantFatal: -32700},
+		{name: "empty batch", body: `[]`, wantFatal: -32600, wantBatch: true},
+		{name: "batch of notifications", body: `[{"jsonrpc":"2.0","method":"notifications/initialized"},{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":1}}]`, wantReqs: 2, wantBatch: true},
+		{name: "invalid id type", body: `{"jsonrpc":"2.0","id":{"a":1},"method":"tools/list"}`, wantRejected: []int{-32600}},
+		{name: "null id", body: `{"jsonrpc":"2.0","id":null,"method":"tools/list"}`, wantRejected: []int{-32600}},
+		{name: "wrong version", body: `{"jsonrpc":"1.0","id":1,"method":"tools/list"}`, wantRejected: []int{-32600}},
+		{name: "scalar params", body: `{"jsonrpc":"2.0","id":1,"method":"tools/list","params":3}`, wantRejected: []int{-32600}},
+		{name: "client response dropped", body: `{"jsonrpc":"2.0","id":1,"result":{}}`},
+		{name: "mixed batch", body: `[{"jsonrpc":"2.0","id":1,"method":"tools/list"},5]`, wantReqs: 1, wantRejected: []int{-32600}, wantBatch: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqs, rejected, batch, fatal := parseMessages([]byte(tt.body))
+			if tt.wantFatal != 0 {
+				if fatal == nil || fatal.Error.Code != tt.wantFatal {
+					t.Fatalf("fatal = %+v, want code %d", fatal, tt.wantFatal)
+				}
+				return
+			}
+			if fatal != nil {
+				t.Fatalf("unexpected fatal response %+v", fatal.Error)
+			}
+			if batch != tt.wantBatch {
+				t.Errorf("batch = %v, want %v", batch, tt.wantBatch)
+			}
+			if len(reqs) != tt.wantReqs {
+				t.Errorf("got %d requests, want %d", len(reqs), tt.wantReqs)
+			}
+			if len(rejected) != len(tt.wantRejected) {
+				t.Fatalf("got %d rejected, want %d", len(rejected), len(tt.wantRejected))
+			}
+			for i, code := range tt.wantRejected {
+				if rejected[i].Error.Code != code {
+					t.Errorf("rejected[%d] code = %d, want %d", i, rejected[i].Error.Code, code)
+				}
+			}
+		})
+	}
+}
+
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    interface{}
+		wantErr bool
+	}{
+		{raw: ``, want: nil},
+		{raw: `"abc"`, want: "abc"},
+		{raw: `42`, want: json.Number("42")},
+		{raw: `-1.5`, want: json.Number("-1.5")},
+		{raw: `null`, wantErr: true},
+		{raw: `true`, wantErr: true},
+		{raw: `[1]`, wantErr: true},
+		{raw: `{"id":1}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			id, errMsg := parseID(json.RawMessage(tt.raw))
+			if (errMsg != "") != tt.wantErr {
+				t.Fatalf("parseID(%s) error = %q, wantErr %v", tt.raw, errMsg, tt.wantErr)
+			}
+			if !tt.wantErr && id != tt.want {
+				t.Errorf("parseID(%s) = %#v, want %#v", tt.raw, id, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotificationsGetNoReply(t *testing.T) {
+	handler := NewMCPHandler(NewToolRegistry(), nil)
+	reqs, _, _, fatal := parseMessages([]byte(`[
+		{"jsonrpc":"2.0","method":"notifications/initialized"},
+		{"jsonrpc":"2.0","method":"notifications/unknown"},
+		{"jsonrpc":"2.0","method":"tools/list"},
+		{"jsonrpc":"2.0","id":"a","method":"tools/list"}
+	]`))
+	if fatal != nil {
+		t.Fatalf("unexpected fatal response %+v", fatal.Error)
+	}
+	for _, req := range reqs[:3] {
+		if !req.IsNotification() {
+			t.Fatalf("%s without an id is not a notification", req.Method)
+		}
+	}
+
+	responses := handler.handleRequests(context.Background(), reqs)
+	if len(responses) != 1 || responses[0].ID != "a" {
+		t.Fatalf("got responses %+v, want only the reply to id \"a\"", responses)
+	}
+}
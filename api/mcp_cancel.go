@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// errRequestCancelled is the cause of requests the client cancelled with
+// notifications/cancelled.
+var errRequestCancelled = errors.New("request cancelled by the client")
+
+// inflightRequests tracks the requests being handled for one client, keyed
+// by JSON-RPC id, so that a notifications/cancelled can stop them. HTTP
+// sessions and stdio connections each have their own, as request ids are
+// only unique per client.
+type inflightRequests struct {
+	mu       sync.Mutex
+	requests map[string]*inflightRequest
+}
+
+type inflightRequest struct {
+	cancel context.CancelCauseFunc
+}
+
+func newInflightRequests() *inflightRequests {
+	return &inflightRequests{requests: make(map[string]*inflightRequest)}
+}
+
+// start registers a request and returns its cancellable context, and a
+// function to call once the request has been answered.
+func (r *inflightRequests) start(ctx context.Context, id interface{}) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	key := requestKey(id)
+	request := &inflightRequest{cancel: cancel}
+
+	r.mu.Lock()
+	r.requests[key] = request
+	r.mu.Unlock()
+
+	return ctx, func() {
+		r.mu.Lock()
+		if r.requests[key] == request {
+			delete(r.requests, key)
+		}
+		r.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// cancel cancels the request with the given id, reporting whether it was
+// still in flight.
+func (r *inflightRequests) cancel(id interface{}) bool {
+	r.mu.Lock()
+	request, ok := r.requests[requestKey(id)]
+	r.mu.Unlock()
+	if ok {
+		request.cancel(errRequestCancelled)
+	}
+	return ok
+}
+
+// requestKey identifies a request id, keeping the string "1" and the
+// number 1 apart.
+func requestKey(id interface{}) string {
+	key, _ := json.Marshal(id)
+	return string(key)
+}
+
+type inflightContextKey struct{}
+
+// withInflight attaches the client's in-flight request tracker to a context.
+func withInflight(ctx context.Context, inflight *inflightRequests) context.Context {
+	return context.WithValue(ctx, inflightContextKey{}, inflight)
+}
+
+func inflightFromContext(ctx context.Context) *inflightRequests {
+	inflight, _ := ctx.Value(inflightContextKey{}).(*inflightRequests)
+	return inflight
+}
+
+// requestCancelled reports whether the client cancelled the request of ctx,
+// in which case no response must be sent.
+func requestCancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errRequestCancelled)
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitTool is a tool that runs until it is released or its request is
+// cancelled, and reports which happened.
+type waitTool struct {
+	started chan struct{}
+	release chan struct{}
+	// causes receives the cause of the tool's context when it returns, or
+	// nil if it was released.
+	causes chan error
+}
+
+func newWaitTool(t *testing.T) (*ToolRegistry, *waitTool) {
+	t.Helper()
+	tool := &waitTool{
+		started: make(chan struct{}, 10),
+		release: make(chan struct{}),
+		causes:  make(chan error, 10),
+	}
+	registry := NewToolRegistry()
+	err := registry.Register(Tool{Name: "wait"}, func(ctx context.Context, _ map[string]interface{}) (interface{}, error) {
+		tool.started <- struct{}{}
+		select {
+		case <-ctx.Done():
+			tool.causes <- context.Cause(ctx)
+			return nil, ctx.Err()
+		case <-tool.release:
+			tool.causes <- nil
+			return map[string]interface{}{"released": true}, nil
+		}
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return registry, tool
+}
+
+func (w *waitTool) waitStarted(t *testing.T) {
+	t.Helper()
+	select {
+	case <-w.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("tool did not start")
+	}
+}
+
+func (w *waitTool) waitCause(t *testing.T) error {
+	t.Helper()
+	select {
+	case cause := <-w.causes:
+		return cause
+	case <-time.After(5 * time.Second):
+		t.Fatal("tool did not return")
+		return nil
+	}
+}
+
+const (
+	waitCall       = `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"wait"}}`
+	cancelWaitCall = `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":7,"reason":"user aborted"}}`
+)
+
+func TestCancelHTTPRequest(t *testing.T) {
+	registry, tool := newWaitTool(t)
+	server := serveTestMCP(t, registry)
+	sessionID := initializeSession(t, server)
+
+	responses := make(chan *http.Response, 1)
+	go func() {
+		responses <- mcpRequest(t, server, http.MethodPost, sessionID, waitCall, nil)
+	}()
+	tool.waitStarted(t)
+
+	resp := mcpRequest(t, server, http.MethodPost, sessionID, cancelWaitCall, nil)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("cancel notification: status = %d, want 202", resp.StatusCode)
+	}
+	if cause := tool.waitCause(t); !errors.Is(cause, errRequestCancelled) {
+		t.Fatalf("tool context ended with %v, want the request cancelled", cause)
+	}
+
+	resp = <-responses
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusAccepted || len(body) != 0 {
+		t.Errorf("cancelled request was answered with %d %q, want 202 and no response", resp.StatusCode, body)
+	}
+}
+
+func TestCancelIsScopedToSession(t *testing.T) {
+	registry, tool := newWaitTool(t)
+	server := serveTestMCP(t, registry)
+	owner := initializeSession(t, server)
+	other := initializeSession(t, server)
+
+	responses := make(chan *http.Response, 1)
+	go func() {
+		responses <- mcpRequest(t, server, http.MethodPost, owner, waitCall, nil)
+	}()
+	tool.waitStarted(t)
+
+	// Another session uses the same request id.
+	mcpRequest(t, server, http.MethodPost, other, cancelWaitCall, nil)
+	close(tool.release)
+	if cause := tool.waitCause(t); cause != nil {
+		t.Fatalf("another session cancelled the request: %v", cause)
+	}
+
+	resp := <-responses
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"id":7`) {
+		t.Errorf("request was answered with %d %q, want its result", resp.StatusCode, body)
+	}
+}
+
+func TestCancelStdioRequest(t *testing.T) {
+	registry, tool := newWaitTool(t)
+	handler := NewMCPHandler(registry, nil)
+	first := serveTestStdio(t, handler)
+	second := serveTestStdio(t, handler)
+
+	first.send(t, waitCall)
+	tool.waitStarted(t)
+
+	// Request ids are per connection: the same id elsewhere is not affected.
+	second.send(t, cancelWaitCall)
+	second.in.Close()
+	if line := second.next(t); line != "" {
+		t.Errorf("cancel notification was answered with %s", line)
+	}
+	select {
+	case cause := <-tool.causes:
+		t.Fatalf("another connection cancelled the request: %v", cause)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	first.send(t, cancelWaitCall)
+	if cause := tool.waitCause(t); !errors.Is(cause, errRequestCancelled) {
+		t.Fatalf("tool context ended with %v, want the request cancelled", cause)
+	}
+
+	// The cancelled request gets no response: the next line answers the
+	// request sent after it.
+	first.send(t, `{"jsonrpc":"2.0","id":8,"method":"tools/list"}`)
+	var resp MCPResponse
+	if err := json.Unmarshal([]byte(first.next(t)), &resp); err != nil || resp.ID != float64(8) {
+		t.Errorf("got response %+v (%v), want only the one to request 8", resp, err)
+	}
+	first.in.Close()
+	if line := first.next(t); line != "" {
+		t.Errorf("unexpected output %s", line)
+	}
+}
+
+func TestCancelUnknownOrFinishedRequest(t *testing.T) {
+	inflight := newInflightRequests()
+	if inflight.cancel(float64(1)) {
+		t.Error("cancelled an unknown request")
+	}
+
+	ctx, done := inflight.start(context.Background(), float64(1))
+	done()
+	if inflight.cancel(float64(1)) {
+		t.Error("cancelled a finished request")
+	}
+	if requestCancelled(ctx) {
+		t.Error("finished request reported as cancelled")
+	}
+
+	// The string "1" is a different id than the number 1.
+	ctx, done = inflight.start(context.Background(), float64(1))
+	defer done()
+	if inflight.cancel("1") {
+		t.Error(`cancelling id "1" matched id 1`)
+	}
+	if ctx.Err() != nil {
+		t.Error("request was cancelled by another id")
+	}
+
+	// A cancellation for a finished or unknown id is a no-op notification.
+	handler := NewMCPHandler(NewToolRegistry(), nil)
+	reqs, _, _, _ := parseMessages([]byte(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":2}}`))
+	responses := handler.handleRequests(withInflight(context.Background(), inflight), reqs)
+	if len(responses) != 0 || ctx.Err() != nil {
+		t.Errorf("cancelling an unknown id answered %+v or cancelled another request", responses)
+	}
+}
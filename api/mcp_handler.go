@@ -2,33 +2,41 @@ package api
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
 
 	"github.com/yt-mcp-server/service"
-	
 )
 
 // MCPHandler handles all MCP protocol requests.
 type MCPHandler struct {
-	youtubeService *service.YouTubeService
+	tools    *ToolRegistry
+	sessions *SessionManager
 }
 
-// NewMCPHandler creates a new MCPHandler.
-func NewMCPHandler(youtubeService *service.YouTubeService) *MCPHandler {
+// NewMCPHandler creates a new MCPHandler serving the tools in the registry.
+func NewMCPHandler(tools *ToolRegistry, sessions *SessionManager) *MCPHandler {
 	return &MCPHandler{
-		youtubeService: youtubeService,
+		tools:    tools,
+		sessions: sessions,
 	}
 }
 
-// ... (rest of the MCP struct definitions are the same)
-
 type MCPRequest struct {
 	JSONRPC string      `json:"jsonrpc"`
 	ID      interface{} `json:"id"`
 	Method  string      `json:"method"`
 	Params  interface{} `json:"params,omitempty"`
+
+	notification bool
+}
+
+// IsNotification reports whether the request was sent without an id, in
+// which case no response must be sent.
+func (r *MCPRequest) IsNotification() bool {
+	return r.notification
 }
 
 type MCPResponse struct {
@@ -38,6 +46,12 @@ type MCPResponse struct {
 	Error   *MCPError   `json:"error,omitempty"`
 }
 
+type MCPNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
 type MCPError struct {
 	Code    int         `json:"code"`
 	Message string      `json:"message"`
@@ -55,176 +69,134 @@ type ToolsListResult struct {
 }
 
 type Tool struct {
-	Name        string                 `json:"name"`
-	Description string                 `json:"description"`
-	InputSchema map[string]interface{} `json:"inputSchema"`
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description"`
+	InputSchema  map[string]interface{} `json:"inputSchema"`
+	OutputSchema map[string]interface{} `json:"outputSchema,omitempty"`
+	Annotations  *ToolAnnotations       `json:"annotations,omitempty"`
+
+	// Public tools only read public data, so they can be offered without a
+	// Google login when an API key is configured.
+	Public bool `json:"-"`
+	// Cached tools answer from the response cache and accept a no_cache
+	// argument to read past it.
+	Cached bool `json:"-"`
 }
 
 type ToolsCallParams struct {
 	Name      string                 `json:"name"`
 	Arguments map[string]interface{} `json:"arguments"`
+	Meta      *RequestMeta           `json:"_meta,omitempty"`
 }
 
 type ToolsCallResult struct {
-	Content []ContentItem `json:"content"`
-	IsError bool          `json:"isError,omitempty"`
+	Content           []ContentItem `json:"content"`
+	StructuredContent interface{}   `json:"structuredContent,omitempty"`
+	IsError           bool          `json:"isError,omitempty"`
+	// Meta carries the code of a *ToolError.
+	Meta map[string]interface{} `json:"_meta,omitempty"`
 }
 
+// ContentItem is a text, image or resource_link content block of a tool result.
 type ContentItem struct {
-	Type string      `json:"type"`
-	Text string      `json:"text,omitempty"`
-	JSON interface{} `json:"json,omitempty"`
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+
+	// Data is the base64-encoded payload of an image.
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+
+	// URI, Name and Description describe a resource_link.
+	URI         string `json:"uri,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
 }
 
-// HandleMCP handles all MCP protocol requests.
-func (h *MCPHandler) HandleMCP(w http.ResponseWriter, r *http.Request) {
-	var req MCPRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendErrorResponse(w, req.ID, -32700, "Parse error", nil)
-		return
-	}
+// TextContent returns a text content block.
+func TextContent(text string) ContentItem {
+	return ContentItem{Type: "text", Text: text}
+}
+
+// ImageContent returns an image content block holding data.
+func ImageContent(data []byte, mimeType string) ContentItem {
+	return ContentItem{Type: "image", Data: base64.StdEncoding.EncodeToString(data), MimeType: mimeType}
+}
 
+// ResourceLinkContent returns a resource_link content block pointing at uri.
+func ResourceLinkContent(uri, name, description string) ContentItem {
+	return ContentItem{Type: "resource_link", URI: uri, Name: name, Description: description}
+}
+
+// dispatch routes a single JSON-RPC request to its method handler. It is
+// shared by every transport and never writes to the wire itself.
+func (h *MCPHandler) dispatch(ctx context.Context, req *MCPRequest) *MCPResponse {
 	switch req.Method {
 	case "initialize":
-		h.handleInitialize(w, &req)
+		return h.handleInitialize(req)
 	case "tools/list":
-		h.handleToolsList(w, &req)
+		return h.handleToolsList(ctx, req)
 	case "tools/call":
-		h.handleToolsCall(w, &req)
+		return h.handleToolsCall(ctx, req)
 	default:
-		h.sendErrorResponse(w, req.ID, -32601, "Method not found", nil)
+		return errorResponse(req.ID, -32601, "Method not found", nil)
 	}
 }
 
-func (h *MCPHandler) handleInitialize(w http.ResponseWriter, req *MCPRequest) {
+func (h *MCPHandler) handleInitialize(req *MCPRequest) *MCPResponse {
 	result := InitializeResult{
-		ProtocolVersion: "2025-06-18",
+		ProtocolVersion: supportedProtocolVersions[0],
 		Capabilities:    map[string]interface{}{"tools": map[string]interface{}{}},
 		ServerInfo:      map[string]string{"name": "youtube-toolkit-server", "version": "2.0.0"},
 	}
-	h.sendSuccessResponse(w, req.ID, result)
+	return successResponse(req.ID, result)
 }
 
-func (h *MCPHandler) handleToolsList(w http.ResponseWriter, req *MCPRequest) {
-	tools := []Tool{
-		{
-			Name:        "get_video_metadata",
-			Description: "Gets detailed information for a specific video.",
-			InputSchema: map[string]interface{}{
-				"type":       "object",
-				"properties": map[string]interface{}{"video_id": map[string]interface{}{"type": "string", "description": "The ID of the YouTube video."}},
-				"required":   []string{"video_id"},
-			},
-		},
-		{
-			Name:        "search_videos",
-			Description: "Searches for YouTube videos.",
-			InputSchema: map[string]interface{}{
-				"type":       "object",
-				"properties": map[string]interface{}{
-					"query":      map[string]interface{}{"type": "string", "description": "The search term."},
-					"channel_id": map[string]interface{}{"type": "string", "description": "Optional: Restricts search to a specific channel."},
-					"limit":      map[string]interface{}{"type": "integer", "description": "Optional: Max number of results (default: 10)."},
-				},
-				"required":   []string{"query"},
-			},
-		},
-		{
-			Name:        "get_video_comments",
-			Description: "Fetches top-level comment threads for a video.",
-			InputSchema: map[string]interface{}{
-				"type":       "object",
-				"properties": map[string]interface{}{
-					"video_id": map[string]interface{}{"type": "string", "description": "The ID of the YouTube video."},
-					"sort_by":  map[string]interface{}{"type": "string", "description": "Optional: Sort order (default: top)."},
-					"limit":    map[string]interface{}{"type": "integer", "description": "Optional: Max number of results (default: 20)."},
-				},
-				"required":   []string{"video_id"},
-			},
-		},
-	}
-	result := ToolsListResult{Tools: tools}
-	h.sendSuccessResponse(w, req.ID, result)
+func (h *MCPHandler) handleToolsList(ctx context.Context, req *MCPRequest) *MCPResponse {
+	result := ToolsListResult{Tools: h.tools.Available(ctx)}
+	return successResponse(req.ID, result)
 }
 
-func (h *MCPHandler) handleToolsCall(w http.ResponseWriter, req *MCPRequest) {
+func (h *MCPHandler) handleToolsCall(ctx context.Context, req *MCPRequest) *MCPResponse {
 	var toolParams ToolsCallParams
 	if err := h.decodeParams(req.Params, &toolParams); err != nil {
-		h.sendErrorResponse(w, req.ID, -32602, "Invalid params", err.Error())
-		return
-	}
-
-	switch toolParams.Name {
-	case "get_video_metadata":
-		h.handleGetVideoMetadata(w, req.ID, &toolParams)
-	case "search_videos":
-		h.handleSearchVideos(w, req.ID, &toolParams)
-	case "get_video_comments":
-		h.handleGetVideoComments(w, req.ID, &toolParams)
-	default:
-		h.sendErrorResponse(w, req.ID, -32601, "Unknown tool", nil)
-	}
-}
-
-func (h *MCPHandler) handleGetVideoMetadata(w http.ResponseWriter, id interface{}, params *ToolsCallParams) {
-	videoID, _ := params.Arguments["video_id"].(string)
-	if videoID == "" {
-		h.sendToolError(w, id, "video_id is required")
-		return
-	}
-
-	metadata, err := h.youtubeService.GetVideoMetadata(context.Background(), videoID)
-	if err != nil {
-		h.sendToolError(w, id, fmt.Sprintf("API Error: %v", err))
-		return
+		return errorResponse(req.ID, -32602, "Invalid params", err.Error())
 	}
 
-	h.sendToolResult(w, id, metadata)
-}
-
-func (h *MCPHandler) handleSearchVideos(w http.ResponseWriter, id interface{}, params *ToolsCallParams) {
-	query, _ := params.Arguments["query"].(string)
-	if query == "" {
-		h.sendToolError(w, id, "query is required")
-		return
+	tool, ok := h.tools.get(toolParams.Name)
+	if !ok {
+		return errorResponse(req.ID, -32601, "Unknown tool", nil)
 	}
-	channelID, _ := params.Arguments["channel_id"].(string)
-	limit, ok := params.Arguments["limit"].(float64) // JSON numbers are float64
-	if !ok || limit == 0 {
-		limit = 10
+	if err := h.tools.checkAvailable(ctx, tool.tool); err != nil {
+		return toolErrorFrom(req.ID, err)
 	}
 
-	results, err := h.youtubeService.SearchVideos(context.Background(), query, channelID, int64(limit))
-	if err != nil {
-		h.sendToolError(w, id, fmt.Sprintf("API Error: %v", err))
-		return
+	// Reject arguments that do not match the advertised schema before the
+	// handler runs, listing every violation so the caller can fix them at once.
+	if violations := tool.validate(toolParams.Arguments); len(violations) > 0 {
+		return errorResponse(req.ID, -32602, "Invalid params", map[string]interface{}{
+			"tool":       toolParams.Name,
+			"violations": violations,
+		})
 	}
 
-	h.sendToolResult(w, id, results)
-}
-
-func (h *MCPHandler) handleGetVideoComments(w http.ResponseWriter, id interface{}, params *ToolsCallParams) {
-	videoID, _ := params.Arguments["video_id"].(string)
-	if videoID == "" {
-		h.sendToolError(w, id, "video_id is required")
-		return
+	if noCache, _ := toolParams.Arguments["no_cache"].(bool); tool.tool.Cached && noCache {
+		ctx = service.WithoutCache(ctx)
 	}
-	sortBy, _ := params.Arguments["sort_by"].(string)
-	if sortBy == "" {
-		sortBy = "top"
-	}
-	limit, ok := params.Arguments["limit"].(float64)
-	if !ok || limit == 0 {
-		limit = 20
+	if meta := toolParams.Meta; meta != nil && len(meta.ProgressToken) > 0 && string(meta.ProgressToken) != "null" {
+		if notifier := NotifierFromContext(ctx); notifier != nil {
+			ctx = service.WithProgress(ctx, progressReporter(notifier, meta.ProgressToken))
+		}
 	}
 
-	comments, err := h.youtubeService.GetVideoComments(context.Background(), videoID, sortBy, int64(limit))
+	result, err := tool.handler(ctx, toolParams.Arguments)
 	if err != nil {
-		h.sendToolError(w, id, fmt.Sprintf("API Error: %v", err))
-		return
+		var invalidArgs *InvalidArgumentsError
+		if errors.As(err, &invalidArgs) {
+			return errorResponse(req.ID, -32602, "Invalid params", invalidArgs.Error())
+		}
+		return toolErrorFrom(req.ID, err)
 	}
-
-	h.sendToolResult(w, id, comments)
+	return toolResult(req.ID, result)
 }
 
 func (h *MCPHandler) decodeParams(source interface{}, dest interface{}) error {
@@ -235,31 +207,56 @@ func (h *MCPHandler) decodeParams(source interface{}, dest interface{}) error {
 	return json.Unmarshal(bytes, dest)
 }
 
-func (h *MCPHandler) sendSuccessResponse(w http.ResponseWriter, id interface{}, result interface{}) {
-	response := MCPResponse{JSONRPC: "2.0", ID: id, Result: result}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+func successResponse(id interface{}, result interface{}) *MCPResponse {
+	return &MCPResponse{JSONRPC: "2.0", ID: id, Result: result}
 }
 
-func (h *MCPHandler) sendErrorResponse(w http.ResponseWriter, id interface{}, code int, message string, data interface{}) {
-	response := MCPResponse{JSONRPC: "2.0", ID: id, Error: &MCPError{Code: code, Message: message, Data: data}}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+func errorResponse(id interface{}, code int, message string, data interface{}) *MCPResponse {
+	return &MCPResponse{JSONRPC: "2.0", ID: id, Error: &MCPError{Code: code, Message: message, Data: data}}
 }
 
-func (h *MCPHandler) sendToolError(w http.ResponseWriter, id interface{}, message string) {
+func toolError(id interface{}, message string) *MCPResponse {
 	result := ToolsCallResult{
-		Content: []ContentItem{{Type: "text", Text: message}},
+		Content: []ContentItem{TextContent(message)},
 		IsError: true,
 	}
-	h.sendSuccessResponse(w, id, result)
+	return successResponse(id, result)
 }
 
-func (h *MCPHandler) sendToolResult(w http.ResponseWriter, id interface{}, result interface{}) {
-	// The YouTube API responses are complex structs. We send them as JSON.
-	// An LLM can then parse this JSON to extract the information it needs.
+// toolErrorFrom reports a handler error as a tool error. The code of a
+// *ToolError is named in the text, for the assistant, and sent in _meta,
+// for programs.
+func toolErrorFrom(id interface{}, err error) *MCPResponse {
+	var coded *ToolError
+	if !errors.As(err, &coded) {
+		return toolError(id, err.Error())
+	}
+	result := ToolsCallResult{
+		Content: []ContentItem{TextContent(fmt.Sprintf("%s (error code: %s)", coded.Message, coded.Code))},
+		IsError: true,
+		Meta: map[string]interface{}{
+			"error": map[string]interface{}{"code": coded.Code, "retryable": coded.Retryable},
+		},
+	}
+	return successResponse(id, result)
+}
+
+func toolResult(id interface{}, result interface{}) *MCPResponse {
+	// Structured data goes in structuredContent. Shaped results bring their
+	// own text rendering; anything else is also sent as serialized JSON text
+	// for clients that only read content.
+	output, ok := result.(*ToolOutput)
+	if !ok {
+		text, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return toolError(id, fmt.Sprintf("failed to encode result: %v", err))
+		}
+		output = &ToolOutput{Text: string(text), Data: result}
+	}
+
 	finalResult := ToolsCallResult{
-		Content: []ContentItem{{Type: "json", JSON: result}},
+		Content:           append([]ContentItem{TextContent(output.Text)}, output.Content...),
+		StructuredContent: output.Data,
 	}
-	h.sendSuccessResponse(w, id, finalResult)
+	return successResponse(id, finalResult)
 }
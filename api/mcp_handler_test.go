@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// wireResult encodes a tools/call response as the client receives it and
+// returns its result.
+func wireResult(t *testing.T, resp *MCPResponse) map[string]interface{} {
+	t.Helper()
+	encoded, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Result map[string]interface{} `json:"result"`
+		Error  interface{}            `json:"error"`
+	}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Error != nil {
+		t.Fatalf("tool result sent as a JSON-RPC error: %s", encoded)
+	}
+	return decoded.Result
+}
+
+func contentTexts(result map[string]interface{}) []string {
+	var texts []string
+	for _, item := range result["content"].([]interface{}) {
+		block := item.(map[string]interface{})
+		kind, _ := block["type"].(string)
+		text, _ := block["text"].(string)
+		uri, _ := block["uri"].(string)
+		texts = append(texts, kind+":"+text+uri)
+	}
+	return texts
+}
+
+func TestToolResultStructuredContent(t *testing.T) {
+	// Plain data is sent as structuredContent and as JSON text.
+	result := wireResult(t, toolResult(1, map[string]interface{}{"title": "Go", "views": 3}))
+	want := map[string]interface{}{"title": "Go", "views": float64(3)}
+	if !reflect.DeepEqual(result["structuredContent"], want) {
+		t.Errorf("structuredContent = %v, want %v", result["structuredContent"], want)
+	}
+	content := result["content"].([]interface{})
+	if len(content) != 1 {
+		t.Fatalf("content = %v, want one text block", content)
+	}
+	var fallback map[string]interface{}
+	if err := json.Unmarshal([]byte(content[0].(map[string]interface{})["text"].(string)), &fallback); err != nil || !reflect.DeepEqual(fallback, want) {
+		t.Errorf("text fallback %v (%v) does not carry the structured data", content[0], err)
+	}
+	if _, isError := result["isError"]; isError {
+		t.Error("successful result is marked as an error")
+	}
+
+	// Shaped output keeps its own text, followed by its other blocks.
+	output := &ToolOutput{
+		Text:    "Go\n views: 3",
+		Data:    map[string]interface{}{"id": "v1"},
+		Content: []ContentItem{ResourceLinkContent("https://www.youtube.com/watch?v=v1", "Go", "")},
+	}
+	result = wireResult(t, toolResult(2, output))
+	if got, want := contentTexts(result), []string{"text:Go\n views: 3", "resource_link:https://www.youtube.com/watch?v=v1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("content = %q, want %q", got, want)
+	}
+	if !reflect.DeepEqual(result["structuredContent"], map[string]interface{}{"id": "v1"}) {
+		t.Errorf("structuredContent = %v, want the output's data", result["structuredContent"])
+	}
+
+	// A result that cannot be encoded becomes a tool error.
+	result = wireResult(t, toolResult(3, map[string]interface{}{"f": func() {}}))
+	if result["isError"] != true || result["structuredContent"] != nil {
+		t.Errorf("unencodable result answered %v, want a tool error", result)
+	}
+}
+
+func TestToolErrorResult(t *testing.T) {
+	result := wireResult(t, toolErrorFrom(4, &ToolError{Code: "quota_exceeded", Message: "Daily quota used up.", Retryable: true}))
+	if result["isError"] != true {
+		t.Errorf("isError = %v, want true", result["isError"])
+	}
+	if got := contentTexts(result); len(got) != 1 || !strings.Contains(got[0], "Daily quota used up.") || !strings.Contains(got[0], "quota_exceeded") {
+		t.Errorf("content = %q, want the message and its code", got)
+	}
+	meta, _ := result["_meta"].(map[string]interface{})
+	want := map[string]interface{}{"code": "quota_exceeded", "retryable": true}
+	if !reflect.DeepEqual(meta["error"], want) {
+		t.Errorf("_meta = %v, want error %v", meta, want)
+	}
+	if _, ok := result["structuredContent"]; ok {
+		t.Error("tool error carries structuredContent")
+	}
+
+	// Wrapped tool errors keep their code; others have none.
+	wrapped := wireResult(t, toolErrorFrom(5, errors.Join(errors.New("context"), &ToolError{Code: "not_found", Message: "No such video."})))
+	if meta, _ := wrapped["_meta"].(map[string]interface{}); meta == nil || meta["error"].(map[string]interface{})["code"] != "not_found" {
+		t.Errorf("wrapped tool error lost its code: %v", wrapped)
+	}
+	plain := wireResult(t, toolErrorFrom(6, errors.New("something broke")))
+	if plain["isError"] != true || plain["_meta"] != nil || !reflect.DeepEqual(contentTexts(plain), []string{"text:something broke"}) {
+		t.Errorf("plain error answered %v", plain)
+	}
+}
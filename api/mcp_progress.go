@@ -0,0 +1,34 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/yt-mcp-server/service"
+)
+
+// RequestMeta is the _meta of a request's params.
+type RequestMeta struct {
+	// ProgressToken, if set, asks for notifications/progress while the
+	// request runs. It is a string or a number, echoed back unchanged.
+	ProgressToken json.RawMessage `json:"progressToken,omitempty"`
+}
+
+// ProgressParams are the params of notifications/progress.
+type ProgressParams struct {
+	ProgressToken json.RawMessage `json:"progressToken"`
+	Progress      int64           `json:"progress"`
+	Total         int64           `json:"total,omitempty"`
+	Message       string          `json:"message,omitempty"`
+}
+
+// progressReporter returns a service.ProgressFunc that sends the progress of
+// a request to the client as notifications/progress.
+func progressReporter(notifier Notifier, token json.RawMessage) service.ProgressFunc {
+	return func(progress, total int64, message string) {
+		params := ProgressParams{ProgressToken: token, Progress: progress, Total: total, Message: message}
+		if err := notifier.Notify("notifications/progress", params); err != nil {
+			log.Printf("Could not send progress notification: %v", err)
+		}
+	}
+}
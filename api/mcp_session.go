@@ -0,0 +1,345 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxStreamEvents bounds how many events each stream keeps for replay.
+	maxStreamEvents = 256
+	// maxSessionStreams bounds how many finished streams a session keeps around
+	// so that a client can still resume them with Last-Event-ID.
+	maxSessionStreams = 64
+)
+
+// sseEvent is a single Server-Sent Event written on an MCP stream.
+type sseEvent struct {
+	ID   string
+	Data []byte
+}
+
+// eventStream is an ordered, replayable log of events for one SSE stream.
+// Each POST that is upgraded to SSE gets its own stream, and every session
+// has one standalone stream that backs GET /mcp.
+type eventStream struct {
+	id string
+
+	mu      sync.Mutex
+	seq     uint64
+	events  []sseEvent
+	done    bool
+	waiters []chan struct{}
+}
+
+func newEventStream() *eventStream {
+	return &eventStream{id: randomID(8)}
+}
+
+// publish appends an event to the stream and wakes any attached readers.
+func (s *eventStream) publish(data []byte) sseEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	event := sseEvent{ID: fmt.Sprintf("%s_%d", s.id, s.seq), Data: data}
+	s.events = append(s.events, event)
+	if len(s.events) > maxStreamEvents {
+		s.events = s.events[len(s.events)-maxStreamEvents:]
+	}
+	s.wakeLocked()
+	return event
+}
+
+// close marks the stream as finished; readers drain it and then return.
+func (s *eventStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.done = true
+	s.wakeLocked()
+}
+
+func (s *eventStream) wakeLocked() {
+	for _, ch := range s.waiters {
+		close(ch)
+	}
+	s.waiters = nil
+}
+
+// since returns the events after seq, whether the stream is finished, and a
+// channel that is closed when new events arrive.
+func (s *eventStream) since(seq uint64) ([]sseEvent, bool, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pending []sseEvent
+	for _, event := range s.events {
+		if eventSeq(event.ID) > seq {
+			pending = append(pending, event)
+		}
+	}
+	wake := make(chan struct{})
+	s.waiters = append(s.waiters, wake)
+	return pending, s.done, wake
+}
+
+// Session is a single MCP client session, created on initialize and
+// identified by the Mcp-Session-Id header.
+type Session struct {
+	ID string
+	// Owner is the subject of the principal that created the session; only
+	// the same user may use it.
+	Owner string
+
+	mu         sync.Mutex
+	lastSeen   time.Time
+	standalone *eventStream
+	streams    map[string]*eventStream
+	order      []string
+	delivered  uint64
+	attached   bool
+	closed     bool
+
+	// inflight holds the session's requests being handled, for cancellation.
+	inflight *inflightRequests
+}
+
+func newSession(owner string) *Session {
+	s := &Session{
+		ID:       randomID(16),
+		Owner:    owner,
+		lastSeen: time.Now(),
+		streams:  make(map[string]*eventStream),
+		inflight: newInflightRequests(),
+	}
+	s.standalone = s.addStream()
+	return s
+}
+
+// addStream registers a new stream with the session, evicting the oldest
+// request stream once the session holds too many.
+func (s *Session) addStream() *eventStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stream := newEventStream()
+	s.streams[stream.id] = stream
+	s.order = append(s.order, stream.id)
+
+	for len(s.order) > maxSessionStreams {
+		evicted := false
+		for i, id := range s.order {
+			if s.standalone != nil && id == s.standalone.id {
+				continue
+			}
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			delete(s.streams, id)
+			evicted = true
+			break
+		}
+		if !evicted {
+			break
+		}
+	}
+	return stream
+}
+
+// stream looks up a stream by the stream part of an event ID.
+func (s *Session) stream(id string) *eventStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.streams[id]
+}
+
+// Notify sends a JSON-RPC notification on the session's standalone stream.
+// If no GET stream is attached the event is kept for later replay.
+func (s *Session) Notify(method string, params interface{}) error {
+	data, err := json.Marshal(MCPNotification{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	s.standalone.publish(data)
+	return nil
+}
+
+// attach claims the standalone stream for a GET request. Only one GET
+// stream may be open per session at a time.
+func (s *Session) attach() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attached || s.closed {
+		return false
+	}
+	s.attached = true
+	return true
+}
+
+func (s *Session) detach() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attached = false
+}
+
+// markDelivered records how far the standalone stream has been written, so
+// that a fresh GET without Last-Event-ID does not repeat old events.
+func (s *Session) markDelivered(seq uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if seq > s.delivered {
+		s.delivered = seq
+	}
+}
+
+func (s *Session) lastDelivered() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.delivered
+}
+
+func (s *Session) touch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSeen = time.Now()
+}
+
+func (s *Session) idleSince() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSeen
+}
+
+// close finishes every stream so that attached readers return.
+func (s *Session) close() {
+	s.mu.Lock()
+	s.closed = true
+	streams := make([]*eventStream, 0, len(s.streams))
+	for _, stream := range s.streams {
+		streams = append(streams, stream)
+	}
+	s.mu.Unlock()
+
+	for _, stream := range streams {
+		stream.close()
+	}
+}
+
+// SessionManager tracks the live MCP sessions of the Streamable HTTP transport.
+type SessionManager struct {
+	idleTimeout time.Duration
+
+	mu       sync.RWMutex
+	sessions map[string]*Session
+}
+
+// NewSessionManager creates a SessionManager that expires sessions after
+// idleTimeout without any traffic.
+func NewSessionManager(idleTimeout time.Duration) *SessionManager {
+	return &SessionManager{
+		idleTimeout: idleTimeout,
+		sessions:    make(map[string]*Session),
+	}
+}
+
+// Create starts a new session owned by the given principal subject.
+func (m *SessionManager) Create(owner string) *Session {
+	session := newSession(owner)
+	m.mu.Lock()
+	m.sessions[session.ID] = session
+	m.mu.Unlock()
+	return session
+}
+
+// Get returns the session with the given ID, or nil if it does not exist.
+func (m *SessionManager) Get(id string) *Session {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.sessions[id]
+}
+
+// Delete terminates a session. It reports whether the session existed.
+func (m *SessionManager) Delete(id string) bool {
+	m.mu.Lock()
+	session, ok := m.sessions[id]
+	delete(m.sessions, id)
+	m.mu.Unlock()
+
+	if ok {
+		session.close()
+	}
+	return ok
+}
+
+// Reaper is a background goroutine that removes idle sessions.
+func (m *SessionManager) Reaper(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cutoff := time.Now().Add(-m.idleTimeout)
+			m.mu.RLock()
+			var expired []string
+			for id, session := range m.sessions {
+				if session.idleSince().Before(cutoff) {
+					expired = append(expired, id)
+				}
+			}
+			m.mu.RUnlock()
+
+			for _, id := range expired {
+				m.Delete(id)
+				log.Printf("MCP session %s expired", id)
+			}
+		}
+	}
+}
+
+type sessionContextKey struct{}
+
+// withSession attaches the MCP session to a request context.
+func withSession(ctx context.Context, session *Session) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, session)
+}
+
+// SessionFromContext returns the MCP session of the request, if any.
+func SessionFromContext(ctx context.Context) *Session {
+	session, _ := ctx.Value(sessionContextKey{}).(*Session)
+	return session
+}
+
+// parseEventID splits an event ID into its stream ID and sequence number.
+func parseEventID(id string) (string, uint64, bool) {
+	i := strings.LastIndexByte(id, '_')
+	if i <= 0 {
+		return "", 0, false
+	}
+	seq, err := strconv.ParseUint(id[i+1:], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return id[:i], seq, true
+}
+
+func eventSeq(id string) uint64 {
+	_, seq, _ := parseEventID(id)
+	return seq
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+)
+
+// maxStdioMessageSize bounds a single newline-delimited JSON-RPC message.
+const maxStdioMessageSize = 4 * 1024 * 1024
+
+// stdioWriter serializes messages written to the stdio transport's output,
+// one JSON document per line.
+type stdioWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (s *stdioWriter) write(v interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.enc.Encode(v); err != nil {
+		log.Printf("Failed to write MCP message: %v", err)
+	}
+}
+
+// Notify implements Notifier.
+func (s *stdioWriter) Notify(method string, params interface{}) error {
+	s.write(MCPNotification{JSONRPC: "2.0", Method: method, Params: params})
+	return nil
+}
+
+// ServeStdio runs the MCP protocol over newline-delimited JSON-RPC, reading
+// requests from in and writing responses and notifications to out. It is
+// used when the server is launched as a subprocess by a desktop MCP host.
+// Requests are handled concurrently and can be stopped with
+// notifications/cancelled; ServeStdio returns once in is closed and every
+// in-flight request has been answered.
+func (h *MCPHandler) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	writer := &stdioWriter{enc: json.NewEncoder(out)}
+	ctx = withInflight(withNotifier(ctx, writer), newInflightRequests())
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStdioMessageSize)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		reqs, rejected, batch, fatal := parseMessages(line)
+		if fatal != nil {
+			writer.write(fatal)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses := append(rejected, h.handleRequests(ctx, reqs)...)
+			if len(responses) == 0 {
+				return
+			}
+			if batch {
+				writer.write(responses)
+			} else {
+				writer.write(responses[0])
+			}
+		}()
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read from stdin: %w", err)
+	}
+	return nil
+}
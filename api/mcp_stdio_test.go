@@ -0,0 +1,203 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stdioConn is a ServeStdio connection driven through pipes.
+type stdioConn struct {
+	in    *io.PipeWriter
+	lines *bufio.Scanner
+	done  chan error
+}
+
+func serveTestStdio(t *testing.T, handler *MCPHandler) *stdioConn {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	conn := &stdioConn{in: inW, lines: bufio.NewScanner(outR), done: make(chan error, 1)}
+	go func() {
+		err := handler.ServeStdio(context.Background(), inR, outW)
+		outW.Close()
+		conn.done <- err
+	}()
+	t.Cleanup(func() {
+		inW.Close()
+		outR.Close()
+	})
+	return conn
+}
+
+func (c *stdioConn) send(t *testing.T, line string) {
+	t.Helper()
+	if _, err := io.WriteString(c.in, line+"\n"); err != nil {
+		t.Fatalf("write to stdin: %v", err)
+	}
+}
+
+// next returns the next line written to stdout, or "" once it is closed.
+func (c *stdioConn) next(t *testing.T) string {
+	t.Helper()
+	lines := make(chan string, 1)
+	go func() {
+		if c.lines.Scan() {
+			lines <- c.lines.Text()
+		} else {
+			lines <- ""
+		}
+	}()
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(5 * time.Second):
+		t.Fatal("no output from ServeStdio")
+		return ""
+	}
+}
+
+// close closes stdin and returns what ServeStdio returned.
+func (c *stdioConn) close(t *testing.T) error {
+	t.Helper()
+	c.in.Close()
+	for c.next(t) != "" {
+	}
+	select {
+	case err := <-c.done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeStdio did not return after stdin was closed")
+		return nil
+	}
+}
+
+// newStdioTestHandler returns a handler with a "log" tool, which writes to
+// the log before answering.
+func newStdioTestHandler(t *testing.T) *MCPHandler {
+	t.Helper()
+	registry := NewToolRegistry()
+	err := registry.Register(Tool{Name: "log"}, func(ctx context.Context, _ map[string]interface{}) (interface{}, error) {
+		log.Printf("log tool called")
+		return map[string]interface{}{"ok": true}, nil
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return NewMCPHandler(registry, nil)
+}
+
+func TestStdioAnswersEachRequestOnALine(t *testing.T) {
+	conn := serveTestStdio(t, newStdioTestHandler(t))
+
+	for _, id := range []int{1, 2} {
+		conn.send(t, fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"tools/list"}`, id))
+		var resp MCPResponse
+		if err := json.Unmarshal([]byte(conn.next(t)), &resp); err != nil {
+			t.Fatalf("response line is not one JSON-RPC message: %v", err)
+		}
+		if resp.ID != float64(id) || resp.Error != nil {
+			t.Errorf("got %+v, want the result of request %d", resp, id)
+		}
+	}
+
+	// Blank lines are skipped.
+	conn.send(t, "")
+	if err := conn.close(t); err != nil {
+		t.Errorf("ServeStdio returned %v at EOF, want nil", err)
+	}
+}
+
+func TestStdioNotificationsAreNotAnswered(t *testing.T) {
+	conn := serveTestStdio(t, newStdioTestHandler(t))
+
+	conn.send(t, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	conn.send(t, `{"jsonrpc":"2.0","method":"notifications/unknown"}`)
+	conn.send(t, `{"jsonrpc":"2.0","id":3,"method":"tools/list"}`)
+
+	// The first line answers the request; the notifications got nothing.
+	var resp MCPResponse
+	if err := json.Unmarshal([]byte(conn.next(t)), &resp); err != nil || resp.ID != float64(3) {
+		t.Fatalf("got %+v (%v), want only the response to request 3", resp, err)
+	}
+	if err := conn.close(t); err != nil {
+		t.Errorf("ServeStdio returned %v at EOF, want nil", err)
+	}
+}
+
+func TestStdioBatch(t *testing.T) {
+	conn := serveTestStdio(t, newStdioTestHandler(t))
+
+	conn.send(t, `[{"jsonrpc":"2.0","id":1,"method":"tools/list"},{"jsonrpc":"2.0","method":"notifications/initialized"},{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"log"}}]`)
+	var responses []MCPResponse
+	if err := json.Unmarshal([]byte(conn.next(t)), &responses); err != nil {
+		t.Fatalf("batch was not answered with one JSON array: %v", err)
+	}
+	ids := map[interface{}]bool{}
+	for _, resp := range responses {
+		ids[resp.ID] = true
+	}
+	if len(responses) != 2 || !ids[float64(1)] || !ids[float64(2)] {
+		t.Errorf("batch responses = %+v, want one per request", responses)
+	}
+
+	// A batch of notifications gets no response at all.
+	conn.send(t, `[{"jsonrpc":"2.0","method":"notifications/initialized"}]`)
+	conn.send(t, `[]`)
+	var invalid MCPResponse
+	if err := json.Unmarshal([]byte(conn.next(t)), &invalid); err != nil || invalid.Error == nil || invalid.Error.Code != -32600 {
+		t.Errorf("empty batch answered %+v (%v), want an Invalid Request error", invalid, err)
+	}
+	if err := conn.close(t); err != nil {
+		t.Errorf("ServeStdio returned %v at EOF, want nil", err)
+	}
+}
+
+func TestStdioOutputIsOnlyJSONRPC(t *testing.T) {
+	var logs bytes.Buffer
+	previous := log.Writer()
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(previous) })
+
+	handler := newStdioTestHandler(t)
+	inR, inW := io.Pipe()
+	var out bytes.Buffer
+	done := make(chan error, 1)
+	go func() { done <- handler.ServeStdio(context.Background(), inR, &out) }()
+
+	for _, line := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"log"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"missing"}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"nope"}`,
+		`not json`,
+	} {
+		io.WriteString(inW, line+"\n")
+	}
+	inW.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("ServeStdio returned %v at EOF, want nil", err)
+	}
+
+	if !strings.Contains(logs.String(), "log tool called") {
+		t.Errorf("the tool's log line was not written to the log: %q", logs.String())
+	}
+	scanner := bufio.NewScanner(&out)
+	lines := 0
+	for scanner.Scan() {
+		lines++
+		var message map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil || message["jsonrpc"] != "2.0" {
+			t.Errorf("stdout line is not a JSON-RPC message: %s", scanner.Text())
+		}
+	}
+	if lines != 4 {
+		t.Errorf("wrote %d lines to stdout, want one response per message", lines)
+	}
+}
@@ -0,0 +1,350 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yt-mcp-server/service"
+)
+
+const (
+	headerSessionID       = "Mcp-Session-Id"
+	headerProtocolVersion = "Mcp-Protocol-Version"
+	headerLastEventID     = "Last-Event-ID"
+
+	// ssePingInterval keeps idle GET streams alive through proxies.
+	ssePingInterval = 30 * time.Second
+)
+
+// supportedProtocolVersions lists the MCP revisions this server understands.
+// The first entry is the one negotiated on initialize.
+var supportedProtocolVersions = []string{"2025-06-18", "2025-03-26"}
+
+// Notifier sends JSON-RPC notifications back to the client while a request
+// is being handled.
+type Notifier interface {
+	Notify(method string, params interface{}) error
+}
+
+type notifierContextKey struct{}
+
+func withNotifier(ctx context.Context, n Notifier) context.Context {
+	return context.WithValue(ctx, notifierContextKey{}, n)
+}
+
+// NotifierFromContext returns the notifier of the request being handled, or
+// nil if the transport cannot deliver notifications.
+func NotifierFromContext(ctx context.Context) Notifier {
+	n, _ := ctx.Value(notifierContextKey{}).(Notifier)
+	return n
+}
+
+// responseStream delivers the messages produced while handling a single
+// POST. It answers with plain JSON unless a notification is sent before the
+// response, in which case the POST is upgraded to an SSE stream.
+type responseStream struct {
+	w          http.ResponseWriter
+	session    *Session
+	acceptsSSE bool
+
+	mu     sync.Mutex
+	stream *eventStream
+	broken bool
+}
+
+// Notify implements Notifier.
+func (s *responseStream) Notify(method string, params interface{}) error {
+	if !s.acceptsSSE {
+		return fmt.Errorf("client does not accept event streams")
+	}
+	data, err := json.Marshal(MCPNotification{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.upgradeLocked()
+	s.sendLocked(data)
+	return nil
+}
+
+// finish writes the final responses, closing the SSE stream if one was
+// opened. Batches are answered with a JSON array, or with one event per
+// response once the POST has been upgraded to SSE.
+func (s *responseStream) finish(responses []*MCPResponse, batch bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stream == nil {
+		switch {
+		case len(responses) == 0:
+			s.w.WriteHeader(http.StatusAccepted)
+		case batch:
+			writeJSON(s.w, http.StatusOK, responses)
+		default:
+			writeJSON(s.w, http.StatusOK, responses[0])
+		}
+		return
+	}
+
+	for _, response := range responses {
+		data, err := json.Marshal(response)
+		if err != nil {
+			log.Printf("Failed to encode MCP response: %v", err)
+			data, _ = json.Marshal(errorResponse(response.ID, -32603, "Internal error", nil))
+		}
+		s.sendLocked(data)
+	}
+	s.stream.close()
+}
+
+func (s *responseStream) upgradeLocked() {
+	if s.stream != nil {
+		return
+	}
+	s.stream = s.session.addStream()
+	setSSEHeaders(s.w)
+	s.w.WriteHeader(http.StatusOK)
+}
+
+// sendLocked records the event for replay and writes it to the client. Once
+// the client has gone away events are only recorded, so that they can be
+// picked up again with Last-Event-ID.
+func (s *responseStream) sendLocked(data []byte) {
+	event := s.stream.publish(data)
+	if s.broken {
+		return
+	}
+	if err := writeSSE(s.w, event); err != nil {
+		s.broken = true
+	}
+}
+
+// HandleMCP handles POST /mcp, which carries a JSON-RPC message or batch
+// from the client. Requests made only of notifications and responses are
+// acknowledged with 202 Accepted and no body.
+func (h *MCPHandler) HandleMCP(w http.ResponseWriter, r *http.Request) {
+	if !h.checkProtocolVersion(w, r) {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse(nil, -32700, "Parse error", nil))
+		return
+	}
+	reqs, rejected, batch, fatal := parseMessages(body)
+	if fatal != nil {
+		writeJSON(w, http.StatusBadRequest, fatal)
+		return
+	}
+
+	var session *Session
+	if containsInitialize(reqs) {
+		// A session is initialized once; a client starting over must
+		// first end it, or open a new one without its ID.
+		if live := h.sessions.Get(r.Header.Get(headerSessionID)); live != nil && live.Owner == principalSubject(r) {
+			writeJSON(w, http.StatusBadRequest, errorResponse(nil, -32600, "Invalid Request", "session is already initialized"))
+			return
+		}
+		session = h.sessions.Create(principalSubject(r))
+		w.Header().Set(headerSessionID, session.ID)
+	} else if session = h.requireSession(w, r); session == nil {
+		return
+	}
+	session.touch()
+
+	stream := &responseStream{
+		w:          w,
+		session:    session,
+		acceptsSSE: accepts(r, "text/event-stream"),
+	}
+	ctx := withInflight(withSession(r.Context(), session), session.inflight)
+	// Without an event stream nothing but the response can reach the
+	// client, so tools must not try to send notifications.
+	if stream.acceptsSSE {
+		ctx = withNotifier(ctx, stream)
+	}
+	responses := append(rejected, h.handleRequests(ctx, reqs)...)
+	stream.finish(responses, batch)
+}
+
+// HandleMCPStream handles GET /mcp, which opens a Server-Sent Events stream
+// for server-initiated messages. With a Last-Event-ID header it resumes the
+// stream that event belongs to, replaying everything the client missed.
+func (h *MCPHandler) HandleMCPStream(w http.ResponseWriter, r *http.Request) {
+	if !accepts(r, "text/event-stream") {
+		writeJSON(w, http.StatusNotAcceptable, errorResponse(nil, -32000, "Client must accept text/event-stream", nil))
+		return
+	}
+	if !h.checkProtocolVersion(w, r) {
+		return
+	}
+	session := h.requireSession(w, r)
+	if session == nil {
+		return
+	}
+	session.touch()
+
+	stream := session.standalone
+	after := session.lastDelivered()
+	if lastEventID := r.Header.Get(headerLastEventID); lastEventID != "" {
+		streamID, seq, ok := parseEventID(lastEventID)
+		if resumed := session.stream(streamID); ok && resumed != nil {
+			stream, after = resumed, seq
+		}
+	}
+
+	if stream == session.standalone {
+		if !session.attach() {
+			writeJSON(w, http.StatusConflict, errorResponse(nil, -32000, "Only one stream is allowed per session", nil))
+			return
+		}
+		defer session.detach()
+	}
+
+	setSSEHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+
+	ping := time.NewTicker(ssePingInterval)
+	defer ping.Stop()
+
+	for {
+		events, done, wake := stream.since(after)
+		for _, event := range events {
+			if err := writeSSE(w, event); err != nil {
+				return
+			}
+			after = eventSeq(event.ID)
+			if stream == session.standalone {
+				session.markDelivered(after)
+			}
+		}
+		if done {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-wake:
+		case <-ping.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+			session.touch()
+		}
+	}
+}
+
+// HandleMCPDelete handles DELETE /mcp, which terminates the session.
+func (h *MCPHandler) HandleMCPDelete(w http.ResponseWriter, r *http.Request) {
+	session := h.requireSession(w, r)
+	if session == nil {
+		return
+	}
+	if !h.sessions.Delete(session.ID) {
+		writeJSON(w, http.StatusNotFound, errorResponse(nil, -32001, "Session not found", nil))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireSession resolves the session named by the Mcp-Session-Id header,
+// writing the error response and returning nil if there is none. Sessions
+// of other users are treated as nonexistent.
+func (h *MCPHandler) requireSession(w http.ResponseWriter, r *http.Request) *Session {
+	id := r.Header.Get(headerSessionID)
+	if id == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse(nil, -32000, "Missing Mcp-Session-Id header", nil))
+		return nil
+	}
+	session := h.sessions.Get(id)
+	if session == nil || session.Owner != principalSubject(r) {
+		writeJSON(w, http.StatusNotFound, errorResponse(nil, -32001, "Session not found", nil))
+		return nil
+	}
+	return session
+}
+
+// principalSubject returns the subject of the authenticated principal of a
+// request, or "" if there is none.
+func principalSubject(r *http.Request) string {
+	if principal, ok := service.PrincipalFromContext(r.Context()); ok {
+		return principal.Subject
+	}
+	return ""
+}
+
+// checkProtocolVersion rejects requests that announce an MCP revision we do
+// not speak. Clients that omit the header are assumed to be compatible.
+func (h *MCPHandler) checkProtocolVersion(w http.ResponseWriter, r *http.Request) bool {
+	version := r.Header.Get(headerProtocolVersion)
+	if version == "" {
+		return true
+	}
+	for _, supported := range supportedProtocolVersions {
+		if version == supported {
+			return true
+		}
+	}
+	writeJSON(w, http.StatusBadRequest, errorResponse(nil, -32000, fmt.Sprintf("Unsupported protocol version: %s", version), nil))
+	return false
+}
+
+func containsInitialize(reqs []*MCPRequest) bool {
+	for _, req := range reqs {
+		if req.Method == "initialize" && !req.IsNotification() {
+			return true
+		}
+	}
+	return false
+}
+
+func accepts(r *http.Request, mediaType string) bool {
+	for _, value := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+			if part == mediaType || part == "*/*" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func setSSEHeaders(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+}
+
+func writeSSE(w http.ResponseWriter, event sseEvent) error {
+	if _, err := fmt.Fprintf(w, "id: %s\nevent: message\ndata: %s\n\n", event.ID, event.Data); err != nil {
+		return err
+	}
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestMCPServer serves the Streamable HTTP transport on /mcp with a
+// "notify" tool that sends one notification before answering, and reports
+// whether it could.
+func newTestMCPServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	registry := NewToolRegistry()
+	err := registry.Register(Tool{Name: "notify"}, func(ctx context.Context, _ map[string]interface{}) (interface{}, error) {
+		notifier := NotifierFromContext(ctx)
+		if notifier != nil {
+			notifier.Notify("notifications/message", map[string]interface{}{"level": "info", "data": "working"})
+		}
+		return map[string]interface{}{"ok": true, "notified": notifier != nil}, nil
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return serveTestMCP(t, registry)
+}
+
+// serveTestMCP serves the Streamable HTTP transport for registry.
+func serveTestMCP(t *testing.T, registry *ToolRegistry) *httptest.Server {
+	t.Helper()
+	handler := NewMCPHandler(registry, NewSessionManager(time.Hour))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			handler.HandleMCP(w, r)
+		case http.MethodGet:
+			handler.HandleMCPStream(w, r)
+		case http.MethodDelete:
+			handler.HandleMCPDelete(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func mcpRequest(t *testing.T, server *httptest.Server, method, sessionID, body string, header map[string]string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if sessionID != "" {
+		req.Header.Set(headerSessionID, sessionID)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func initializeSession(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	resp := mcpRequest(t, server, http.MethodPost, "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("initialize status = %d", resp.StatusCode)
+	}
+	sessionID := resp.Header.Get(headerSessionID)
+	if sessionID == "" {
+		t.Fatal("initialize did not issue an Mcp-Session-Id")
+	}
+	return sessionID
+}
+
+// readSSE returns the id and data of every event in an SSE body.
+func readSSE(t *testing.T, body io.Reader) (ids, data []string) {
+	t.Helper()
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if id, ok := strings.CutPrefix(line, "id: "); ok {
+			ids = append(ids, id)
+		} else if d, ok := strings.CutPrefix(line, "data: "); ok {
+			data = append(data, d)
+		}
+	}
+	return ids, data
+}
+
+func TestInitializeIssuesSession(t *testing.T) {
+	server := newTestMCPServer(t)
+	sessionID := initializeSession(t, server)
+
+	resp := mcpRequest(t, server, http.MethodPost, sessionID, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("tools/list with session: status = %d, want 200", resp.StatusCode)
+	}
+	resp = mcpRequest(t, server, http.MethodPost, "", `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`, nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("tools/list without session: status = %d, want 400", resp.StatusCode)
+	}
+	resp = mcpRequest(t, server, http.MethodPost, sessionID, `{"jsonrpc":"2.0","method":"notifications/initialized"}`, nil)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("notification: status = %d, want 202", resp.StatusCode)
+	}
+}
+
+func TestInitializeOnLiveSessionRejected(t *testing.T) {
+	server := newTestMCPServer(t)
+	sessionID := initializeSession(t, server)
+
+	initialize := `{"jsonrpc":"2.0","id":3,"method":"initialize","params":{}}`
+	resp := mcpRequest(t, server, http.MethodPost, sessionID, initialize, nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("initialize on a live session: status = %d, want 400", resp.StatusCode)
+	}
+	if id := resp.Header.Get(headerSessionID); id != "" {
+		t.Errorf("rejected initialize issued session %q", id)
+	}
+
+	// The session is still usable.
+	resp = mcpRequest(t, server, http.MethodPost, sessionID, `{"jsonrpc":"2.0","id":4,"method":"tools/list"}`, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("tools/list after the rejected initialize: status = %d, want 200", resp.StatusCode)
+	}
+
+	// Once the session is ended, the client can start over.
+	mcpRequest(t, server, http.MethodDelete, sessionID, "", nil)
+	resp = mcpRequest(t, server, http.MethodPost, sessionID, initialize, nil)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get(headerSessionID) == sessionID {
+		t.Errorf("initialize after DELETE: status = %d, session %q", resp.StatusCode, resp.Header.Get(headerSessionID))
+	}
+}
+
+func TestReplayAfterLastEventID(t *testing.T) {
+	server := newTestMCPServer(t)
+	sessionID := initializeSession(t, server)
+
+	resp := mcpRequest(t, server, http.MethodPost, sessionID, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"notify"}}`, nil)
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+	}
+	ids, data := readSSE(t, resp.Body)
+	if len(ids) != 2 {
+		t.Fatalf("got %d events, want the notification and the response", len(ids))
+	}
+	if !strings.Contains(data[0], "notifications/message") || !strings.Contains(data[1], `"id":2`) {
+		t.Fatalf("unexpected events %q", data)
+	}
+
+	// Resume as if the connection dropped after the first event.
+	resp = mcpRequest(t, server, http.MethodGet, sessionID, "", map[string]string{headerLastEventID: ids[0]})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET status = %d, want 200", resp.StatusCode)
+	}
+	replayedIDs, replayed := readSSE(t, resp.Body)
+	if len(replayedIDs) != 1 || replayedIDs[0] != ids[1] || replayed[0] != data[1] {
+		t.Fatalf("replayed %q %q, want only event %s", replayedIDs, replayed, ids[1])
+	}
+}
+
+func TestDeleteEndsSession(t *testing.T) {
+	server := newTestMCPServer(t)
+	sessionID := initializeSession(t, server)
+
+	resp := mcpRequest(t, server, http.MethodDelete, sessionID, "", nil)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("DELETE status = %d, want 204", resp.StatusCode)
+	}
+	resp = mcpRequest(t, server, http.MethodPost, sessionID, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`, nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("POST after DELETE: status = %d, want 404", resp.StatusCode)
+	}
+	resp = mcpRequest(t, server, http.MethodDelete, sessionID, "", nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("second DELETE: status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestNoNotifierWithoutEventStream(t *testing.T) {
+	server := newTestMCPServer(t)
+	sessionID := initializeSession(t, server)
+
+	resp := mcpRequest(t, server, http.MethodPost, sessionID, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"notify"}}`,
+		map[string]string{"Accept": "application/json"})
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type = %q, want application/json", ct)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), `"notified":false`) {
+		t.Errorf("tool was given a notifier for a client that does not accept event streams: %s", body)
+	}
+}
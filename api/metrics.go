@@ -0,0 +1,67 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/yt-mcp-server/service"
+)
+
+// MetricsHandler serves the YouTube quota usage and response cache
+// statistics in the Prometheus text exposition format. Scrapers must send
+// token as a bearer token, since the statistics reveal how the server is used.
+func MetricsHandler(quota *service.QuotaTracker, cache *service.ResponseCache, token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "metrics token required", http.StatusUnauthorized)
+			return
+		}
+
+		usage := quota.Usage()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprintln(w, "# HELP youtube_quota_used_units YouTube Data API quota units used today (Pacific time).")
+		fmt.Fprintln(w, "# TYPE youtube_quota_used_units gauge")
+		fmt.Fprintf(w, "youtube_quota_used_units %d\n", usage.Used)
+		fmt.Fprintln(w, "# HELP youtube_quota_budget_units Daily quota budget in units; 0 means unlimited.")
+		fmt.Fprintln(w, "# TYPE youtube_quota_budget_units gauge")
+		fmt.Fprintf(w, "youtube_quota_budget_units %d\n", usage.Budget)
+		fmt.Fprintln(w, "# HELP youtube_quota_refused_calls Calls refused today because they would exceed the budget.")
+		fmt.Fprintln(w, "# TYPE youtube_quota_refused_calls gauge")
+		fmt.Fprintf(w, "youtube_quota_refused_calls %d\n", usage.Refused)
+
+		methods := usage.SortedMethods()
+		fmt.Fprintln(w, "# HELP youtube_api_calls YouTube Data API calls made today, by method.")
+		fmt.Fprintln(w, "# TYPE youtube_api_calls gauge")
+		for _, method := range methods {
+			fmt.Fprintf(w, "youtube_api_calls{method=%q} %d\n", method, usage.Methods[method].Calls)
+		}
+		fmt.Fprintln(w, "# HELP youtube_quota_method_units Quota units used today, by method.")
+		fmt.Fprintln(w, "# TYPE youtube_quota_method_units gauge")
+		for _, method := range methods {
+			fmt.Fprintf(w, "youtube_quota_method_units{method=%q} %d\n", method, usage.Methods[method].Units)
+		}
+		fmt.Fprintln(w, "# HELP youtube_api_revalidations YouTube Data API calls made today that were answered 304 Not Modified, by method.")
+		fmt.Fprintln(w, "# TYPE youtube_api_revalidations gauge")
+		for _, method := range methods {
+			fmt.Fprintf(w, "youtube_api_revalidations{method=%q} %d\n", method, usage.Methods[method].Revalidations)
+		}
+
+		stats := cache.Stats()
+		fmt.Fprintln(w, "# HELP youtube_cache_requests_total Cacheable YouTube API reads, by how they were answered.")
+		fmt.Fprintln(w, "# TYPE youtube_cache_requests_total counter")
+		fmt.Fprintf(w, "youtube_cache_requests_total{result=\"hit\"} %d\n", stats.Hits)
+		fmt.Fprintf(w, "youtube_cache_requests_total{result=\"revalidated\"} %d\n", stats.Revalidated)
+		fmt.Fprintf(w, "youtube_cache_requests_total{result=\"miss\"} %d\n", stats.Misses)
+		fmt.Fprintln(w, "# HELP youtube_cache_entries Responses held in the cache.")
+		fmt.Fprintln(w, "# TYPE youtube_cache_entries gauge")
+		fmt.Fprintf(w, "youtube_cache_entries %d\n", stats.Entries)
+		fmt.Fprintln(w, "# HELP youtube_cache_bytes Size of the cached responses in bytes.")
+		fmt.Fprintln(w, "# TYPE youtube_cache_bytes gauge")
+		fmt.Fprintf(w, "youtube_cache_bytes %d\n", stats.Bytes)
+	}
+}
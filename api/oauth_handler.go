@@ -2,9 +2,13 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
+	"log"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 
@@ -12,42 +16,128 @@ import (
 )
 
 type OAuthHandler struct {
-	oauthService  *service.OAuthService
-	googleService *service.GoogleOAuthService
+	oauthService   *service.OAuthService
+	googleService  *service.GoogleOAuthService
+	youtubeService *service.YouTubeService
+	stateCookie    *stateCookie
 }
 
-func NewOAuthHandler(oauthService *service.OAuthService, googleService *service.GoogleOAuthService) *OAuthHandler {
+func NewOAuthHandler(oauthService *service.OAuthService, googleService *service.GoogleOAuthService, youtubeService *service.YouTubeService) *OAuthHandler {
 	return &OAuthHandler{
-		oauthService:  oauthService,
-		googleService: googleService,
+		oauthService:   oauthService,
+		googleService:  googleService,
+		youtubeService: youtubeService,
+		stateCookie:    newStateCookie(oauthService.PendingAuthorizationTTL(), oauthService.SecureCookies(), googleService.RedirectURL()),
 	}
 }
 
-// Authorize handles the start of the OAuth flow.
-// In our simplified single-user model, this just redirects to Google.
+// Authorize handles the start of the OAuth flow. With a client_id it is the
+// authorization endpoint of the MCP authorization server; without one it is
+// a plain browser login that only stores the Google token (used by the
+// stdio transport, and to grant a further Google scope named by
+// google_scope). Either way the user is sent to log in with Google.
 func (h *OAuthHandler) Authorize(w http.ResponseWriter, r *http.Request) {
-	// The state parameter is not strictly needed for a single-user app, but it's good practice.
-	googleAuthURL := h.googleService.GetAuthURL("state-token")
-	h.renderAuthorizePage(w, googleAuthURL)
+	query := r.URL.Query()
+	if query.Get("client_id") == "" {
+		var scope string
+		if name := query.Get("google_scope"); name != "" {
+			var err error
+			if scope, err = service.ParseGoogleScope(name); err != nil {
+				renderOAuthErrorPage(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+		h.startGoogleLogin(w, h.oauthService.StartLogin(), "", scope, query.Get("login_hint"))
+		return
+	}
+
+	// Until the redirect URI is known to belong to the client, errors must
+	// not be redirected, or the endpoint becomes an open redirector.
+	client, redirectURI, err := h.oauthService.ClientForRedirect(query.Get("client_id"), query.Get("redirect_uri"))
+	if err != nil {
+		renderOAuthErrorPage(w, http.StatusBadRequest, err)
+		return
+	}
+
+	pending, err := h.oauthService.StartAuthorization(client, service.AuthorizationRequest{
+		ClientID:            client.ClientID,
+		RedirectURI:         redirectURI,
+		ResponseType:        query.Get("response_type"),
+		State:               query.Get("state"),
+		Scope:               query.Get("scope"),
+		Resource:            query.Get("resource"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+	})
+	if err != nil {
+		redirectWithError(w, r, redirectURI, query.Get("state"), err)
+		return
+	}
+
+	clientName := client.ClientName
+	if clientName == "" {
+		clientName = "An MCP client"
+	}
+	h.startGoogleLogin(w, pending, clientName, "", "")
+}
+
+// startGoogleLogin binds the pending authorization to the browser and
+// renders the page that sends the user to Google, asking for extraScope
+// besides read-only access if it is set.
+func (h *OAuthHandler) startGoogleLogin(w http.ResponseWriter, pending *service.PendingAuthorization, clientName, extraScope, loginHint string) {
+	w.Header().Set("Cache-Control", "no-store")
+	h.stateCookie.set(w, pending.ID)
+	authURL := h.googleService.GetAuthURL(pending.ID, pending.GoogleVerifier, extraScope, loginHint)
+	h.renderAuthorizePage(w, authURL, clientName, service.DescribeGoogleScope(extraScope))
 }
 
 // GoogleCallback handles the redirect from Google after the user grants consent.
 func (h *OAuthHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 	code := r.URL.Query().Get("code")
 	errorParam := r.URL.Query().Get("error")
+	state := r.URL.Query().Get("state")
+
+	// The state must match the cookie set when this browser started the
+	// login; only then may the pending authorization be used up.
+	if err := h.stateCookie.verify(r, state); err != nil {
+		renderOAuthErrorPage(w, http.StatusBadRequest, err)
+		return
+	}
+	h.stateCookie.clear(w)
+
+	pending, err := h.oauthService.TakePendingAuthorization(state)
+	if err != nil {
+		renderOAuthErrorPage(w, http.StatusBadRequest, fmt.Errorf("%v; please start again", err))
+		return
+	}
 
 	if errorParam != "" {
-		http.Error(w, fmt.Sprintf("OAuth error from Google: %s", errorParam), http.StatusBadRequest)
+		if pending.ClientID != "" {
+			redirectWithError(w, r, pending.RedirectURI, pending.State, &service.OAuthError{Code: "access_denied", Description: "Google login failed: " + errorParam})
+			return
+		}
+		renderOAuthErrorPage(w, http.StatusBadRequest, fmt.Errorf("OAuth error from Google: %s", errorParam))
 		return
 	}
 
 	if code == "" {
-		http.Error(w, "Missing authorization code from Google", http.StatusBadRequest)
+		renderOAuthErrorPage(w, http.StatusBadRequest, fmt.Errorf("missing authorization code from Google"))
+		return
+	}
+
+	identity, err := h.googleService.ExchangeCodeForToken(r.Context(), code, pending.GoogleVerifier)
+	if err != nil {
+		renderOAuthErrorPage(w, http.StatusInternalServerError, fmt.Errorf("failed to exchange Google code for token: %v", err))
 		return
 	}
 
-	if err := h.googleService.ExchangeCodeForToken(r.Context(), code); err != nil {
-		http.Error(w, fmt.Sprintf("Failed to exchange Google code for token: %v", err), http.StatusInternalServerError)
+	// Hand the MCP client its authorization code.
+	if pending.ClientID != "" {
+		params := url.Values{"code": {h.oauthService.IssueAuthorizationCode(pending, *identity)}}
+		if pending.State != "" {
+			params.Set("state", pending.State)
+		}
+		http.Redirect(w, r, appendQuery(pending.RedirectURI, params), http.StatusFound)
 		return
 	}
 
@@ -68,31 +158,240 @@ func (h *OAuthHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 	`)
 }
 
-// RequireAuth is a middleware that checks if the user is authenticated with Google.
+// Register handles RFC 7591 dynamic client registration.
+func (h *OAuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	var req service.ClientRegistration
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+		writeOAuthError(w, &service.OAuthError{Code: "invalid_client_metadata", Description: "request body is not valid JSON", Status: http.StatusBadRequest})
+		return
+	}
+
+	client, err := h.oauthService.RegisterClient(req)
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusCreated, client)
+}
+
+// Token handles the token endpoint for the authorization_code and
+// refresh_token grants.
+func (h *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+
+	var tokens *service.TokenResponse
+	var err error
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		tokens, err = h.oauthService.ExchangeAuthorizationCode(client, r.PostForm.Get("code"), r.PostForm.Get("redirect_uri"), r.PostForm.Get("code_verifier"))
+	case "refresh_token":
+		tokens, err = h.oauthService.RefreshAccessToken(client, r.PostForm.Get("refresh_token"), r.PostForm.Get("scope"))
+	default:
+		err = &service.OAuthError{Code: "unsupported_grant_type", Description: "grant_type must be authorization_code or refresh_token", Status: http.StatusBadRequest}
+	}
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+// Revoke handles RFC 7009 token revocation for access and refresh tokens
+// issued by our authorization server.
+func (h *OAuthHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.authenticateClient(w, r)
+	if !ok {
+		return
+	}
+	token := r.PostForm.Get("token")
+	if token == "" {
+		writeOAuthError(w, &service.OAuthError{Code: "invalid_request", Description: "token is required", Status: http.StatusBadRequest})
+		return
+	}
+
+	h.oauthService.RevokeToken(client, token)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+}
+
+// Logout disconnects the caller's Google account: the Google token is
+// revoked at Google and removed from the store, and every token our
+// authorization server issued for the account is revoked.
+func (h *OAuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	principal, _ := service.PrincipalFromContext(r.Context())
+
+	googleRevoked := true
+	if err := h.youtubeService.Logout(r.Context(), principal.Subject); err != nil {
+		log.Printf("Logout of %s: %v", principal.Email, err)
+		googleRevoked = false
+	}
+	h.oauthService.RevokeUser(principal.Subject)
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"logged_out":     true,
+		"google_revoked": googleRevoked,
+	})
+}
+
+// Status reports the Google account connected for the caller.
+func (h *OAuthHandler) Status(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, h.googleService.Status(r.Context()))
+}
+
+// authenticateClient parses the form body of a token or revocation request
+// and authenticates the client with HTTP Basic or form credentials, writing
+// the error response if that fails.
+func (h *OAuthHandler) authenticateClient(w http.ResponseWriter, r *http.Request) (*service.OAuthClient, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, 64<<10)
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, &service.OAuthError{Code: "invalid_request", Description: "request body is not a valid form", Status: http.StatusBadRequest})
+		return nil, false
+	}
+
+	clientID, clientSecret, basic := r.BasicAuth()
+	if basic {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	client, err := h.oauthService.AuthenticateClient(clientID, clientSecret)
+	if err != nil {
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		writeOAuthError(w, err)
+		return nil, false
+	}
+	return client, true
+}
+
+// RequireAuth is a middleware that requires a valid bearer token issued by
+// our authorization server, and puts its principal in the request context.
 func (h *OAuthHandler) RequireAuth(next http.Handler) http.Handler {
+	return h.authenticate(next, true)
+}
+
+// OptionalAuth is like RequireAuth, but lets requests without an
+// Authorization header through anonymously. A token that is sent must
+// still be valid.
+func (h *OAuthHandler) OptionalAuth(next http.Handler) http.Handler {
+	return h.authenticate(next, false)
+}
+
+func (h *OAuthHandler) authenticate(next http.Handler, required bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !h.googleService.IsAuthenticated() {
-			h.sendUnauthorized(w, r)
+		authorization := r.Header.Get("Authorization")
+		if authorization == "" {
+			if !required {
+				next.ServeHTTP(w, r)
+				return
+			}
+			h.sendUnauthorized(w, "", "")
+			return
+		}
+		scheme, token, _ := strings.Cut(authorization, " ")
+		if !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			h.sendUnauthorized(w, "invalid_request", "Authorization header must use the Bearer scheme")
+			return
+		}
+
+		principal, err := h.oauthService.ValidateAccessToken(strings.TrimSpace(token))
+		if err != nil {
+			h.sendUnauthorized(w, "invalid_token", err.Error())
 			return
 		}
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(service.WithPrincipal(r.Context(), principal)))
 	})
 }
 
-// sendUnauthorized sends a 401 response, prompting the user to authenticate.
-func (h *OAuthHandler) sendUnauthorized(w http.ResponseWriter, r *http.Request) {
-	// In this simplified model, we don't need complex WWW-Authenticate headers.
-	// We can just return a simple error message.
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusUnauthorized)
-	json.NewEncoder(w).Encode(map[string]string{
-		"error":             "unauthorized",
-		"error_description": "Not authenticated with Google. Please visit /oauth/authorize to log in.",
+// sendUnauthorized sends a 401 response whose WWW-Authenticate challenge
+// points the client at our protected resource metadata (RFC 9728), from
+// which it discovers the authorization server.
+func (h *OAuthHandler) sendUnauthorized(w http.ResponseWriter, errorCode, description string) {
+	challenge := fmt.Sprintf(`Bearer resource_metadata=%q`, h.oauthService.ProtectedResourceMetadataURL())
+	if errorCode != "" {
+		challenge += fmt.Sprintf(`, error=%q, error_description=%q`, errorCode, description)
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+
+	if description == "" {
+		description = "An access token is required. Obtain one through the authorization server advertised in the resource metadata."
+	}
+	if errorCode == "" {
+		errorCode = "unauthorized"
+	}
+	writeJSON(w, http.StatusUnauthorized, map[string]string{
+		"error":             errorCode,
+		"error_description": description,
 	})
 }
 
+// writeOAuthError writes an OAuth error response (RFC 6749 section 5.2).
+func writeOAuthError(w http.ResponseWriter, err error) {
+	var oauthErr *service.OAuthError
+	if !errors.As(err, &oauthErr) {
+		oauthErr = &service.OAuthError{Code: "server_error", Description: err.Error(), Status: http.StatusInternalServerError}
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, oauthErr.Status, oauthErr)
+}
+
+// redirectWithError reports an authorization error to the client's redirect URI.
+func redirectWithError(w http.ResponseWriter, r *http.Request, redirectURI, state string, err error) {
+	var oauthErr *service.OAuthError
+	if !errors.As(err, &oauthErr) {
+		oauthErr = &service.OAuthError{Code: "server_error", Description: err.Error()}
+	}
+	params := url.Values{"error": {oauthErr.Code}}
+	if oauthErr.Description != "" {
+		params.Set("error_description", oauthErr.Description)
+	}
+	if state != "" {
+		params.Set("state", state)
+	}
+	http.Redirect(w, r, appendQuery(redirectURI, params), http.StatusFound)
+}
+
+// appendQuery adds params to the query of uri, keeping any it already has.
+func appendQuery(uri string, params url.Values) string {
+	if strings.Contains(uri, "?") {
+		return uri + "&" + params.Encode()
+	}
+	return uri + "?" + params.Encode()
+}
+
+// renderOAuthErrorPage shows an authorization error to the user in the browser.
+func renderOAuthErrorPage(w http.ResponseWriter, status int, err error) {
+	tmpl := template.Must(template.New("error").Parse(`
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<title>Authorization Failed</title>
+		<style>body{font-family: Arial, sans-serif; max-width: 600px; margin: 100px auto; text-align: center;}</style>
+	</head>
+	<body>
+		<h1>❌ Authorization Failed</h1>
+		<p>{{.}}</p>
+	</body>
+	</html>
+	`))
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(status)
+	tmpl.Execute(w, err.Error())
+}
+
 // renderAuthorizePage renders the page that prompts the user to log in.
-func (h *OAuthHandler) renderAuthorizePage(w http.ResponseWriter, googleAuthURL string) {
+func (h *OAuthHandler) renderAuthorizePage(w http.ResponseWriter, googleAuthURL string, clientName string, permission string) {
 	tmpl := `
 	<!DOCTYPE html>
 	<html>
@@ -114,7 +413,9 @@ func (h *OAuthHandler) renderAuthorizePage(w http.ResponseWriter, googleAuthURL
 	</head>
 	<body>
 		<h1>YouTube MCP Server Authorization</h1>
-		<p>This server needs permission to access YouTube transcripts on your behalf.</p>
+		{{if .ClientName}}<p><strong>{{.ClientName}}</strong> is asking to use this server's YouTube tools on your behalf.</p>{{end}}
+		{{if .Permission}}<p>This server needs additional permission to <strong>{{.Permission}}</strong> on your behalf.</p>
+		{{else}}<p>This server needs permission to view your YouTube account on your behalf.</p>{{end}}
 		<p>Click the button below to sign in with your Google account.</p>
 		<a href="{{.GoogleAuthURL}}" class="button">Authorize with Google</a>
 	</body>
@@ -128,21 +429,27 @@ func (h *OAuthHandler) renderAuthorizePage(w http.ResponseWriter, googleAuthURL
 	}
 
 	w.Header().Set("Content-Type", "text/html")
-	t.Execute(w, map[string]interface{}{"GoogleAuthURL": googleAuthURL})
+	t.Execute(w, map[string]interface{}{"GoogleAuthURL": googleAuthURL, "ClientName": clientName, "Permission": permission})
 }
 
-// SetupOAuthRoutes sets up the simplified OAuth-related routes.
+// SetupOAuthRoutes sets up the discovery, authorization server and Google
+// login routes.
 func SetupOAuthRoutes(r chi.Router, handler *OAuthHandler) {
-	// These discovery endpoints are not strictly necessary for our simplified flow,
-	// but we keep them for potential future compatibility.
 	r.Get("/.well-known/oauth-protected-resource", func(w http.ResponseWriter, r *http.Request) {
-		json.NewEncoder(w).Encode(handler.oauthService.GetProtectedResourceMetadata())
+		writeJSON(w, http.StatusOK, handler.oauthService.GetProtectedResourceMetadata())
 	})
 	r.Get("/.well-known/oauth-authorization-server", func(w http.ResponseWriter, r *http.Request) {
-		json.NewEncoder(w).Encode(handler.oauthService.GetAuthServerMetadata())
+		writeJSON(w, http.StatusOK, handler.oauthService.GetAuthServerMetadata())
 	})
 
 	// OAuth flow endpoints
 	r.Get("/oauth/authorize", handler.Authorize)
 	r.Get("/oauth/callback", handler.GoogleCallback)
+	r.Post("/oauth/register", handler.Register)
+	r.Post("/oauth/token", handler.Token)
+	r.Post("/oauth/revoke", handler.Revoke)
+
+	// Account endpoints act on the caller's own Google account.
+	r.With(handler.RequireAuth).Post("/oauth/logout", handler.Logout)
+	r.With(handler.RequireAuth).Get("/oauth/status", handler.Status)
 }
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stateCookieName is the cookie that binds a Google login to the browser
+// that started it.
+const stateCookieName = "yt_mcp_oauth_state"
+
+// stateCookie signs the OAuth state into a short-lived cookie, so that the
+// Google callback is only accepted in the browser that started the login.
+// Without it, an attacker could send a victim their own callback URL and
+// bind the attacker's Google account to the victim's client.
+type stateCookie struct {
+	key    []byte
+	maxAge time.Duration
+	secure bool
+	// path is the path of the Google redirect URI, the only request the
+	// cookie is sent with.
+	path string
+	now  func() time.Time
+}
+
+// newStateCookie creates a stateCookie for logins that return to
+// redirectURI, with a random signing key. Pending logins do not survive a
+// restart, so neither needs the key.
+func newStateCookie(maxAge time.Duration, secure bool, redirectURI string) *stateCookie {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("failed to generate state cookie key: %v", err))
+	}
+	return &stateCookie{key: key, maxAge: maxAge, secure: secure, path: callbackPath(redirectURI), now: time.Now}
+}
+
+// callbackPath returns the path of the Google redirect URI, which may sit
+// below a prefix when the server runs behind a reverse proxy.
+func callbackPath(redirectURI string) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil || u.Path == "" {
+		return "/oauth/callback"
+	}
+	return u.Path
+}
+
+// set stores the signed state in the browser, together with when it
+// expires, as the browser cannot be relied on to drop it in time.
+func (c *stateCookie) set(w http.ResponseWriter, state string) {
+	value := state + "." + strconv.FormatInt(c.now().Add(c.maxAge).Unix(), 10)
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookieName,
+		Value:    value + "." + c.sign(value),
+		Path:     c.path,
+		MaxAge:   int(c.maxAge.Seconds()),
+		Secure:   c.secure,
+		HttpOnly: true,
+		// Lax, so the cookie is sent on the top-level redirect back from Google.
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// verify checks that the browser holds a validly signed cookie for state.
+func (c *stateCookie) verify(r *http.Request, state string) error {
+	if state == "" {
+		return fmt.Errorf("the response from Google did not include a state parameter")
+	}
+	cookie, err := r.Cookie(stateCookieName)
+	if err != nil {
+		return fmt.Errorf("this browser did not start the login, or it took longer than %v; please start again", c.maxAge)
+	}
+	i := strings.LastIndexByte(cookie.Value, '.')
+	if i < 0 || !hmac.Equal([]byte(cookie.Value[i+1:]), []byte(c.sign(cookie.Value[:i]))) {
+		return fmt.Errorf("the login cookie is invalid; please start again")
+	}
+	value, expiry, _ := strings.Cut(cookie.Value[:i], ".")
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || !c.now().Before(time.Unix(expiresAt, 0)) {
+		return fmt.Errorf("the login took longer than %v; please start again", c.maxAge)
+	}
+	if !hmac.Equal([]byte(value), []byte(state)) {
+		return fmt.Errorf("the response from Google does not belong to the login started in this browser; please start again")
+	}
+	return nil
+}
+
+// clear removes the cookie once the login is complete.
+func (c *stateCookie) clear(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookieName,
+		Path:     c.path,
+		MaxAge:   -1,
+		Secure:   c.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (c *stateCookie) sign(value string) string {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// issueStateCookie sets the cookie for state and returns it as the browser
+// would send it back.
+func issueStateCookie(t *testing.T, c *stateCookie, state string) *http.Cookie {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	c.set(rec, state)
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("set wrote %d cookies, want 1", len(cookies))
+	}
+	return cookies[0]
+}
+
+func callbackRequest(cookie *http.Cookie) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/oauth/callback", nil)
+	if cookie != nil {
+		r.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	return r
+}
+
+func TestStateCookie(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := newStateCookie(10*time.Minute, true, "https://mcp.example/oauth/callback")
+	c.now = func() time.Time { return now }
+	cookie := issueStateCookie(t, c, "state-a")
+
+	if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("cookie attributes = %+v", cookie)
+	}
+	if err := c.verify(callbackRequest(cookie), "state-a"); err != nil {
+		t.Fatalf("signed cookie rejected: %v", err)
+	}
+
+	tampered := *cookie
+	tampered.Value = strings.Replace(cookie.Value, "state-a", "state-b", 1)
+	other := newStateCookie(10*time.Minute, true, "")
+	foreign := issueStateCookie(t, other, "state-a")
+
+	tests := []struct {
+		name   string
+		cookie *http.Cookie
+		state  string
+	}{
+		{"no cookie", nil, "state-a"},
+		{"no state", cookie, ""},
+		{"mismatched state", cookie, "state-b"},
+		{"tampered state", &tampered, "state-b"},
+		{"tampered signature", &http.Cookie{Name: stateCookieName, Value: cookie.Value + "x"}, "state-a"},
+		{"signed with another key", foreign, "state-a"},
+		{"unsigned", &http.Cookie{Name: stateCookieName, Value: "state-a"}, "state-a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := c.verify(callbackRequest(tt.cookie), tt.state); err == nil {
+				t.Error("verify accepted the cookie")
+			}
+		})
+	}
+
+	t.Run("expired", func(t *testing.T) {
+		now = now.Add(10*time.Minute + time.Second)
+		if err := c.verify(callbackRequest(cookie), "state-a"); err == nil {
+			t.Error("verify accepted an expired cookie")
+		}
+	})
+}
+
+func TestStateCookiePathFollowsRedirectURI(t *testing.T) {
+	tests := []struct {
+		redirectURI string
+		want        string
+	}{
+		{"http://localhost:8080/oauth/callback", "/oauth/callback"},
+		{"https://example.com/youtube/oauth/callback", "/youtube/oauth/callback"},
+		{"https://example.com/auth/google", "/auth/google"},
+		{"", "/oauth/callback"},
+	}
+	for _, tt := range tests {
+		c := newStateCookie(time.Minute, false, tt.redirectURI)
+		if got := issueStateCookie(t, c, "state").Path; got != tt.want {
+			t.Errorf("cookie path for %q = %q, want %q", tt.redirectURI, got, tt.want)
+		}
+		rec := httptest.NewRecorder()
+		c.clear(rec)
+		if got := rec.Result().Cookies()[0].Path; got != tt.want {
+			t.Errorf("cleared cookie path for %q = %q, want %q", tt.redirectURI, got, tt.want)
+		}
+	}
+}
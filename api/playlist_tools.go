@@ -0,0 +1,294 @@
+package api
+
+import (
+	"context"
+
+	"github.com/yt-mcp-server/service"
+)
+
+var (
+	playlistItemIDSchema = map[string]interface{}{
+		"type":        "string",
+		"description": "The ID of the playlist item (not the video ID).",
+		"minLength":   1,
+	}
+	privacySchema = map[string]interface{}{
+		"type":        "string",
+		"description": "Optional: Who can see the playlist (default for new playlists: private).",
+		"enum":        []string{"public", "unlisted", "private"},
+	}
+)
+
+// idResultSchema is the output schema of tools that only report the ID of
+// what they acted on.
+func idResultSchema(property string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{property: map[string]interface{}{"type": "string"}},
+		"required":   []string{property},
+	}
+}
+
+// registerPlaylistTools registers the playlist management tools.
+func (t *youtubeTools) registerPlaylistTools(registry *ToolRegistry) error {
+	readOnly := &ToolAnnotations{ReadOnlyHint: boolPtr(true), OpenWorldHint: boolPtr(true)}
+
+	if err := RegisterTool(registry, Tool{
+		Name:        "list_my_playlists",
+		Cached:      true,
+		Description: "Lists the playlists of the authenticated channel.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": playlistShape.withShapeArgs(map[string]interface{}{
+				"cursor": cursorSchema,
+				"limit":  map[string]interface{}{"type": "integer", "description": "Optional: Max number of results (default: 25).", "minimum": 1, "maximum": 50},
+			}),
+		},
+		OutputSchema: playlistShape.listSchema(),
+		Annotations:  readOnly,
+	}, t.listMyPlaylists); err != nil {
+		return err
+	}
+
+	if err := RegisterTool(registry, Tool{
+		Name:        "list_playlist_items",
+		Public:      true,
+		Cached:      true,
+		Description: "Lists the videos in a playlist, one page at a time, or up to max_total videos across pages.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": playlistItemShape.withShapeArgs(map[string]interface{}{
+				"playlist_id": playlistIDSchema,
+				"cursor":      cursorSchema,
+				"limit":       map[string]interface{}{"type": "integer", "description": "Optional: Max number of results per page (default: 25). Ignored when max_total is set.", "minimum": 1, "maximum": 50},
+				"max_total":   map[string]interface{}{"type": "integer", "description": "Optional: Follow pages until this many videos are collected (each page costs quota). Takes precedence over limit.", "minimum": 1, "maximum": 1000},
+			}),
+			"required": []string{"playlist_id"},
+		},
+		OutputSchema: playlistItemShape.listSchema(),
+		Annotations:  readOnly,
+	}, t.listPlaylistItems); err != nil {
+		return err
+	}
+
+	if err := RegisterTool(registry, Tool{
+		Name:        "create_playlist",
+		Description: "Creates a new playlist on the authenticated channel.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": playlistShape.withShapeArgs(map[string]interface{}{
+				"title":       map[string]interface{}{"type": "string", "description": "The playlist title.", "minLength": 1, "maxLength": 150},
+				"description": map[string]interface{}{"type": "string", "description": "Optional: The playlist description.", "maxLength": 5000},
+				"privacy":     privacySchema,
+			}),
+			"required": []string{"title"},
+		},
+		OutputSchema: playlistShape.itemSchema(),
+		Annotations:  &ToolAnnotations{ReadOnlyHint: boolPtr(false), DestructiveHint: boolPtr(false), IdempotentHint: boolPtr(false), OpenWorldHint: boolPtr(true)},
+	}, t.createPlaylist); err != nil {
+		return err
+	}
+
+	if err := RegisterTool(registry, Tool{
+		Name:        "update_playlist",
+		Description: "Changes the title, description or privacy of a playlist. Owner-only.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": playlistShape.withShapeArgs(map[string]interface{}{
+				"playlist_id": playlistIDSchema,
+				"title":       map[string]interface{}{"type": "string", "description": "Optional: The new title.", "minLength": 1, "maxLength": 150},
+				"description": map[string]interface{}{"type": "string", "description": "Optional: The new description.", "maxLength": 5000},
+				"privacy":     privacySchema,
+			}),
+			"required": []string{"playlist_id"},
+		},
+		OutputSchema: playlistShape.itemSchema(),
+		Annotations:  &ToolAnnotations{ReadOnlyHint: boolPtr(false), DestructiveHint: boolPtr(false), IdempotentHint: boolPtr(true), OpenWorldHint: boolPtr(true)},
+	}, t.updatePlaylist); err != nil {
+		return err
+	}
+
+	if err := RegisterTool(registry, Tool{
+		Name:        "delete_playlist",
+		Description: "Permanently deletes a playlist. Owner-only.",
+		InputSchema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"playlist_id": playlistIDSchema},
+			"required":   []string{"playlist_id"},
+		},
+		OutputSchema: idResultSchema("deleted_playlist_id"),
+		Annotations:  &ToolAnnotations{ReadOnlyHint: boolPtr(false), DestructiveHint: boolPtr(true), IdempotentHint: boolPtr(true), OpenWorldHint: boolPtr(true)},
+	}, t.deletePlaylist); err != nil {
+		return err
+	}
+
+	if err := RegisterTool(registry, Tool{
+		Name:        "remove_playlist_item",
+		Description: "Removes an item from a playlist. Owner-only.",
+		InputSchema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"playlist_item_id": playlistItemIDSchema},
+			"required":   []string{"playlist_item_id"},
+		},
+		OutputSchema: idResultSchema("removed_playlist_item_id"),
+		Annotations:  &ToolAnnotations{ReadOnlyHint: boolPtr(false), DestructiveHint: boolPtr(true), IdempotentHint: boolPtr(true), OpenWorldHint: boolPtr(true)},
+	}, t.removePlaylistItem); err != nil {
+		return err
+	}
+
+	if err := RegisterTool(registry, Tool{
+		Name:        "move_playlist_item",
+		Description: "Moves a playlist item to a new position. Owner-only.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": playlistItemShape.withShapeArgs(map[string]interface{}{
+				"playlist_item_id": playlistItemIDSchema,
+				"position":         map[string]interface{}{"type": "integer", "description": "The new zero-based position in the playlist.", "minimum": 0},
+			}),
+			"required": []string{"playlist_item_id", "position"},
+		},
+		OutputSchema: playlistItemShape.itemSchema(),
+		Annotations:  &ToolAnnotations{ReadOnlyHint: boolPtr(false), DestructiveHint: boolPtr(false), IdempotentHint: boolPtr(true), OpenWorldHint: boolPtr(true)},
+	}, t.movePlaylistItem); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type listMyPlaylistsArgs struct {
+	Cursor string `json:"cursor"`
+	Limit  int64  `json:"limit"`
+	shapeArgs
+}
+
+func (t *youtubeTools) listMyPlaylists(ctx context.Context, args listMyPlaylistsArgs) (interface{}, error) {
+	if args.Limit == 0 {
+		args.Limit = 25
+	}
+	scope := newCursorScope("list_my_playlists")
+	pageToken, err := decodeCursor(scope, args.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	playlists, err := t.youtubeService.ListMyPlaylists(ctx, pageToken, args.Limit)
+	if err != nil {
+		return nil, apiError(err)
+	}
+	summaries := make([]PlaylistSummary, 0, len(playlists.Items))
+	for _, playlist := range playlists.Items {
+		summaries = append(summaries, newPlaylistSummary(playlist))
+	}
+	return playlistShape.list(summaries, args.shapeArgs, encodeCursor(scope, playlists.NextPageToken), 0)
+}
+
+type listPlaylistItemsArgs struct {
+	PlaylistID string `json:"playlist_id"`
+	Cursor     string `json:"cursor"`
+	Limit      int64  `json:"limit"`
+	MaxTotal   int64  `json:"max_total"`
+	shapeArgs
+}
+
+func (t *youtubeTools) listPlaylistItems(ctx context.Context, args listPlaylistItemsArgs) (interface{}, error) {
+	if args.Limit == 0 {
+		args.Limit = 25
+	}
+	scope := newCursorScope("list_playlist_items", args.PlaylistID)
+	page, err := pageRequest(scope, args.Cursor, args.Limit, args.MaxTotal, 50)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := t.youtubeService.ListPlaylistItems(ctx, args.PlaylistID, page)
+	if err != nil {
+		return nil, apiError(err)
+	}
+	return playlistItemList(items, args.shapeArgs, scope)
+}
+
+// playlistItemList shapes a page of playlist items, binding its next
+// cursor to scope.
+func playlistItemList(items *service.PlaylistItemPage, args shapeArgs, scope cursorScope) (*ToolOutput, error) {
+	summaries := make([]PlaylistItemSummary, 0, len(items.Items))
+	for _, item := range items.Items {
+		summaries = append(summaries, newPlaylistItemSummary(item))
+	}
+	return playlistItemShape.list(summaries, args, encodeCursor(scope, items.NextPageToken), items.TotalResults)
+}
+
+type createPlaylistArgs struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Privacy     string `json:"privacy"`
+	shapeArgs
+}
+
+func (t *youtubeTools) createPlaylist(ctx context.Context, args createPlaylistArgs) (interface{}, error) {
+	if args.Privacy == "" {
+		args.Privacy = "private"
+	}
+
+	playlist, err := t.youtubeService.CreatePlaylist(ctx, args.Title, args.Description, args.Privacy)
+	if err != nil {
+		return nil, apiError(err)
+	}
+	return playlistShape.one(newPlaylistSummary(playlist), args.shapeArgs)
+}
+
+type updatePlaylistArgs struct {
+	PlaylistID  string  `json:"playlist_id"`
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	Privacy     *string `json:"privacy"`
+	shapeArgs
+}
+
+func (t *youtubeTools) updatePlaylist(ctx context.Context, args updatePlaylistArgs) (interface{}, error) {
+	playlist, err := t.youtubeService.UpdatePlaylist(ctx, args.PlaylistID, service.PlaylistUpdate{
+		Title:       args.Title,
+		Description: args.Description,
+		Privacy:     args.Privacy,
+	})
+	if err != nil {
+		return nil, ownerActionError(err)
+	}
+	return playlistShape.one(newPlaylistSummary(playlist), args.shapeArgs)
+}
+
+type deletePlaylistArgs struct {
+	PlaylistID string `json:"playlist_id"`
+}
+
+func (t *youtubeTools) deletePlaylist(ctx context.Context, args deletePlaylistArgs) (interface{}, error) {
+	if err := t.youtubeService.DeletePlaylist(ctx, args.PlaylistID); err != nil {
+		return nil, ownerActionError(err)
+	}
+	return map[string]string{"deleted_playlist_id": args.PlaylistID}, nil
+}
+
+type removePlaylistItemArgs struct {
+	PlaylistItemID string `json:"playlist_item_id"`
+}
+
+func (t *youtubeTools) removePlaylistItem(ctx context.Context, args removePlaylistItemArgs) (interface{}, error) {
+	if err := t.youtubeService.RemovePlaylistItem(ctx, args.PlaylistItemID); err != nil {
+		return nil, ownerActionError(err)
+	}
+	return map[string]string{"removed_playlist_item_id": args.PlaylistItemID}, nil
+}
+
+type movePlaylistItemArgs struct {
+	PlaylistItemID string `json:"playlist_item_id"`
+	Position       int64  `json:"position"`
+	shapeArgs
+}
+
+func (t *youtubeTools) movePlaylistItem(ctx context.Context, args movePlaylistItemArgs) (interface{}, error) {
+	item, err := t.youtubeService.MovePlaylistItem(ctx, args.PlaylistItemID, args.Position)
+	if err != nil {
+		return nil, ownerActionError(err)
+	}
+	return playlistItemShape.one(newPlaylistItemSummary(item), args.shapeArgs)
+}
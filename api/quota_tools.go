@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/yt-mcp-server/service"
+)
+
+// quotaUsageArgs takes no arguments.
+type quotaUsageArgs struct{}
+
+// registerQuotaTools registers the quota reporting tool.
+func (t *youtubeTools) registerQuotaTools(registry *ToolRegistry) error {
+	return RegisterTool(registry, Tool{
+		Name:        "get_quota_usage",
+		Public:      true,
+		Description: "Shows how much of the daily YouTube API quota budget is used, by API method, and when it resets. Searches cost 100 units, changes 50, other reads 1.",
+		InputSchema: map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+		OutputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"day":       map[string]interface{}{"type": "string"},
+				"used":      map[string]interface{}{"type": "integer"},
+				"budget":    map[string]interface{}{"type": "integer"},
+				"remaining": map[string]interface{}{"type": "integer"},
+				"refused":   map[string]interface{}{"type": "integer"},
+				"resets_at": map[string]interface{}{"type": "string", "format": "date-time"},
+				"methods": map[string]interface{}{
+					"type": "object",
+					"additionalProperties": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"calls": map[string]interface{}{"type": "integer"},
+							"units": map[string]interface{}{"type": "integer"},
+							"revalidations": map[string]interface{}{
+								"type":        "integer",
+								"description": "Calls, included in calls and units, that revalidated a cached response and were answered 304 Not Modified",
+							},
+						},
+					},
+				},
+			},
+			"required": []string{"day", "used", "budget"},
+		},
+		Annotations: &ToolAnnotations{ReadOnlyHint: boolPtr(true), OpenWorldHint: boolPtr(false)},
+	}, t.getQuotaUsage)
+}
+
+func (t *youtubeTools) getQuotaUsage(ctx context.Context, args quotaUsageArgs) (interface{}, error) {
+	usage := t.youtubeService.QuotaUsage()
+	return &ToolOutput{Text: renderQuotaUsage(usage), Data: usage}, nil
+}
+
+func renderQuotaUsage(usage *service.QuotaUsage) string {
+	var b strings.Builder
+	if usage.Budget > 0 {
+		fmt.Fprintf(&b, "Used %d of %d quota units today (%d remaining).", usage.Used, usage.Budget, usage.Remaining)
+	} else {
+		fmt.Fprintf(&b, "Used %d quota units today (no budget set).", usage.Used)
+	}
+	fmt.Fprintf(&b, " Resets at %s.", usage.ResetsAt.Format("2006-01-02 15:04 MST"))
+	if usage.Refused > 0 {
+		fmt.Fprintf(&b, "\n%d calls were refused to stay within the budget.", usage.Refused)
+	}
+	for _, method := range usage.SortedMethods() {
+		m := usage.Methods[method]
+		fmt.Fprintf(&b, "\n- %s: %d calls, %d units", method, m.Calls, m.Units)
+		if m.Revalidations > 0 {
+			fmt.Fprintf(&b, " (%d of the calls revalidated cached responses)", m.Revalidations)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,308 @@
+package api
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// The summary types below are the curated, LLM-friendly views of YouTube
+// resources returned by the tools. They drop etags, kinds and the many
+// thumbnail sizes of the raw API structs. Counts are pointers so that an
+// unknown count is omitted rather than reported as zero.
+
+// VideoSummary describes a video.
+type VideoSummary struct {
+	ID              string   `json:"id"`
+	Title           string   `json:"title,omitempty"`
+	ChannelTitle    string   `json:"channel_title,omitempty"`
+	ChannelID       string   `json:"channel_id,omitempty"`
+	PublishedAt     string   `json:"published_at,omitempty"`
+	Duration        string   `json:"duration,omitempty"`
+	DurationSeconds *int64   `json:"duration_seconds,omitempty"`
+	ViewCount       *uint64  `json:"view_count,omitempty"`
+	LikeCount       *uint64  `json:"like_count,omitempty"`
+	CommentCount    *uint64  `json:"comment_count,omitempty"`
+	Description     string   `json:"description,omitempty"`
+	Tags            []string `json:"tags,omitempty"`
+	LiveBroadcast   string   `json:"live_broadcast,omitempty"`
+	Thumbnail       string   `json:"thumbnail,omitempty"`
+	URL             string   `json:"url"`
+}
+
+// CommentSummary describes a top-level comment or a reply.
+type CommentSummary struct {
+	ID              string           `json:"id"`
+	Author          string           `json:"author,omitempty"`
+	AuthorChannelID string           `json:"author_channel_id,omitempty"`
+	Text            string           `json:"text,omitempty"`
+	LikeCount       int64            `json:"like_count"`
+	PublishedAt     string           `json:"published_at,omitempty"`
+	UpdatedAt       string           `json:"updated_at,omitempty"`
+	ReplyCount      *int64           `json:"reply_count,omitempty"`
+	VideoID         string           `json:"video_id,omitempty"`
+	ParentID        string           `json:"parent_id,omitempty"`
+	Replies         []CommentSummary `json:"replies,omitempty"`
+}
+
+// PlaylistSummary describes a playlist.
+type PlaylistSummary struct {
+	ID           string `json:"id"`
+	Title        string `json:"title,omitempty"`
+	ItemCount    *int64 `json:"item_count,omitempty"`
+	Privacy      string `json:"privacy,omitempty"`
+	ChannelTitle string `json:"channel_title,omitempty"`
+	PublishedAt  string `json:"published_at,omitempty"`
+	Description  string `json:"description,omitempty"`
+	URL          string `json:"url"`
+}
+
+// PlaylistItemSummary describes an entry of a playlist.
+type PlaylistItemSummary struct {
+	ID           string `json:"id"`
+	VideoID      string `json:"video_id,omitempty"`
+	Title        string `json:"title,omitempty"`
+	Position     int64  `json:"position"`
+	ChannelTitle string `json:"channel_title,omitempty"`
+	PublishedAt  string `json:"published_at,omitempty"`
+	Description  string `json:"description,omitempty"`
+	URL          string `json:"url,omitempty"`
+}
+
+// ChannelSummary describes a channel.
+type ChannelSummary struct {
+	ID                string  `json:"id"`
+	Title             string  `json:"title,omitempty"`
+	Handle            string  `json:"handle,omitempty"`
+	SubscriberCount   *uint64 `json:"subscriber_count,omitempty"`
+	VideoCount        *uint64 `json:"video_count,omitempty"`
+	ViewCount         *uint64 `json:"view_count,omitempty"`
+	Country           string  `json:"country,omitempty"`
+	PublishedAt       string  `json:"published_at,omitempty"`
+	UploadsPlaylistID string  `json:"uploads_playlist_id,omitempty"`
+	Description       string  `json:"description,omitempty"`
+	URL               string  `json:"url"`
+}
+
+func newVideoSummary(video *youtube.Video) VideoSummary {
+	summary := VideoSummary{ID: video.Id, URL: videoURL(video.Id)}
+	if snippet := video.Snippet; snippet != nil {
+		summary.Title = snippet.Title
+		summary.ChannelTitle = snippet.ChannelTitle
+		summary.ChannelID = snippet.ChannelId
+		summary.PublishedAt = snippet.PublishedAt
+		summary.Description = snippet.Description
+		summary.Tags = snippet.Tags
+		summary.Thumbnail = bestThumbnail(snippet.Thumbnails)
+		if snippet.LiveBroadcastContent != "none" {
+			summary.LiveBroadcast = snippet.LiveBroadcastContent
+		}
+	}
+	if details := video.ContentDetails; details != nil && details.Duration != "" {
+		if seconds, ok := parseISODuration(details.Duration); ok {
+			summary.Duration = formatDuration(seconds)
+			summary.DurationSeconds = &seconds
+		}
+	}
+	if stats := video.Statistics; stats != nil {
+		summary.ViewCount = &stats.ViewCount
+		summary.LikeCount = &stats.LikeCount
+		summary.CommentCount = &stats.CommentCount
+	}
+	return summary
+}
+
+func newSearchResultSummary(result *youtube.SearchResult) VideoSummary {
+	var summary VideoSummary
+	if result.Id != nil {
+		summary.ID = result.Id.VideoId
+		summary.URL = videoURL(result.Id.VideoId)
+	}
+	if snippet := result.Snippet; snippet != nil {
+		summary.Title = html.UnescapeString(snippet.Title)
+		summary.ChannelTitle = snippet.ChannelTitle
+		summary.ChannelID = snippet.ChannelId
+		summary.PublishedAt = snippet.PublishedAt
+		summary.Description = html.UnescapeString(snippet.Description)
+		summary.Thumbnail = bestThumbnail(snippet.Thumbnails)
+		if snippet.LiveBroadcastContent != "none" {
+			summary.LiveBroadcast = snippet.LiveBroadcastContent
+		}
+	}
+	return summary
+}
+
+func newCommentThreadSummary(thread *youtube.CommentThread) CommentSummary {
+	var summary CommentSummary
+	if thread.Snippet != nil && thread.Snippet.TopLevelComment != nil {
+		summary = newCommentSummary(thread.Snippet.TopLevelComment)
+		replies := thread.Snippet.TotalReplyCount
+		summary.ReplyCount = &replies
+		summary.VideoID = thread.Snippet.VideoId
+	}
+	summary.ID = thread.Id
+	if thread.Replies != nil {
+		for _, reply := range thread.Replies.Comments {
+			summary.Replies = append(summary.Replies, newCommentSummary(reply))
+		}
+	}
+	return summary
+}
+
+func newCommentSummary(comment *youtube.Comment) CommentSummary {
+	summary := CommentSummary{ID: comment.Id}
+	if snippet := comment.Snippet; snippet != nil {
+		summary.Author = snippet.AuthorDisplayName
+		if snippet.AuthorChannelId != nil {
+			summary.AuthorChannelID = snippet.AuthorChannelId.Value
+		}
+		summary.Text = snippet.TextOriginal
+		if summary.Text == "" {
+			summary.Text = plainText(snippet.TextDisplay)
+		}
+		summary.LikeCount = snippet.LikeCount
+		summary.PublishedAt = snippet.PublishedAt
+		if snippet.UpdatedAt != snippet.PublishedAt {
+			summary.UpdatedAt = snippet.UpdatedAt
+		}
+		summary.VideoID = snippet.VideoId
+		summary.ParentID = snippet.ParentId
+	}
+	return summary
+}
+
+func newPlaylistSummary(playlist *youtube.Playlist) PlaylistSummary {
+	summary := PlaylistSummary{ID: playlist.Id, URL: "https://www.youtube.com/playlist?list=" + playlist.Id}
+	if snippet := playlist.Snippet; snippet != nil {
+		summary.Title = snippet.Title
+		summary.ChannelTitle = snippet.ChannelTitle
+		summary.PublishedAt = snippet.PublishedAt
+		summary.Description = snippet.Description
+	}
+	if playlist.Status != nil {
+		summary.Privacy = playlist.Status.PrivacyStatus
+	}
+	if playlist.ContentDetails != nil {
+		count := playlist.ContentDetails.ItemCount
+		summary.ItemCount = &count
+	}
+	return summary
+}
+
+func newPlaylistItemSummary(item *youtube.PlaylistItem) PlaylistItemSummary {
+	summary := PlaylistItemSummary{ID: item.Id}
+	if snippet := item.Snippet; snippet != nil {
+		summary.Title = snippet.Title
+		summary.Position = snippet.Position
+		summary.ChannelTitle = snippet.VideoOwnerChannelTitle
+		summary.Description = snippet.Description
+		if snippet.ResourceId != nil {
+			summary.VideoID = snippet.ResourceId.VideoId
+		}
+	}
+	if details := item.ContentDetails; details != nil {
+		summary.PublishedAt = details.VideoPublishedAt
+		if summary.VideoID == "" {
+			summary.VideoID = details.VideoId
+		}
+	}
+	if summary.VideoID != "" {
+		summary.URL = videoURL(summary.VideoID)
+	}
+	return summary
+}
+
+func newChannelSummary(channel *youtube.Channel) ChannelSummary {
+	summary := ChannelSummary{ID: channel.Id, URL: "https://www.youtube.com/channel/" + channel.Id}
+	if snippet := channel.Snippet; snippet != nil {
+		summary.Title = snippet.Title
+		summary.Handle = snippet.CustomUrl
+		summary.Country = snippet.Country
+		summary.PublishedAt = snippet.PublishedAt
+		summary.Description = snippet.Description
+	}
+	if stats := channel.Statistics; stats != nil {
+		if !stats.HiddenSubscriberCount {
+			summary.SubscriberCount = &stats.SubscriberCount
+		}
+		summary.VideoCount = &stats.VideoCount
+		summary.ViewCount = &stats.ViewCount
+	}
+	if details := channel.ContentDetails; details != nil && details.RelatedPlaylists != nil {
+		summary.UploadsPlaylistID = details.RelatedPlaylists.Uploads
+	}
+	return summary
+}
+
+func videoURL(videoID string) string {
+	return "https://www.youtube.com/watch?v=" + videoID
+}
+
+// bestThumbnail returns the URL of the largest available thumbnail.
+func bestThumbnail(thumbnails *youtube.ThumbnailDetails) string {
+	if thumbnails == nil {
+		return ""
+	}
+	for _, t := range []*youtube.Thumbnail{thumbnails.Maxres, thumbnails.Standard, thumbnails.High, thumbnails.Medium, thumbnails.Default} {
+		if t != nil && t.Url != "" {
+			return t.Url
+		}
+	}
+	return ""
+}
+
+var (
+	isoDurationPattern = regexp.MustCompile(`^P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+	htmlBreakPattern   = regexp.MustCompile(`(?i)<br\s*/?>`)
+	htmlTagPattern     = regexp.MustCompile(`<[^>]+>`)
+)
+
+// parseISODuration converts an ISO-8601 duration such as "PT1H2M3S", as
+// used by contentDetails.duration, to seconds.
+func parseISODuration(s string) (int64, bool) {
+	m := isoDurationPattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	var seconds int64
+	for i, unit := range []int64{7 * 24 * 3600, 24 * 3600, 3600, 60, 1} {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(m[i+1], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		seconds += n * unit
+	}
+	return seconds, true
+}
+
+// formatDuration renders seconds as e.g. "1h 2m 3s".
+func formatDuration(seconds int64) string {
+	if seconds == 0 {
+		return "0s"
+	}
+	var parts []string
+	for _, unit := range []struct {
+		size  int64
+		label string
+	}{{24 * 3600, "d"}, {3600, "h"}, {60, "m"}, {1, "s"}} {
+		if n := seconds / unit.size; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d%s", n, unit.label))
+			seconds %= unit.size
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// plainText turns the HTML of textDisplay into plain text.
+func plainText(s string) string {
+	s = htmlBreakPattern.ReplaceAllString(s, "\n")
+	s = htmlTagPattern.ReplaceAllString(s, "")
+	return html.UnescapeString(s)
+}
@@ -0,0 +1,29 @@
+package api
+
+import "testing"
+
+func TestParseISODuration(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   int64
+		wantOK bool
+	}{
+		{"PT0S", 0, true},
+		{"PT45S", 45, true},
+		{"PT2H", 7200, true},
+		{"PT1H2M3S", 3723, true},
+		{"P1DT2H", 93600, true},
+		{"P1W", 604800, true},
+		{"", 0, false},
+		{"1:02:03", 0, false},
+		{"PT1.5S", 0, false},
+		{"PT-5S", 0, false},
+		{"PT1H30", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseISODuration(tt.in)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseISODuration(%q) = %d, %v, want %d, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
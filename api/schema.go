@@ -0,0 +1,324 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// SchemaViolation describes one way in which a value does not match a schema.
+type SchemaViolation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// normalizeSchema round-trips a schema through JSON so that it only contains
+// the types encoding/json produces (map[string]interface{}, []interface{},
+// float64, ...), no matter how the Go literal was written.
+func normalizeSchema(schema map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// schemaTypeNames are the types a schema may name.
+var schemaTypeNames = map[string]bool{
+	"object": true, "array": true, "string": true, "integer": true, "number": true, "boolean": true, "null": true,
+}
+
+// checkSchema reports the first problem that would keep a normalized schema
+// from validating values: a type that does not exist, or a pattern that
+// does not compile.
+func checkSchema(schema map[string]interface{}, path string) error {
+	where := path
+	if where == "" {
+		where = "(root)"
+	}
+	for _, t := range schemaTypes(schema["type"]) {
+		if !schemaTypeNames[t] {
+			return fmt.Errorf("%s: unknown type %q", where, t)
+		}
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		if _, err := compilePattern(pattern); err != nil {
+			return fmt.Errorf("%s: invalid pattern %q", where, pattern)
+		}
+	}
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		for name, property := range properties {
+			if sub, ok := property.(map[string]interface{}); ok {
+				if err := checkSchema(sub, joinPath(path, name)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for _, key := range []string{"items", "additionalProperties"} {
+		if sub, ok := schema[key].(map[string]interface{}); ok {
+			if err := checkSchema(sub, joinPath(path, key)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateSchema checks value against a normalized JSON Schema and returns
+// every violation found. It supports the subset of JSON Schema used by tool
+// input schemas: type, enum, const, properties, required,
+// additionalProperties, items, minItems/maxItems, minLength/maxLength,
+// pattern, minimum/maximum and their exclusive forms.
+func validateSchema(schema map[string]interface{}, value interface{}) []SchemaViolation {
+	var v schemaValidator
+	v.validate(schema, value, "")
+	return v.violations
+}
+
+type schemaValidator struct {
+	violations []SchemaViolation
+}
+
+func (v *schemaValidator) fail(path, format string, args ...interface{}) {
+	if path == "" {
+		path = "(root)"
+	}
+	v.violations = append(v.violations, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *schemaValidator) validate(schema map[string]interface{}, value interface{}, path string) {
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if matchesType(t, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(path, "expected %s, got %s", strings.Join(types, " or "), jsonTypeOf(value))
+			// The remaining keywords assume the right type; skip them.
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if jsonEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(path, "must be one of %s", formatEnum(enum))
+		}
+	}
+	if constant, ok := schema["const"]; ok && !jsonEqual(constant, value) {
+		v.fail(path, "must be %s", formatEnum([]interface{}{constant}))
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		v.validateObject(schema, value, path)
+	case []interface{}:
+		v.validateArray(schema, value, path)
+	case string:
+		v.validateString(schema, value, path)
+	case float64:
+		v.validateNumber(schema, value, path)
+	}
+}
+
+func (v *schemaValidator) validateObject(schema map[string]interface{}, value map[string]interface{}, path string) {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, present := value[name]; !present {
+					v.fail(joinPath(path, name), "is required")
+				}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if propSchema, ok := properties[name].(map[string]interface{}); ok {
+			v.validate(propSchema, value[name], joinPath(path, name))
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.fail(joinPath(path, name), "is not an allowed property")
+			}
+		case map[string]interface{}:
+			v.validate(additional, value[name], joinPath(path, name))
+		}
+	}
+}
+
+func (v *schemaValidator) validateArray(schema map[string]interface{}, value []interface{}, path string) {
+	if min, ok := schema["minItems"].(float64); ok && float64(len(value)) < min {
+		v.fail(path, "must contain at least %s items", formatNumber(min))
+	}
+	if max, ok := schema["maxItems"].(float64); ok && float64(len(value)) > max {
+		v.fail(path, "must contain at most %s items", formatNumber(max))
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		for i, item := range value {
+			v.validate(items, item, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+}
+
+func (v *schemaValidator) validateString(schema map[string]interface{}, value string, path string) {
+	length := float64(utf8.RuneCountInString(value))
+	if min, ok := schema["minLength"].(float64); ok && length < min {
+		v.fail(path, "must be at least %s characters long", formatNumber(min))
+	}
+	if max, ok := schema["maxLength"].(float64); ok && length > max {
+		v.fail(path, "must be at most %s characters long", formatNumber(max))
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := compilePattern(pattern)
+		if err != nil {
+			v.fail(path, "schema has an invalid pattern %q", pattern)
+		} else if !re.MatchString(value) {
+			v.fail(path, "must match pattern %q", pattern)
+		}
+	}
+}
+
+func (v *schemaValidator) validateNumber(schema map[string]interface{}, value float64, path string) {
+	if min, ok := schema["minimum"].(float64); ok && value < min {
+		v.fail(path, "must be >= %s", formatNumber(min))
+	}
+	if max, ok := schema["maximum"].(float64); ok && value > max {
+		v.fail(path, "must be <= %s", formatNumber(max))
+	}
+	if min, ok := schema["exclusiveMinimum"].(float64); ok && value <= min {
+		v.fail(path, "must be > %s", formatNumber(min))
+	}
+	if max, ok := schema["exclusiveMaximum"].(float64); ok && value >= max {
+		v.fail(path, "must be < %s", formatNumber(max))
+	}
+}
+
+func schemaTypes(raw interface{}) []string {
+	switch t := raw.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesType(t string, value interface{}) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n) && !math.IsInf(n, 0)
+	}
+	return false
+}
+
+func jsonTypeOf(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if value == math.Trunc(value) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func jsonEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func formatEnum(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		data, _ := json.Marshal(value)
+		parts[i] = string(data)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func formatNumber(n float64) string {
+	return fmt.Sprintf("%g", n)
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+var patternCache sync.Map
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patternCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patternCache.Store(pattern, re)
+	return re, nil
+}
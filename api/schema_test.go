@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func mustSchema(t *testing.T, raw string) map[string]interface{} {
+	t.Helper()
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &schema); err != nil {
+		t.Fatalf("invalid schema literal: %v", err)
+	}
+	return schema
+}
+
+func mustValue(t *testing.T, raw string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		t.Fatalf("invalid value literal: %v", err)
+	}
+	return value
+}
+
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		// want lists the expected violations as "path: message" prefixes;
+		// empty means the value must validate.
+		want []string
+	}{
+		{"type string ok", `{"type":"string"}`, `"a"`, nil},
+		{"type string mismatch", `{"type":"string"}`, `5`, []string{"(root): expected string, got integer"}},
+		{"type integer ok", `{"type":"integer"}`, `5`, nil},
+		{"type integer rejects fraction", `{"type":"integer"}`, `5.5`, []string{"(root): expected integer, got number"}},
+		{"type number accepts integer", `{"type":"number"}`, `5`, nil},
+		{"type boolean mismatch", `{"type":"boolean"}`, `"true"`, []string{"(root): expected boolean, got string"}},
+		{"type union", `{"type":["string","null"]}`, `null`, nil},
+		{"type array mismatch", `{"type":"array"}`, `{}`, []string{"(root): expected array, got object"}},
+
+		{"required present", `{"type":"object","required":["q"]}`, `{"q":"x"}`, nil},
+		{"required missing", `{"type":"object","required":["q"]}`, `{}`, []string{"q: is required"}},
+
+		{"enum ok", `{"enum":["date","rating"]}`, `"date"`, nil},
+		{"enum mismatch", `{"enum":["date","rating"]}`, `"views"`, []string{`(root): must be one of ["date", "rating"]`}},
+		{"const mismatch", `{"const":1}`, `2`, []string{"(root): must be [1]"}},
+
+		{"minimum ok", `{"type":"integer","minimum":1}`, `1`, nil},
+		{"minimum violated", `{"type":"integer","minimum":1}`, `0`, []string{"(root): must be >= 1"}},
+		{"maximum violated", `{"type":"integer","maximum":50}`, `51`, []string{"(root): must be <= 50"}},
+		{"exclusiveMinimum violated", `{"type":"number","exclusiveMinimum":0}`, `0`, []string{"(root): must be > 0"}},
+		{"exclusiveMaximum violated", `{"type":"number","exclusiveMaximum":10}`, `10`, []string{"(root): must be < 10"}},
+
+		{"minLength violated", `{"type":"string","minLength":2}`, `"a"`, []string{"(root): must be at least 2 characters long"}},
+		{"maxLength counts runes", `{"type":"string","maxLength":2}`, `"éé"`, nil},
+		{"maxLength violated", `{"type":"string","maxLength":2}`, `"abc"`, []string{"(root): must be at most 2 characters long"}},
+
+		{"pattern ok", `{"type":"string","pattern":"^[A-Za-z0-9_-]{11}$"}`, `"dQw4w9WgXcQ"`, nil},
+		{"pattern violated", `{"type":"string","pattern":"^[A-Za-z0-9_-]{11}$"}`, `"short"`, []string{`(root): must match pattern "^[A-Za-z0-9_-]{11}$"`}},
+		{"pattern invalid", `{"type":"string","pattern":"("}`, `"x"`, []string{`(root): schema has an invalid pattern "("`}},
+
+		{"minItems violated", `{"type":"array","minItems":1}`, `[]`, []string{"(root): must contain at least 1 items"}},
+		{"maxItems violated", `{"type":"array","maxItems":1}`, `[1,2]`, []string{"(root): must contain at most 1 items"}},
+		{"items checked with index", `{"type":"array","items":{"type":"string"}}`, `["a",2]`, []string{"[1]: expected string, got integer"}},
+
+		{"additionalProperties false", `{"type":"object","additionalProperties":false,"properties":{"a":{}}}`, `{"a":1,"b":2}`, []string{"b: is not an allowed property"}},
+		{"additionalProperties schema", `{"type":"object","additionalProperties":{"type":"string"}}`, `{"b":2}`, []string{"b: expected string, got integer"}},
+		{"nested path", `{"type":"object","properties":{"a":{"type":"object","properties":{"b":{"type":"integer"}}}}}`, `{"a":{"b":"x"}}`, []string{"a.b: expected integer, got string"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations := validateSchema(mustSchema(t, tt.schema), mustValue(t, tt.value))
+			assertViolations(t, violations, tt.want)
+		})
+	}
+}
+
+// TestValidateSchemaToolArguments checks the examples from the validation
+// request against a real tool's input schema.
+func TestValidateSchemaToolArguments(t *testing.T) {
+	schema := mustSchema(t, `{
+		"type": "object",
+		"properties": {
+			"query": {"type": "string"},
+			"limit": {"type": "integer", "minimum": 1, "maximum": 50}
+		},
+		"required": ["query"]
+	}`)
+
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{"valid", `{"query":"go","limit":5}`, nil},
+		{"limit as string", `{"query":"go","limit":"5"}`, []string{"limit: expected integer, got string"}},
+		{"limit out of range", `{"query":"go","limit":500}`, []string{"limit: must be <= 50"}},
+		{"every violation reported", `{"limit":0}`, []string{"query: is required", "limit: must be >= 1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertViolations(t, validateSchema(schema, mustValue(t, tt.value)), tt.want)
+		})
+	}
+}
+
+func TestRegisteredToolValidateNilArguments(t *testing.T) {
+	registry := NewToolRegistry()
+	tool := Tool{
+		Name: "needs_query",
+		InputSchema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"query": map[string]interface{}{"type": "string"}},
+			"required":   []string{"query"},
+		},
+	}
+	if err := registry.Register(tool, func(ctx context.Context, _ map[string]interface{}) (interface{}, error) {
+		return nil, nil
+	}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	registered, _ := registry.get("needs_query")
+	assertViolations(t, registered.validate(nil), []string{"query: is required"})
+}
+
+func assertViolations(t *testing.T, got []SchemaViolation, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d violations %+v, want %d %v", len(got), got, len(want), want)
+	}
+	remaining := append([]SchemaViolation(nil), got...)
+	for _, w := range want {
+		found := -1
+		for i, v := range remaining {
+			if strings.HasPrefix(v.Path+": "+v.Message, w) {
+				found = i
+				break
+			}
+		}
+		if found < 0 {
+			t.Errorf("missing violation %q in %+v", w, got)
+			continue
+		}
+		remaining = append(remaining[:found], remaining[found+1:]...)
+	}
+}
+
+func TestToolsCallReportsViolations(t *testing.T) {
+	registry := NewToolRegistry()
+	called := false
+	tool := Tool{
+		Name: "search",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{"type": "string"},
+				"limit": map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 50},
+			},
+			"required": []string{"query"},
+		},
+	}
+	if err := registry.Register(tool, func(ctx context.Context, _ map[string]interface{}) (interface{}, error) {
+		called = true
+		return nil, nil
+	}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	handler := NewMCPHandler(registry, nil)
+
+	resp := handler.dispatch(context.Background(), &MCPRequest{
+		JSONRPC: "2.0",
+		ID:      float64(1),
+		Method:  "tools/call",
+		Params: map[string]interface{}{
+			"name":      "search",
+			"arguments": map[string]interface{}{"limit": "5"},
+		},
+	})
+	if called {
+		t.Fatal("handler ran despite invalid arguments")
+	}
+	if resp.Error == nil || resp.Error.Code != -32602 {
+		t.Fatalf("got %+v, want a -32602 error", resp)
+	}
+	data, _ := resp.Error.Data.(map[string]interface{})
+	violations, _ := data["violations"].([]SchemaViolation)
+	assertViolations(t, violations, []string{"query: is required", "limit: expected integer, got string"})
+}
@@ -0,0 +1,415 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ToolOutput is a tool result with a human-readable rendering alongside the
+// structured data. Handlers return it instead of bare data when the text
+// is more useful to a model than the JSON alone.
+type ToolOutput struct {
+	Text string
+	// Data is sent as structuredContent and must encode to a JSON object
+	// matching the tool's output schema.
+	Data interface{}
+	// Content holds extra content blocks, such as images or resource links,
+	// sent after the text.
+	Content []ContentItem
+}
+
+// Verbosity levels accepted by the verbosity argument.
+const (
+	verbosityMinimal  = "minimal"
+	verbosityCompact  = "compact"
+	verbosityDetailed = "detailed"
+)
+
+// maxCompactDescription is how much of a description is kept below detailed verbosity.
+const maxCompactDescription = 280
+
+// shapeArgs are the result shaping arguments shared by every tool that
+// returns YouTube resources. Args structs embed it.
+type shapeArgs struct {
+	Verbosity string   `json:"verbosity"`
+	Fields    []string `json:"fields"`
+}
+
+// resultShape lists the fields of a summary type, in display order, and
+// which of them each verbosity level includes.
+type resultShape struct {
+	// summary is a zero value of the summary type, from which the output
+	// schema is derived.
+	summary interface{}
+	// heading holds the fields tried, in order, as the title line of an item.
+	heading  []string
+	minimal  []string
+	compact  []string
+	detailed []string
+}
+
+var (
+	videoShape = resultShape{
+		summary:  VideoSummary{},
+		heading:  []string{"title", "id"},
+		minimal:  []string{"id", "title", "url"},
+		compact:  []string{"id", "title", "channel_title", "published_at", "duration", "view_count", "like_count", "comment_count", "description", "url"},
+		detailed: []string{"id", "title", "channel_title", "channel_id", "published_at", "duration", "duration_seconds", "view_count", "like_count", "comment_count", "live_broadcast", "description", "tags", "thumbnail", "url"},
+	}
+	commentShape = resultShape{
+		summary:  CommentSummary{},
+		heading:  []string{"author", "id"},
+		minimal:  []string{"id", "author", "text"},
+		compact:  []string{"id", "author", "text", "like_count", "reply_count", "published_at"},
+		detailed: []string{"id", "author", "author_channel_id", "text", "like_count", "reply_count", "published_at", "updated_at", "video_id", "parent_id", "replies"},
+	}
+	playlistShape = resultShape{
+		summary:  PlaylistSummary{},
+		heading:  []string{"title", "id"},
+		minimal:  []string{"id", "title"},
+		compact:  []string{"id", "title", "item_count", "privacy", "description", "url"},
+		detailed: []string{"id", "title", "item_count", "privacy", "channel_title", "published_at", "description", "url"},
+	}
+	playlistItemShape = resultShape{
+		summary:  PlaylistItemSummary{},
+		heading:  []string{"title", "id"},
+		minimal:  []string{"id", "video_id", "title", "position"},
+		compact:  []string{"id", "video_id", "title", "position", "channel_title", "published_at", "url"},
+		detailed: []string{"id", "video_id", "title", "position", "channel_title", "published_at", "description", "url"},
+	}
+	channelShape = resultShape{
+		summary:  ChannelSummary{},
+		heading:  []string{"title", "id"},
+		minimal:  []string{"id", "title", "handle"},
+		compact:  []string{"id", "title", "handle", "subscriber_count", "video_count", "view_count", "description", "url"},
+		detailed: []string{"id", "title", "handle", "subscriber_count", "video_count", "view_count", "country", "published_at", "uploads_playlist_id", "description", "url"},
+	}
+)
+
+// withShapeArgs adds the verbosity and fields arguments to a tool's input
+// schema properties.
+func (s resultShape) withShapeArgs(properties map[string]interface{}) map[string]interface{} {
+	properties["verbosity"] = map[string]interface{}{
+		"type":        "string",
+		"description": "Optional: How much detail to return (default: compact). Compact truncates long descriptions.",
+		"enum":        []string{verbosityMinimal, verbosityCompact, verbosityDetailed},
+	}
+	properties["fields"] = map[string]interface{}{
+		"type":        "array",
+		"description": "Optional: Return only these fields (the id is always included). Overrides verbosity.",
+		"items":       map[string]interface{}{"type": "string", "enum": s.detailed},
+		"minItems":    1,
+	}
+	return properties
+}
+
+// itemSchema is the output schema of a single shaped summary. Only the id is
+// required, since verbosity and fields decide which properties are present.
+func (s resultShape) itemSchema() map[string]interface{} {
+	return summarySchema(reflect.TypeOf(s.summary))
+}
+
+// listSchema is the output schema of a shaped list.
+func (s resultShape) listSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"items":        map[string]interface{}{"type": "array", "items": s.itemSchema()},
+			"nextCursor":   map[string]interface{}{"type": "string"},
+			"totalResults": map[string]interface{}{"type": "integer"},
+		},
+		"required": []string{"items"},
+	}
+}
+
+// summarySchema derives a JSON Schema from a summary struct's json tags.
+func summarySchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		properties[name] = typeSchema(field.Type, t)
+	}
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   []string{"id"},
+	}
+}
+
+func typeSchema(t reflect.Type, parent reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Slice:
+		// Replies nest the parent type; describe them without recursing.
+		if t.Elem() == parent {
+			return map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "object"}}
+		}
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), parent)}
+	case reflect.Struct:
+		return summarySchema(t)
+	}
+	return map[string]interface{}{}
+}
+
+// fieldsFor returns the fields to include for the given arguments.
+func (s resultShape) fieldsFor(args shapeArgs) []string {
+	if len(args.Fields) > 0 {
+		fields := []string{"id"}
+		for _, field := range s.detailed {
+			if field != "id" && contains(args.Fields, field) {
+				fields = append(fields, field)
+			}
+		}
+		return fields
+	}
+	switch args.Verbosity {
+	case verbosityMinimal:
+		return s.minimal
+	case verbosityDetailed:
+		return s.detailed
+	default:
+		return s.compact
+	}
+}
+
+// project reduces a summary to the selected fields. Descriptions are
+// truncated unless detailed output was asked for.
+func (s resultShape) project(full map[string]interface{}, args shapeArgs) map[string]interface{} {
+	return s.projectMap(full, s.fieldsFor(args), args.Verbosity == verbosityDetailed)
+}
+
+func (s resultShape) projectMap(full map[string]interface{}, fields []string, detailed bool) map[string]interface{} {
+	projected := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		value, ok := full[field]
+		if !ok {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			if field == "description" && !detailed {
+				value = truncate(v, maxCompactDescription)
+			}
+		case []interface{}:
+			// Nested summaries (comment replies) get the same fields as their parent.
+			if field == "replies" {
+				for i, reply := range v {
+					if m, ok := reply.(map[string]interface{}); ok {
+						v[i] = s.projectMap(m, fields, detailed)
+					}
+				}
+			}
+		}
+		projected[field] = value
+	}
+	return projected
+}
+
+// one shapes a single summary.
+func (s resultShape) one(summary interface{}, args shapeArgs) (*ToolOutput, error) {
+	full, err := toMap(summary)
+	if err != nil {
+		return nil, err
+	}
+	item := s.project(full, args)
+
+	var text strings.Builder
+	s.renderItem(&text, item, s.fieldsFor(args), "")
+	output := &ToolOutput{Text: strings.TrimRight(text.String(), "\n"), Data: item}
+	if link, ok := s.resourceLink(full); ok {
+		output.Content = append(output.Content, link)
+	}
+	return output, nil
+}
+
+// shapedList is the structured form of a shaped, paginated list.
+type shapedList struct {
+	Items        []map[string]interface{} `json:"items"`
+	NextCursor   string                   `json:"nextCursor,omitempty"`
+	TotalResults int64                    `json:"totalResults,omitempty"`
+}
+
+// list shapes a page of summaries. summaries must be a slice.
+func (s resultShape) list(summaries interface{}, args shapeArgs, nextCursor string, totalResults int64) (*ToolOutput, error) {
+	var raw []map[string]interface{}
+	encoded, err := json.Marshal(summaries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+	if err := decodeJSON(encoded, &raw); err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+
+	fields := s.fieldsFor(args)
+	result := shapedList{Items: make([]map[string]interface{}, 0, len(raw)), NextCursor: nextCursor, TotalResults: totalResults}
+	var links []ContentItem
+	for _, full := range raw {
+		result.Items = append(result.Items, s.project(full, args))
+		if link, ok := s.resourceLink(full); ok {
+			links = append(links, link)
+		}
+	}
+
+	var text strings.Builder
+	switch {
+	case len(result.Items) == 0:
+		text.WriteString("No results.\n")
+	case totalResults > 0:
+		fmt.Fprintf(&text, "%s (about %s in total):\n\n", resultCount(len(result.Items)), formatCount(totalResults))
+	default:
+		fmt.Fprintf(&text, "%s:\n\n", resultCount(len(result.Items)))
+	}
+	for i, item := range result.Items {
+		s.renderItem(&text, item, fields, fmt.Sprintf("%d. ", i+1))
+	}
+	if nextCursor != "" {
+		fmt.Fprintf(&text, "More results are available; pass cursor %q to continue.\n", nextCursor)
+	}
+	return &ToolOutput{Text: strings.TrimRight(text.String(), "\n"), Data: result, Content: links}, nil
+}
+
+// resourceLink links to the YouTube page of a summary, if it has one. It is
+// built from the full summary so the link survives field selection.
+func (s resultShape) resourceLink(full map[string]interface{}) (ContentItem, bool) {
+	uri, _ := full["url"].(string)
+	if uri == "" {
+		return ContentItem{}, false
+	}
+	name := ""
+	for _, field := range s.heading {
+		if value, ok := full[field].(string); ok && value != "" {
+			name = value
+			break
+		}
+	}
+	description, _ := full["channel_title"].(string)
+	return ResourceLinkContent(uri, name, description), true
+}
+
+// renderItem writes an item as a heading line followed by one indented
+// "label: value" line per remaining field.
+func (s resultShape) renderItem(text *strings.Builder, item map[string]interface{}, fields []string, prefix string) {
+	heading := ""
+	for _, field := range s.heading {
+		if value, ok := item[field].(string); ok && value != "" {
+			heading = field
+			fmt.Fprintf(text, "%s%s\n", prefix, value)
+			break
+		}
+	}
+
+	indent := strings.Repeat(" ", len(prefix)+1)
+	for _, field := range fields {
+		value, ok := item[field]
+		if !ok || field == heading {
+			continue
+		}
+		label := strings.ReplaceAll(field, "_", " ")
+		switch v := value.(type) {
+		case []interface{}:
+			if field == "replies" {
+				fmt.Fprintf(text, "%s%s:\n", indent, label)
+				for _, reply := range v {
+					if m, ok := reply.(map[string]interface{}); ok {
+						s.renderItem(text, m, fields, indent+"- ")
+					}
+				}
+				continue
+			}
+			parts := make([]string, len(v))
+			for i, part := range v {
+				parts[i] = fmt.Sprint(part)
+			}
+			fmt.Fprintf(text, "%s%s: %s\n", indent, label, strings.Join(parts, ", "))
+		case json.Number:
+			if n, err := v.Int64(); err == nil && strings.HasSuffix(field, "_count") {
+				fmt.Fprintf(text, "%s%s: %s\n", indent, label, formatCount(n))
+			} else {
+				fmt.Fprintf(text, "%s%s: %s\n", indent, label, v)
+			}
+		case string:
+			fmt.Fprintf(text, "%s%s: %s\n", indent, label, strings.ReplaceAll(v, "\n", "\n"+indent+"  "))
+		default:
+			fmt.Fprintf(text, "%s%s: %v\n", indent, label, v)
+		}
+	}
+	text.WriteString("\n")
+}
+
+// toMap converts a summary struct to a map keyed by its JSON field names.
+func toMap(v interface{}) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+	var m map[string]interface{}
+	if err := decodeJSON(encoded, &m); err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+	return m, nil
+}
+
+// decodeJSON decodes keeping numbers as json.Number, so large counts are not
+// rounded through float64.
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// truncate shortens s to at most max runes, marking the cut with an ellipsis.
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:max])) + "…"
+}
+
+func resultCount(n int) string {
+	if n == 1 {
+		return "1 result"
+	}
+	return fmt.Sprintf("%d results", n)
+}
+
+// formatCount renders n with thousands separators.
+func formatCount(n int64) string {
+	digits := strconv.FormatInt(n, 10)
+	if n < 0 {
+		return "-" + formatCount(-n)
+	}
+	var out strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			out.WriteByte(',')
+		}
+		out.WriteRune(d)
+	}
+	return out.String()
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
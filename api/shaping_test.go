@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestFormatCount(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{1000, "1,000"},
+		{999999, "999,999"},
+		{1000000, "1,000,000"},
+		{999999999, "999,999,999"},
+		{1000000000, "1,000,000,000"},
+		{-1234, "-1,234"},
+	}
+	for _, tt := range tests {
+		if got := formatCount(tt.n); got != tt.want {
+			t.Errorf("formatCount(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestProjectFields(t *testing.T) {
+	full := map[string]interface{}{
+		"id":          "v1",
+		"title":       "Go",
+		"view_count":  json.Number("1000"),
+		"description": strings.Repeat("a", maxCompactDescription+10),
+		"url":         "https://www.youtube.com/watch?v=v1",
+		"etag":        "not a summary field",
+	}
+
+	tests := []struct {
+		name string
+		args shapeArgs
+		want []string
+	}{
+		{name: "minimal", args: shapeArgs{Verbosity: verbosityMinimal}, want: []string{"id", "title", "url"}},
+		{name: "compact", args: shapeArgs{}, want: []string{"description", "id", "title", "url", "view_count"}},
+		{name: "fields keep the id", args: shapeArgs{Fields: []string{"title"}}, want: []string{"id", "title"}},
+		{name: "unknown fields dropped", args: shapeArgs{Fields: []string{"title", "etag", "bogus"}}, want: []string{"id", "title"}},
+		{name: "fields override verbosity", args: shapeArgs{Verbosity: verbosityDetailed, Fields: []string{"view_count"}}, want: []string{"id", "view_count"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projected := videoShape.project(full, tt.args)
+			var got []string
+			for field := range projected {
+				got = append(got, field)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("projected fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if d := videoShape.project(full, shapeArgs{}); len([]rune(d["description"].(string))) != maxCompactDescription+1 {
+		t.Errorf("compact description was not truncated: %q", d["description"])
+	}
+	if d := videoShape.project(full, shapeArgs{Verbosity: verbosityDetailed}); d["description"] != full["description"] {
+		t.Error("detailed description was truncated")
+	}
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// ToolAnnotations are optional hints describing a tool's behavior to the client.
+type ToolAnnotations struct {
+	Title           string `json:"title,omitempty"`
+	ReadOnlyHint    *bool  `json:"readOnlyHint,omitempty"`
+	DestructiveHint *bool  `json:"destructiveHint,omitempty"`
+	IdempotentHint  *bool  `json:"idempotentHint,omitempty"`
+	OpenWorldHint   *bool  `json:"openWorldHint,omitempty"`
+}
+
+// ToolHandlerFunc handles a call to a tool. The returned value is sent back
+// to the client as the tool result; a returned error is reported as a tool
+// execution error so the model can see what went wrong.
+type ToolHandlerFunc func(ctx context.Context, arguments map[string]interface{}) (interface{}, error)
+
+// ToolAvailabilityFunc reports why a tool cannot be used in the context of
+// a request, or nil if it can.
+type ToolAvailabilityFunc func(ctx context.Context, tool Tool) error
+
+// InvalidArgumentsError reports tool arguments that could not be decoded.
+// It is answered with a JSON-RPC Invalid params error rather than a tool error.
+type InvalidArgumentsError struct {
+	Err error
+}
+
+func (e *InvalidArgumentsError) Error() string {
+	return fmt.Sprintf("invalid arguments: %v", e.Err)
+}
+
+func (e *InvalidArgumentsError) Unwrap() error {
+	return e.Err
+}
+
+// ToolError is a tool execution error with a machine-readable code, so the
+// assistant can tell, for example, a missing video from a rate limit.
+type ToolError struct {
+	Code    string
+	Message string
+	// Retryable reports whether the same call may succeed later.
+	Retryable bool
+}
+
+func (e *ToolError) Error() string {
+	return e.Message
+}
+
+type registeredTool struct {
+	tool    Tool
+	handler ToolHandlerFunc
+	schema  map[string]interface{}
+}
+
+// validate checks the arguments of a call against the tool's input schema.
+func (t *registeredTool) validate(arguments map[string]interface{}) []SchemaViolation {
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+	return validateSchema(t.schema, arguments)
+}
+
+// ToolRegistry holds every tool the server exposes. Both tools/list and
+// tools/call are derived from it, so a tool is declared exactly once.
+type ToolRegistry struct {
+	mu        sync.RWMutex
+	tools     map[string]*registeredTool
+	order     []string
+	available ToolAvailabilityFunc
+}
+
+// NewToolRegistry creates an empty ToolRegistry.
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{
+		tools: make(map[string]*registeredTool),
+	}
+}
+
+// Register adds a tool with an untyped handler. Tool names must be unique.
+func (r *ToolRegistry) Register(tool Tool, handler ToolHandlerFunc) error {
+	if tool.Name == "" {
+		return fmt.Errorf("tool name is required")
+	}
+	if handler == nil {
+		return fmt.Errorf("tool %q has no handler", tool.Name)
+	}
+	if tool.InputSchema == nil {
+		tool.InputSchema = map[string]interface{}{"type": "object"}
+	}
+	if tool.Cached {
+		tool.InputSchema = withNoCacheArg(tool.InputSchema)
+	}
+	schema, err := normalizeSchema(tool.InputSchema)
+	if err == nil && schema["type"] != "object" {
+		err = fmt.Errorf("it does not describe an object")
+	}
+	if err == nil {
+		err = checkSchema(schema, "")
+	}
+	if err != nil {
+		return fmt.Errorf("tool %q has an invalid input schema: %w", tool.Name, err)
+	}
+	if tool.OutputSchema != nil && tool.OutputSchema["type"] != "object" {
+		return fmt.Errorf("tool %q has an output schema that does not describe an object", tool.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tools[tool.Name]; exists {
+		return fmt.Errorf("tool %q is already registered", tool.Name)
+	}
+	r.tools[tool.Name] = &registeredTool{tool: tool, handler: handler, schema: schema}
+	r.order = append(r.order, tool.Name)
+	return nil
+}
+
+// RegisterTool adds a tool whose handler receives its arguments decoded into
+// Args, a struct with json tags matching the tool's input schema.
+func RegisterTool[Args any](r *ToolRegistry, tool Tool, handler func(ctx context.Context, args Args) (interface{}, error)) error {
+	return r.Register(tool, func(ctx context.Context, arguments map[string]interface{}) (interface{}, error) {
+		var args Args
+		if err := decodeArguments(arguments, &args); err != nil {
+			return nil, &InvalidArgumentsError{Err: err}
+		}
+		return handler(ctx, args)
+	})
+}
+
+// SetAvailability sets the check deciding which tools a request may use.
+// Without one, every tool is available.
+func (r *ToolRegistry) SetAvailability(available ToolAvailabilityFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.available = available
+}
+
+// Available returns the tools the request may use, in registration order.
+func (r *ToolRegistry) Available(ctx context.Context) []Tool {
+	tools := []Tool{}
+	for _, tool := range r.Tools() {
+		if r.checkAvailable(ctx, tool) == nil {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+func (r *ToolRegistry) checkAvailable(ctx context.Context, tool Tool) error {
+	r.mu.RLock()
+	available := r.available
+	r.mu.RUnlock()
+	if available == nil {
+		return nil
+	}
+	return available(ctx, tool)
+}
+
+// Tools returns the registered tools in registration order.
+func (r *ToolRegistry) Tools() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tools := make([]Tool, 0, len(r.order))
+	for _, name := range r.order {
+		tools = append(tools, r.tools[name].tool)
+	}
+	return tools
+}
+
+// Lookup returns the tool with the given name and its handler.
+func (r *ToolRegistry) Lookup(name string) (Tool, ToolHandlerFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	registered, ok := r.tools[name]
+	if !ok {
+		return Tool{}, nil, false
+	}
+	return registered.tool, registered.handler, true
+}
+
+func (r *ToolRegistry) get(name string) (*registeredTool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	registered, ok := r.tools[name]
+	return registered, ok
+}
+
+// withNoCacheArg returns a copy of an input schema with the no_cache
+// argument of cached tools added.
+func withNoCacheArg(schema map[string]interface{}) map[string]interface{} {
+	properties := map[string]interface{}{}
+	if existing, ok := schema["properties"].(map[string]interface{}); ok {
+		for name, property := range existing {
+			properties[name] = property
+		}
+	}
+	properties["no_cache"] = map[string]interface{}{
+		"type":        "boolean",
+		"description": "Optional: Fetch fresh data from YouTube instead of a recently cached response.",
+	}
+
+	copied := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		copied[key] = value
+	}
+	copied["properties"] = properties
+	return copied
+}
+
+func decodeArguments(arguments map[string]interface{}, dest interface{}) error {
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+	bytes, err := json.Marshal(arguments)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, dest)
+}
+
+// boolPtr is a helper for filling in ToolAnnotations hints.
+func boolPtr(b bool) *bool {
+	return &b
+}
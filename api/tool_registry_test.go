@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yt-mcp-server/service"
+)
+
+func noopHandler(context.Context, map[string]interface{}) (interface{}, error) {
+	return map[string]interface{}{"ok": true}, nil
+}
+
+func toolNames(tools []Tool) []string {
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name
+	}
+	return names
+}
+
+func TestRegisterRejectsInvalidTools(t *testing.T) {
+	tests := []struct {
+		name    string
+		tool    Tool
+		handler ToolHandlerFunc
+		wantErr string
+	}{
+		{name: "no name", tool: Tool{}, handler: noopHandler, wantErr: "name is required"},
+		{name: "no handler", tool: Tool{Name: "t"}, wantErr: "has no handler"},
+		{name: "duplicate name", tool: Tool{Name: "taken"}, handler: noopHandler, wantErr: "already registered"},
+		{
+			name:    "input schema not an object",
+			tool:    Tool{Name: "t", InputSchema: map[string]interface{}{"type": "array"}},
+			handler: noopHandler,
+			wantErr: "does not describe an object",
+		},
+		{
+			name:    "unknown property type",
+			tool:    Tool{Name: "t", InputSchema: map[string]interface{}{"type": "object", "properties": map[string]interface{}{"q": map[string]interface{}{"type": "text"}}}},
+			handler: noopHandler,
+			wantErr: `q: unknown type "text"`,
+		},
+		{
+			name:    "invalid pattern",
+			tool:    Tool{Name: "t", InputSchema: map[string]interface{}{"type": "object", "properties": map[string]interface{}{"ids": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string", "pattern": "("}}}}},
+			handler: noopHandler,
+			wantErr: `ids.items: invalid pattern`,
+		},
+		{
+			name:    "schema that cannot be encoded",
+			tool:    Tool{Name: "t", InputSchema: map[string]interface{}{"type": "object", "default": func() {}}},
+			handler: noopHandler,
+			wantErr: "invalid input schema",
+		},
+		{
+			name:    "output schema not an object",
+			tool:    Tool{Name: "t", OutputSchema: map[string]interface{}{"type": "string"}},
+			handler: noopHandler,
+			wantErr: "output schema",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewToolRegistry()
+			if err := registry.Register(Tool{Name: "taken"}, noopHandler); err != nil {
+				t.Fatal(err)
+			}
+			err := registry.Register(tt.tool, tt.handler)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Register error = %v, want one containing %q", err, tt.wantErr)
+			}
+			if got := toolNames(registry.Tools()); !reflect.DeepEqual(got, []string{"taken"}) {
+				t.Errorf("rejected tool was registered: %v", got)
+			}
+		})
+	}
+}
+
+func TestRegisterToolDecodesArguments(t *testing.T) {
+	type args struct {
+		Query string `json:"query"`
+		Limit int    `json:"limit"`
+	}
+	registry := NewToolRegistry()
+	err := RegisterTool(registry, Tool{Name: "search"}, func(ctx context.Context, a args) (interface{}, error) {
+		return a, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, handler, ok := registry.Lookup("search")
+	if !ok {
+		t.Fatal("registered tool not found")
+	}
+	got, err := handler(context.Background(), map[string]interface{}{"query": "go", "limit": float64(5)})
+	if err != nil || got != (args{Query: "go", Limit: 5}) {
+		t.Errorf("handler got %+v, %v", got, err)
+	}
+
+	var invalid *InvalidArgumentsError
+	if _, err := handler(context.Background(), map[string]interface{}{"limit": "five"}); !errors.As(err, &invalid) {
+		t.Errorf("undecodable arguments: err = %v, want *InvalidArgumentsError", err)
+	}
+}
+
+func TestToolsListedInRegistrationOrder(t *testing.T) {
+	registry := NewToolRegistry()
+	want := []string{"search_videos", "add_video_to_playlist", "get_channel", "whoami"}
+	for _, name := range want {
+		if err := registry.Register(Tool{Name: name}, noopHandler); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if got := toolNames(registry.Tools()); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Tools() = %v, want %v", got, want)
+		}
+		if got := toolNames(registry.Available(context.Background())); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Available() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestUnavailableToolsHidden(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(Tool{Name: "search", Public: true}, noopHandler)
+	registry.Register(Tool{Name: "delete"}, noopHandler)
+	registry.SetAvailability(func(ctx context.Context, tool Tool) error {
+		if !tool.Public {
+			return &ToolError{Code: "login_required", Message: "log in first"}
+		}
+		return nil
+	})
+
+	if got := toolNames(registry.Available(context.Background())); !reflect.DeepEqual(got, []string{"search"}) {
+		t.Errorf("Available() = %v, want only the public tool", got)
+	}
+	if tool, handler, ok := registry.Lookup("delete"); !ok || handler == nil || tool.Name != "delete" {
+		t.Error("Lookup does not report the unavailable tool")
+	}
+	if _, _, ok := registry.Lookup("missing"); ok {
+		t.Error("Lookup reports a tool that was never registered")
+	}
+
+	handler := NewMCPHandler(registry, nil)
+	reqs, _, _, _ := parseMessages([]byte(`[
+		{"jsonrpc":"2.0","id":1,"method":"tools/list"},
+		{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"delete"}}
+	]`))
+	responses := handler.handleRequests(context.Background(), reqs)
+	if len(responses) != 2 {
+		t.Fatalf("got %d responses, want 2", len(responses))
+	}
+	var listed ToolsListResult
+	encoded, _ := json.Marshal(responses[0].Result)
+	if err := json.Unmarshal(encoded, &listed); err != nil {
+		t.Fatal(err)
+	}
+	if got := toolNames(listed.Tools); !reflect.DeepEqual(got, []string{"search"}) {
+		t.Errorf("tools/list = %v, want only the public tool", got)
+	}
+	call, _ := json.Marshal(responses[1])
+	if !strings.Contains(string(call), `"isError":true`) || !strings.Contains(string(call), "login_required") {
+		t.Errorf("calling the unavailable tool answered %s, want a login_required tool error", call)
+	}
+}
+
+func TestYouTubeToolSchemasAreValid(t *testing.T) {
+	registry := NewToolRegistry()
+	tools := newTestYouTubeTools(t, &service.InMemoryTokenStore{}, "key")
+	if err := RegisterYouTubeTools(registry, tools.youtubeService); err != nil {
+		t.Fatalf("RegisterYouTubeTools: %v", err)
+	}
+	if len(registry.Tools()) == 0 {
+		t.Error("no tools were registered")
+	}
+}
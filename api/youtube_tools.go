@@ -0,0 +1,352 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/yt-mcp-server/service"
+)
+
+// Schema fragments shared by several tools.
+var (
+	videoIDSchema = map[string]interface{}{
+		"type":        "string",
+		"description": "The ID of the YouTube video.",
+		"pattern":     "^[A-Za-z0-9_-]{11}$",
+	}
+	playlistIDSchema = map[string]interface{}{
+		"type":        "string",
+		"description": "The ID of the playlist.",
+		"pattern":     "^[A-Za-z0-9_-]{2,64}$",
+	}
+	channelIDSchema = map[string]interface{}{
+		"type":        "string",
+		"description": "Optional: Restricts search to a specific channel.",
+		"pattern":     "^UC[A-Za-z0-9_-]{22}$",
+	}
+)
+
+// commentOrders maps the sort_by values we advertise to the API's order values.
+var commentOrders = map[string]string{
+	"top":    "relevance",
+	"recent": "time",
+}
+
+// youtubeTools exposes YouTubeService operations as MCP tools.
+type youtubeTools struct {
+	youtubeService *service.YouTubeService
+}
+
+// RegisterYouTubeTools registers the YouTube toolkit with the registry.
+func RegisterYouTubeTools(registry *ToolRegistry, youtubeService *service.YouTubeService) error {
+	t := &youtubeTools{youtubeService: youtubeService}
+	registry.SetAvailability(t.available)
+
+	readOnly := &ToolAnnotations{ReadOnlyHint: boolPtr(true), OpenWorldHint: boolPtr(true)}
+
+	if err := RegisterTool(registry, Tool{
+		Name:        "get_video_metadata",
+		Public:      true,
+		Cached:      true,
+		Description: "Gets detailed information for a specific video.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": videoShape.withShapeArgs(map[string]interface{}{
+				"video_id":          videoIDSchema,
+				"include_thumbnail": map[string]interface{}{"type": "boolean", "description": "Optional: Also return the video's thumbnail as an image."},
+			}),
+			"required": []string{"video_id"},
+		},
+		OutputSchema: videoShape.itemSchema(),
+		Annotations:  readOnly,
+	}, t.getVideoMetadata); err != nil {
+		return err
+	}
+
+	if err := RegisterTool(registry, Tool{
+		Name:        "search_videos",
+		Public:      true,
+		Cached:      true,
+		Description: "Searches for YouTube videos.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": videoShape.withShapeArgs(map[string]interface{}{
+				"query":      map[string]interface{}{"type": "string", "description": "The search term.", "minLength": 1},
+				"channel_id": channelIDSchema,
+				"limit":      map[string]interface{}{"type": "integer", "description": "Optional: Max number of results per page (default: 10). Ignored when max_total is set.", "minimum": 1, "maximum": 50},
+				"cursor":     cursorSchema,
+				"max_total":  map[string]interface{}{"type": "integer", "description": "Optional: Follow pages until this many results are collected (each page costs quota). Takes precedence over limit.", "minimum": 1, "maximum": 500},
+			}),
+			"required": []string{"query"},
+		},
+		OutputSchema: videoShape.listSchema(),
+		Annotations:  readOnly,
+	}, t.searchVideos); err != nil {
+		return err
+	}
+
+	if err := RegisterTool(registry, Tool{
+		Name:        "get_video_comments",
+		Public:      true,
+		Cached:      true,
+		Description: "Fetches top-level comment threads for a video.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": commentShape.withShapeArgs(map[string]interface{}{
+				"video_id":  videoIDSchema,
+				"sort_by":   map[string]interface{}{"type": "string", "description": "Optional: Sort order (default: top).", "enum": []string{"top", "recent"}},
+				"limit":     map[string]interface{}{"type": "integer", "description": "Optional: Max number of results per page (default: 20). Ignored when max_total is set.", "minimum": 1, "maximum": 100},
+				"cursor":    cursorSchema,
+				"max_total": map[string]interface{}{"type": "integer", "description": "Optional: Follow pages until this many comment threads are collected. Takes precedence over limit.", "minimum": 1, "maximum": 10000},
+			}),
+			"required": []string{"video_id"},
+		},
+		OutputSchema: commentShape.listSchema(),
+		Annotations:  readOnly,
+	}, t.getVideoComments); err != nil {
+		return err
+	}
+
+	ownerOnly := &ToolAnnotations{ReadOnlyHint: boolPtr(false), DestructiveHint: boolPtr(false), IdempotentHint: boolPtr(false), OpenWorldHint: boolPtr(true)}
+
+	if err := RegisterTool(registry, Tool{
+		Name:        "reply_to_comment",
+		Description: "Posts a reply to a comment. Owner-only: the comment must be on a video owned by the authenticated channel.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": commentShape.withShapeArgs(map[string]interface{}{
+				"comment_id": map[string]interface{}{"type": "string", "description": "The ID of the top-level comment to reply to.", "minLength": 1},
+				"text":       map[string]interface{}{"type": "string", "description": "The content of the reply.", "minLength": 1, "maxLength": 10000},
+			}),
+			"required": []string{"comment_id", "text"},
+		},
+		OutputSchema: commentShape.itemSchema(),
+		Annotations:  ownerOnly,
+	}, t.replyToComment); err != nil {
+		return err
+	}
+
+	if err := RegisterTool(registry, Tool{
+		Name:        "add_video_to_playlist",
+		Description: "Adds a video to one of the user's playlists. Owner-only: the playlist must belong to the authenticated channel.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": playlistItemShape.withShapeArgs(map[string]interface{}{
+				"playlist_id": playlistIDSchema,
+				"video_id":    videoIDSchema,
+			}),
+			"required": []string{"playlist_id", "video_id"},
+		},
+		OutputSchema: playlistItemShape.itemSchema(),
+		Annotations:  ownerOnly,
+	}, t.addVideoToPlaylist); err != nil {
+		return err
+	}
+
+	if err := t.registerPlaylistTools(registry); err != nil {
+		return err
+	}
+	if err := t.registerChannelTools(registry); err != nil {
+		return err
+	}
+	if err := t.registerAccountTools(registry); err != nil {
+		return err
+	}
+	return t.registerQuotaTools(registry)
+}
+
+type getVideoMetadataArgs struct {
+	VideoID          string `json:"video_id"`
+	IncludeThumbnail bool   `json:"include_thumbnail"`
+	shapeArgs
+}
+
+func (t *youtubeTools) getVideoMetadata(ctx context.Context, args getVideoMetadataArgs) (interface{}, error) {
+	metadata, err := t.youtubeService.GetVideoMetadata(ctx, args.VideoID)
+	if err != nil {
+		return nil, apiError(err)
+	}
+	if len(metadata.Items) == 0 {
+		return nil, &ToolError{Code: string(service.ErrCodeNotFound), Message: fmt.Sprintf("Video %s not found.", args.VideoID)}
+	}
+	summary := newVideoSummary(metadata.Items[0])
+	output, err := videoShape.one(summary, args.shapeArgs)
+	if err != nil || !args.IncludeThumbnail || summary.Thumbnail == "" {
+		return output, err
+	}
+
+	// A missing thumbnail should not fail the whole call.
+	thumbnail, err := t.youtubeService.FetchThumbnail(ctx, summary.Thumbnail)
+	if err != nil {
+		log.Printf("Could not fetch thumbnail for video %s: %v", args.VideoID, err)
+		return output, nil
+	}
+	output.Content = append([]ContentItem{ImageContent(thumbnail.Data, thumbnail.MimeType)}, output.Content...)
+	return output, nil
+}
+
+type searchVideosArgs struct {
+	Query     string `json:"query"`
+	ChannelID string `json:"channel_id"`
+	Limit     int64  `json:"limit"`
+	Cursor    string `json:"cursor"`
+	MaxTotal  int64  `json:"max_total"`
+	shapeArgs
+}
+
+func (t *youtubeTools) searchVideos(ctx context.Context, args searchVideosArgs) (interface{}, error) {
+	if args.Limit == 0 {
+		args.Limit = 10
+	}
+	scope := newCursorScope("search_videos", args.Query, args.ChannelID)
+	page, err := pageRequest(scope, args.Cursor, args.Limit, args.MaxTotal, 50)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := t.youtubeService.SearchVideos(ctx, args.Query, args.ChannelID, page)
+	if err != nil {
+		return nil, apiError(err)
+	}
+	summaries := make([]VideoSummary, 0, len(results.Items))
+	for _, result := range results.Items {
+		summaries = append(summaries, newSearchResultSummary(result))
+	}
+	return videoShape.list(summaries, args.shapeArgs, encodeCursor(scope, results.NextPageToken), results.TotalResults)
+}
+
+type getVideoCommentsArgs struct {
+	VideoID  string `json:"video_id"`
+	SortBy   string `json:"sort_by"`
+	Limit    int64  `json:"limit"`
+	Cursor   string `json:"cursor"`
+	MaxTotal int64  `json:"max_total"`
+	shapeArgs
+}
+
+func (t *youtubeTools) getVideoComments(ctx context.Context, args getVideoCommentsArgs) (interface{}, error) {
+	if args.SortBy == "" {
+		args.SortBy = "top"
+	}
+	if args.Limit == 0 {
+		args.Limit = 20
+	}
+	scope := newCursorScope("get_video_comments", args.VideoID, args.SortBy)
+	page, err := pageRequest(scope, args.Cursor, args.Limit, args.MaxTotal, 100)
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := t.youtubeService.GetVideoComments(ctx, args.VideoID, commentOrders[args.SortBy], page)
+	if err != nil {
+		return nil, apiError(err)
+	}
+	summaries := make([]CommentSummary, 0, len(comments.Items))
+	for _, thread := range comments.Items {
+		summaries = append(summaries, newCommentThreadSummary(thread))
+	}
+	return commentShape.list(summaries, args.shapeArgs, encodeCursor(scope, comments.NextPageToken), 0)
+}
+
+// pageRequest builds the service page request for a paginated tool call.
+// Without max_total a single page of limit results is fetched; with it,
+// limit is ignored and full pages of maxPageSize are followed until
+// max_total is reached.
+func pageRequest(scope cursorScope, cursor string, limit int64, maxTotal int64, maxPageSize int64) (service.PageRequest, error) {
+	pageToken, err := decodeCursor(scope, cursor)
+	if err != nil {
+		return service.PageRequest{}, err
+	}
+	if maxTotal == 0 {
+		return service.PageRequest{PageToken: pageToken, PageSize: limit}, nil
+	}
+	return service.PageRequest{PageToken: pageToken, PageSize: maxPageSize, MaxTotal: maxTotal}, nil
+}
+
+type replyToCommentArgs struct {
+	CommentID string `json:"comment_id"`
+	Text      string `json:"text"`
+	shapeArgs
+}
+
+func (t *youtubeTools) replyToComment(ctx context.Context, args replyToCommentArgs) (interface{}, error) {
+	reply, err := t.youtubeService.ReplyToComment(ctx, args.CommentID, args.Text)
+	if err != nil {
+		return nil, ownerActionError(err)
+	}
+	return commentShape.one(newCommentSummary(reply), args.shapeArgs)
+}
+
+type addVideoToPlaylistArgs struct {
+	PlaylistID string `json:"playlist_id"`
+	VideoID    string `json:"video_id"`
+	shapeArgs
+}
+
+func (t *youtubeTools) addVideoToPlaylist(ctx context.Context, args addVideoToPlaylistArgs) (interface{}, error) {
+	item, err := t.youtubeService.AddVideoToPlaylist(ctx, args.PlaylistID, args.VideoID)
+	if err != nil {
+		return nil, ownerActionError(err)
+	}
+	return playlistItemShape.one(newPlaylistItemSummary(item), args.shapeArgs)
+}
+
+// available offers every tool to users who logged in with Google, and only
+// the public tools to anyone else when an API key is configured.
+func (t *youtubeTools) available(ctx context.Context, tool Tool) error {
+	if t.youtubeService.HasUserAccess(ctx) || tool.Public && t.youtubeService.HasAPIKey() {
+		return nil
+	}
+	if tool.Public {
+		return &ToolError{Code: "login_required", Message: fmt.Sprintf("Tool %s needs a Google login or a configured YouTube API key.", tool.Name)}
+	}
+	return &ToolError{Code: "login_required", Message: fmt.Sprintf("Tool %s acts on your YouTube account and needs a Google login. Connect with an authenticated MCP client to use it.", tool.Name)}
+}
+
+// ownerActionError turns a failed owner-only action into a tool error,
+// spelling out ownership failures so the assistant does not retry them.
+func ownerActionError(err error) error {
+	if errors.Is(err, service.ErrNotOwner) {
+		return &ToolError{Code: "not_owner", Message: fmt.Sprintf("Owner-only action refused: %v. This tool only works on videos and playlists of the authenticated channel.", err)}
+	}
+	return apiError(err)
+}
+
+// apiErrorMessages explain classified YouTube API errors to the assistant.
+var apiErrorMessages = map[service.ErrorCode]string{
+	service.ErrCodeQuotaExceeded:    "YouTube's daily API quota for this server is used up; it resets at midnight Pacific time",
+	service.ErrCodeRateLimited:      "YouTube is rate limiting requests; wait a moment before trying again",
+	service.ErrCodeCommentsDisabled: "Comments are disabled for this video",
+	service.ErrCodeNotFound:         "YouTube could not find the requested resource",
+	service.ErrCodeForbidden:        "YouTube refused the request",
+	service.ErrCodeUnauthorized:     "YouTube did not accept the Google login",
+	service.ErrCodeInvalidArgument:  "YouTube rejected the request",
+	service.ErrCodeBackendError:     "YouTube had a temporary error; try again later",
+}
+
+// apiError reports a failed YouTube API call as a tool error with a code.
+// A missing Google scope or an exhausted quota budget keeps its message, as
+// it tells the user what to do.
+func apiError(err error) error {
+	var scopeErr *service.ScopeRequiredError
+	if errors.As(err, &scopeErr) {
+		return &ToolError{Code: "scope_required", Message: scopeErr.Error()}
+	}
+	var quotaErr *service.QuotaExceededError
+	if errors.As(err, &quotaErr) {
+		return &ToolError{Code: string(service.ErrCodeQuotaExceeded), Message: quotaErr.Error()}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &ToolError{Code: "timeout", Message: "The YouTube API did not answer in time; try again.", Retryable: true}
+	}
+	if apiErr := service.ClassifyAPIError(err); apiErr != nil {
+		message, ok := apiErrorMessages[apiErr.Code]
+		if !ok {
+			message = "API Error"
+		}
+		return &ToolError{Code: string(apiErr.Code), Message: fmt.Sprintf("%s: %v", message, apiErr), Retryable: apiErr.Retryable}
+	}
+	return fmt.Errorf("API Error: %v", err)
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/yt-mcp-server/service"
+)
+
+func TestAPIErrorScopeRequired(t *testing.T) {
+	authURL := "http://localhost:8080/oauth/authorize?google_scope=youtube.force-ssl"
+	err := apiError(fmt.Errorf("failed to get YouTube service: %w", &service.ScopeRequiredError{Scope: service.ScopeManage, AuthURL: authURL}))
+
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) {
+		t.Fatalf("err = %v, want a *ToolError", err)
+	}
+	if toolErr.Code != "scope_required" || toolErr.Retryable {
+		t.Errorf("error = %+v, want code scope_required, not retryable", toolErr)
+	}
+	if !strings.Contains(toolErr.Message, authURL) {
+		t.Errorf("message %q does not name the consent URL", toolErr.Message)
+	}
+}
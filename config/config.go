@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -13,8 +15,42 @@ type Config struct {
 	GoogleClientSecret string
 	GoogleRedirectURI  string
 
+	// Google OAuth client of the "TVs and Limited Input devices" type, for
+	// the device login of the login command (optional). Google only offers
+	// the device flow to such clients, and they cannot do browser logins.
+	GoogleDeviceClientID     string
+	GoogleDeviceClientSecret string
+
+	// YouTubeAPIKey, if set, lets the public-data tools work without a
+	// Google login.
+	YouTubeAPIKey string
+
+	// Daily YouTube Data API quota budget in units, and the file the usage
+	// is kept in across restarts (optional).
+	QuotaBudget int64
+	QuotaFile   string
+
+	// CacheMaxBytes bounds the cache of YouTube API responses; 0 disables it.
+	CacheMaxBytes int64
+
+	// MetricsToken is the bearer token Prometheus scrapers send to
+	// /metrics. Without one, /metrics is not served.
+	MetricsToken string
+
+	// Token storage: "memory" or "file". The file store encrypts the token
+	// with TokenEncryptionKey; keys it replaced are kept in
+	// TokenEncryptionPreviousKeys so existing files can still be read.
+	TokenStore                  string
+	GoogleTokenFile             string
+	TokenEncryptionKey          string
+	TokenEncryptionPreviousKeys []string
+
+	// StdioUser selects, by email or Google account ID, whose token the
+	// stdio transport uses when more than one user is stored.
+	StdioUser string
+
 	// MCP OAuth (for Claude authentication)
-	MCPServerURL   string
+	MCPServerURL string
 
 	// Server config
 	Port        string
@@ -30,7 +66,19 @@ func Load() *Config {
 		GoogleClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
 		GoogleRedirectURI:  getEnv("GOOGLE_REDIRECT_URI", "http://localhost:8080/oauth/callback"),
 
-		MCPServerURL:   getEnv("MCP_SERVER_URL", "http://localhost:8080"),
+		GoogleDeviceClientID:     getEnv("GOOGLE_DEVICE_CLIENT_ID", ""),
+		GoogleDeviceClientSecret: getEnv("GOOGLE_DEVICE_CLIENT_SECRET", ""),
+
+		YouTubeAPIKey: getEnv("YOUTUBE_API_KEY", ""),
+		QuotaFile:     getEnv("QUOTA_FILE", ""),
+		MetricsToken:  getEnv("METRICS_TOKEN", ""),
+
+		GoogleTokenFile:             getEnv("GOOGLE_TOKEN_FILE", ""),
+		TokenEncryptionKey:          getEnv("TOKEN_ENCRYPTION_KEY", ""),
+		TokenEncryptionPreviousKeys: getEnvList("TOKEN_ENCRYPTION_PREVIOUS_KEYS"),
+		StdioUser:                   getEnv("STDIO_USER", ""),
+
+		MCPServerURL: getEnv("MCP_SERVER_URL", "http://localhost:8080"),
 
 		Port:        getEnv("PORT", "8080"),
 		Environment: getEnv("ENVIRONMENT", "development"),
@@ -43,6 +91,40 @@ func Load() *Config {
 	if config.GoogleClientSecret == "" {
 		log.Fatal("GOOGLE_CLIENT_SECRET environment variable is required")
 	}
+	if (config.GoogleDeviceClientID == "") != (config.GoogleDeviceClientSecret == "") {
+		log.Fatal("GOOGLE_DEVICE_CLIENT_ID and GOOGLE_DEVICE_CLIENT_SECRET must be set together")
+	}
+
+	budget, err := strconv.ParseInt(getEnv("YOUTUBE_QUOTA_BUDGET", "10000"), 10, 64)
+	if err != nil || budget < 0 {
+		log.Fatalf("YOUTUBE_QUOTA_BUDGET must be a non-negative number of units, got %q", os.Getenv("YOUTUBE_QUOTA_BUDGET"))
+	}
+	config.QuotaBudget = budget
+
+	cacheBytes, err := strconv.ParseInt(getEnv("CACHE_MAX_BYTES", "33554432"), 10, 64)
+	if err != nil || cacheBytes < 0 {
+		log.Fatalf("CACHE_MAX_BYTES must be a non-negative number of bytes, got %q", os.Getenv("CACHE_MAX_BYTES"))
+	}
+	config.CacheMaxBytes = cacheBytes
+
+	// The file store is the default whenever a token file is configured.
+	defaultStore := "memory"
+	if config.GoogleTokenFile != "" {
+		defaultStore = "file"
+	}
+	config.TokenStore = getEnv("TOKEN_STORE", defaultStore)
+	switch config.TokenStore {
+	case "memory":
+	case "file":
+		if config.GoogleTokenFile == "" {
+			log.Fatal("GOOGLE_TOKEN_FILE environment variable is required when TOKEN_STORE is file")
+		}
+		if config.TokenEncryptionKey == "" {
+			log.Fatal("TOKEN_ENCRYPTION_KEY environment variable is required when TOKEN_STORE is file")
+		}
+	default:
+		log.Fatalf("TOKEN_STORE must be memory or file, got %q", config.TokenStore)
+	}
 
 	return config
 }
@@ -53,3 +135,14 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvList returns the comma-separated values of key, without empty entries.
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
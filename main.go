@@ -2,11 +2,13 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -20,23 +22,79 @@ import (
 )
 
 func main() {
+	transport := flag.String("transport", "http", "MCP transport to serve: \"http\" or \"stdio\"")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [login]\n\n", os.Args[0])
+		fmt.Fprintln(flag.CommandLine.Output(), "  login\tLog in with Google using a code entered on another device, then exit")
+		fmt.Fprintln(flag.CommandLine.Output())
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *transport != "http" && *transport != "stdio" {
+		log.Fatalf("Unknown transport %q; expected \"http\" or \"stdio\"", *transport)
+	}
+	if flag.NArg() > 1 || flag.NArg() == 1 && flag.Arg(0) != "login" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Logs must never reach stdout, which carries the protocol in stdio mode.
+	log.SetOutput(os.Stderr)
+
 	// Load configuration
 	cfg := config.Load()
 
-	// Create an in-memory token store for our single user.
-	tokenStore := &service.InMemoryTokenStore{}
+	tokenStore, err := newTokenStore(cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up token store: %v", err)
+	}
 
 	// Initialize services
 	oauthService := service.NewOAuthService(cfg.MCPServerURL)
 	googleService := service.NewGoogleOAuthService(tokenStore, cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.GoogleRedirectURI)
-	youtubeService := service.NewYouTubeService(googleService)
+	if cfg.GoogleDeviceClientID != "" {
+		googleService.SetDeviceClient(cfg.GoogleDeviceClientID, cfg.GoogleDeviceClientSecret)
+	}
+	quota, err := service.NewQuotaTracker(cfg.QuotaBudget, cfg.QuotaFile)
+	if err != nil {
+		log.Fatalf("Failed to set up quota tracking: %v", err)
+	}
+	cache := service.NewResponseCache(cfg.CacheMaxBytes)
+	youtubeService := service.NewYouTubeService(googleService, cfg.YouTubeAPIKey, quota, cache)
+
+	// Tokens saved before they were stored per user belong to an unknown
+	// account until it is looked up.
+	if err := googleService.MigrateLegacyToken(context.Background()); err != nil {
+		log.Printf("⚠️ Could not migrate the stored Google token: %v", err)
+	}
+
+	if flag.Arg(0) == "login" {
+		deviceLogin(googleService, cfg)
+		return
+	}
 
 	// Start the proactive token refresher
 	go googleService.TokenRefresher(context.Background())
+	go oauthService.Reaper(context.Background())
+
+	// Register the MCP tools
+	tools := api.NewToolRegistry()
+	if err := api.RegisterYouTubeTools(tools, youtubeService); err != nil {
+		log.Fatalf("Failed to register YouTube tools: %v", err)
+	}
+
+	if *transport == "stdio" {
+		// The stdio transport has exactly one client and needs no sessions.
+		serveStdio(api.NewMCPHandler(tools, nil), googleService, cfg)
+		return
+	}
 
 	// Initialize handlers
-	oauthHandler := api.NewOAuthHandler(oauthService, googleService)
-	mcpHandler := api.NewMCPHandler(youtubeService)
+	oauthHandler := api.NewOAuthHandler(oauthService, googleService, youtubeService)
+	sessions := api.NewSessionManager(30 * time.Minute)
+	go sessions.Reaper(context.Background())
+	mcpHandler := api.NewMCPHandler(tools, sessions)
 
 	// Setup HTTP router
 	r := chi.NewRouter()
@@ -44,27 +102,46 @@ func main() {
 	// Middleware
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(60 * time.Second))
 
 	// CORS configuration
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"https://claude.ai", "https://*.claude.ai", "http://localhost:*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Mcp-Session-Id", "Mcp-Protocol-Version", "Last-Event-ID"},
+		ExposedHeaders:   []string{"Link", "Mcp-Session-Id"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
 	// OAuth routes
-	api.SetupOAuthRoutes(r, oauthHandler)
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(60 * time.Second))
+		api.SetupOAuthRoutes(r, oauthHandler)
+	})
 
-	// MCP endpoint (protected)
+	// MCP endpoint (protected), using the Streamable HTTP transport.
+	// There is no request timeout: a POST may be upgraded to a long-lived
+	// event stream, where a 504 could no longer be sent. Tool calls stop
+	// when the client disconnects or sends notifications/cancelled.
 	r.Route("/mcp", func(r chi.Router) {
-		r.Use(oauthHandler.RequireAuth)
+		// With an API key, clients may connect without a token and use
+		// the public-data tools.
+		if cfg.YouTubeAPIKey != "" {
+			r.Use(oauthHandler.OptionalAuth)
+		} else {
+			r.Use(oauthHandler.RequireAuth)
+		}
 		r.Post("/", mcpHandler.HandleMCP)
+		r.Get("/", mcpHandler.HandleMCPStream)
+		r.Delete("/", mcpHandler.HandleMCPDelete)
 	})
 
+	// Quota and cache metrics for Prometheus, only for scrapers that know
+	// the metrics token.
+	if cfg.MetricsToken != "" {
+		r.Get("/metrics", api.MetricsHandler(quota, cache, cfg.MetricsToken))
+	}
+
 	// Health check endpoint
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -113,11 +190,19 @@ func main() {
             <div class="code">POST /oauth/register</div>
             <div class="code">GET /oauth/authorize</div>
             <div class="code">POST /oauth/token</div>
+            <div class="code">POST /oauth/revoke</div>
+            <div class="code">GET /oauth/status</div>
+            <div class="code">POST /oauth/logout</div>
         </div>
         <div class="endpoint">
             <strong>MCP Protocol:</strong>
             <div class="code">POST /mcp (requires authentication)</div>
         </div>
+        <div class="endpoint">
+            <strong>Monitoring:</strong>
+            <div class="code">GET /health</div>
+            <div class="code">GET /metrics (requires METRICS_TOKEN)</div>
+        </div>
     </div>
 
     <div class="section">
@@ -178,4 +263,103 @@ func main() {
 	log.Println("Server shutdown complete")
 }
 
+// newTokenStore creates the token store selected by the configuration. The
+// file store keeps the token encrypted on disk, so it survives restarts and
+// can be shared with the stdio transport, which has no browser callback route.
+func newTokenStore(cfg *config.Config) (service.TokenStore, error) {
+	if cfg.TokenStore != "file" {
+		return &service.InMemoryTokenStore{}, nil
+	}
 
+	key, err := service.ParseEncryptionKey(cfg.TokenEncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOKEN_ENCRYPTION_KEY: %w", err)
+	}
+	var previousKeys [][]byte
+	for i, encoded := range cfg.TokenEncryptionPreviousKeys {
+		previous, err := service.ParseEncryptionKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %d in TOKEN_ENCRYPTION_PREVIOUS_KEYS: %w", i+1, err)
+		}
+		previousKeys = append(previousKeys, previous)
+	}
+
+	return service.NewFileTokenStore(cfg.GoogleTokenFile, key, previousKeys...)
+}
+
+// serveStdio runs the MCP server over stdin/stdout until stdin is closed or
+// the process is interrupted. All calls act as the user selected by
+// stdioUser.
+func serveStdio(mcpHandler *api.MCPHandler, googleService *service.GoogleOAuthService, cfg *config.Config) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if user, err := stdioUser(googleService, cfg); err != nil {
+		if cfg.YouTubeAPIKey != "" {
+			log.Printf("⚠️ %v; only the public-data tools will be available in stdio mode.", err)
+		} else {
+			log.Printf("⚠️ %v; YouTube tools will be unavailable in stdio mode.", err)
+		}
+	} else {
+		log.Printf("👤 Using the Google account of %s", user.Email)
+		ctx = service.WithPrincipal(ctx, &service.Principal{Subject: user.Subject, Email: user.Email})
+	}
+
+	log.Println("🚀 YouTube MCP Server serving on stdio")
+	if err := mcpHandler.ServeStdio(ctx, os.Stdin, os.Stdout); err != nil {
+		log.Fatalf("Stdio transport error: %v", err)
+	}
+	log.Println("Stdin closed, shutting down")
+}
+
+// deviceLogin logs in with Google's device authorization grant, for
+// machines without a browser, and stores the token in the file token store
+// where a server started later picks it up.
+func deviceLogin(googleService *service.GoogleOAuthService, cfg *config.Config) {
+	if cfg.TokenStore != "file" {
+		log.Fatal("The login command needs the file token store; set GOOGLE_TOKEN_FILE and TOKEN_ENCRYPTION_KEY")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	device, err := googleService.StartDeviceLogin(ctx)
+	if err != nil {
+		log.Fatalf("Device login failed: %v", err)
+	}
+	fmt.Printf("To log in, open %s on any device and enter the code %s\n", device.VerificationURI, device.UserCode)
+	if !device.Expiry.IsZero() {
+		fmt.Printf("The code expires at %s. Waiting for approval...\n", device.Expiry.Format(time.Kitchen))
+	}
+
+	identity, err := googleService.CompleteDeviceLogin(ctx, device)
+	if err != nil {
+		log.Fatalf("Device login failed: %v", err)
+	}
+	fmt.Printf("Logged in as %s. The token was saved to %s.\n", identity.Email, cfg.GoogleTokenFile)
+}
+
+// stdioUser picks the stored user the stdio transport acts as: the one named
+// by STDIO_USER, or the only stored user.
+func stdioUser(googleService *service.GoogleOAuthService, cfg *config.Config) (*service.GoogleIdentity, error) {
+	if cfg.TokenStore != "file" {
+		return nil, fmt.Errorf("no file token store is configured")
+	}
+	users := googleService.StoredUsers()
+	if len(users) == 0 {
+		return nil, fmt.Errorf("no Google token found in %s; authorize once in HTTP mode with the same GOOGLE_TOKEN_FILE", cfg.GoogleTokenFile)
+	}
+
+	if cfg.StdioUser != "" {
+		for _, user := range users {
+			if user.Subject == cfg.StdioUser || strings.EqualFold(user.Email, cfg.StdioUser) {
+				return &user, nil
+			}
+		}
+		return nil, fmt.Errorf("no Google token found for STDIO_USER %s", cfg.StdioUser)
+	}
+	if len(users) > 1 {
+		return nil, fmt.Errorf("%d Google accounts are stored in %s; set STDIO_USER to choose one", len(users), cfg.GoogleTokenFile)
+	}
+	return &users[0], nil
+}
@@ -1,33 +1,119 @@
 package service
 
 import (
+	"sort"
 	"sync"
 
 	"golang.org/x/oauth2"
 )
 
-// InMemoryTokenStore holds the token in memory for a single user.
+// LegacyUserID is the key of a token saved before tokens were stored per
+// user, whose Google account is not known yet. GoogleOAuthService moves it
+// to the right user with MigrateLegacyToken.
+const LegacyUserID = ""
+
+// Google OAuth clients a token can be issued to. Tokens can only be
+// refreshed by the client they were issued to.
+const (
+	// GoogleClientWeb is the web application client of browser logins.
+	GoogleClientWeb = ""
+	// GoogleClientDevice is the "TVs and Limited Input devices" client of
+	// device logins.
+	GoogleClientDevice = "device"
+)
+
+// StoredToken is a user's Google token together with what we know about
+// the account it belongs to.
+type StoredToken struct {
+	Token *oauth2.Token `json:"token"`
+	Email string        `json:"email,omitempty"`
+	// Client is the Google OAuth client that issued the token, one of
+	// GoogleClientWeb and GoogleClientDevice.
+	Client string `json:"client,omitempty"`
+	// Scopes are the Google scopes granted to the token.
+	Scopes []string `json:"scopes,omitempty"`
+	// RefreshError is set when Google rejected the refresh token, which
+	// then stays unusable until the user logs in again.
+	RefreshError string `json:"refresh_error,omitempty"`
+}
+
+// GrantedScopes returns the scopes granted to the token. Tokens stored
+// before scopes were tracked were granted the full set requested back then.
+func (t *StoredToken) GrantedScopes() []string {
+	if len(t.Scopes) == 0 {
+		return legacyScopes
+	}
+	return t.Scopes
+}
+
+// TokenStore holds the Google OAuth tokens of every user, keyed by the
+// stable Google account ID. Implementations must be safe for concurrent use.
+type TokenStore interface {
+	// GetToken returns a copy of the user's stored token, or nil if there is none.
+	GetToken(userID string) *StoredToken
+	// SetToken replaces the user's stored token. A nil token removes it.
+	// If an error is returned the stored token is unchanged.
+	SetToken(userID string, token *StoredToken) error
+	// UserIDs returns the IDs of all users with a stored token.
+	UserIDs() []string
+}
+
+// InMemoryTokenStore holds the tokens in memory.
 // A mutex is used to handle concurrent access safely.
 type InMemoryTokenStore struct {
-	mu    sync.RWMutex
-	token *oauth2.Token
+	mu     sync.RWMutex
+	tokens map[string]*StoredToken
 }
 
-// GetToken retrieves the token from the store.
-func (ts *InMemoryTokenStore) GetToken() *oauth2.Token {
+// GetToken retrieves a user's token from the store.
+func (ts *InMemoryTokenStore) GetToken(userID string) *StoredToken {
 	ts.mu.RLock()
 	defer ts.mu.RUnlock()
-	if ts.token == nil {
+	return copyStoredToken(ts.tokens[userID])
+}
+
+// SetToken saves a user's token to the store.
+func (ts *InMemoryTokenStore) SetToken(userID string, token *StoredToken) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if token == nil {
+		delete(ts.tokens, userID)
 		return nil
 	}
-	// Return a copy to avoid race conditions if the caller modifies it
-	tokenCopy := *ts.token
+	if ts.tokens == nil {
+		ts.tokens = make(map[string]*StoredToken)
+	}
+	ts.tokens[userID] = copyStoredToken(token)
+	return nil
+}
+
+// UserIDs returns the users with a stored token.
+func (ts *InMemoryTokenStore) UserIDs() []string {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return sortedUserIDs(ts.tokens)
+}
+
+// copyStoredToken returns a deep copy of token so callers cannot modify the
+// stored one.
+func copyStoredToken(token *StoredToken) *StoredToken {
+	if token == nil {
+		return nil
+	}
+	tokenCopy := *token
+	tokenCopy.Scopes = append([]string(nil), token.Scopes...)
+	if token.Token != nil {
+		oauthToken := *token.Token
+		tokenCopy.Token = &oauthToken
+	}
 	return &tokenCopy
 }
 
-// SetToken saves the token to the store.
-func (ts *InMemoryTokenStore) SetToken(token *oauth2.Token) {
-	ts.mu.Lock()
-	defer ts.mu.Unlock()
-	ts.token = token
+func sortedUserIDs(tokens map[string]*StoredToken) []string {
+	ids := make([]string, 0, len(tokens))
+	for id := range tokens {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
 }
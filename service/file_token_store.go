@@ -0,0 +1,344 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// tokenFileVersion is the version of the encrypted token file format.
+// Version 1 held a single token; version 2 holds the tokens of every user.
+const tokenFileVersion = 2
+
+// tokenFileAAD binds the ciphertext to its file format version, so an
+// encrypted blob from another purpose cannot be passed off as tokens.
+var tokenFileAAD = map[int][]byte{
+	1: []byte("yt-mcp-server/token/v1"),
+	2: []byte("yt-mcp-server/tokens/v2"),
+}
+
+// tokenFile is the on-disk form of the encrypted tokens.
+type tokenFile struct {
+	Version    int    `json:"version"`
+	KeyID      string `json:"key_id"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// FileTokenStore keeps the tokens of all users in one file, encrypted with
+// AES-256-GCM. Writes are atomic, and a lock file serializes access between
+// processes that share the file (for example an HTTP and a stdio server).
+// Changes made by another process are picked up on the next read.
+type FileTokenStore struct {
+	mu      sync.Mutex
+	path    string
+	primary string
+	keys    map[string]cipher.AEAD
+
+	tokens  map[string]*StoredToken
+	modTime time.Time
+	size    int64
+}
+
+// NewFileTokenStore creates a token store backed by the encrypted file at
+// path. New tokens are encrypted with key; previousKeys are only used to
+// decrypt a file written before a key rotation, which is then re-encrypted
+// with key. Keys must be 32 bytes long.
+func NewFileTokenStore(path string, key []byte, previousKeys ...[]byte) (*FileTokenStore, error) {
+	ts := &FileTokenStore{path: path, keys: make(map[string]cipher.AEAD)}
+
+	for i, k := range append([][]byte{key}, previousKeys...) {
+		id, aead, err := newTokenCipher(k)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			ts.primary = id
+		}
+		if _, exists := ts.keys[id]; !exists {
+			ts.keys[id] = aead
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create token directory: %w", err)
+	}
+
+	unlock, err := lockFile(path+".lock", true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock token file: %w", err)
+	}
+	defer unlock()
+
+	rewrite, err := ts.loadLocked()
+	if err != nil {
+		return nil, err
+	}
+	if rewrite && len(ts.tokens) > 0 {
+		if err := ts.writeLocked(); err != nil {
+			return nil, fmt.Errorf("failed to re-encrypt token file: %w", err)
+		}
+		log.Printf("🔑 Re-encrypted %s with the current token encryption key and format", path)
+	}
+	return ts, nil
+}
+
+// ParseEncryptionKey decodes a base64-encoded 32-byte encryption key.
+func ParseEncryptionKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		key, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("encryption key is not valid base64: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// GetToken returns a user's token, reloading the file first if it was
+// changed by another process.
+func (ts *FileTokenStore) GetToken(userID string) *StoredToken {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.refreshLocked()
+	return copyStoredToken(ts.tokens[userID])
+}
+
+// UserIDs returns the users with a stored token.
+func (ts *FileTokenStore) UserIDs() []string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.refreshLocked()
+	return sortedUserIDs(ts.tokens)
+}
+
+// SetToken updates a user's token and rewrites the file, or removes the
+// file when no user has a token left. The file is re-read under the lock
+// first, so changes to other users made by another process are kept. If
+// it cannot be re-read nothing is written, as that would replace those
+// changes with the tokens cached by this process.
+func (ts *FileTokenStore) SetToken(userID string, token *StoredToken) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	unlock, err := lockFile(ts.path+".lock", true)
+	if err != nil {
+		return fmt.Errorf("failed to lock token file: %w", err)
+	}
+	defer unlock()
+
+	if _, err := ts.loadLocked(); err != nil {
+		return err
+	}
+	ts.applyLocked(userID, token)
+	if err := ts.writeLocked(); err != nil {
+		// Forget the unsaved change: the next read reloads the file.
+		ts.modTime, ts.size = time.Time{}, -1
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	return nil
+}
+
+func (ts *FileTokenStore) applyLocked(userID string, token *StoredToken) {
+	if token == nil {
+		delete(ts.tokens, userID)
+		return
+	}
+	if ts.tokens == nil {
+		ts.tokens = make(map[string]*StoredToken)
+	}
+	ts.tokens[userID] = copyStoredToken(token)
+}
+
+// refreshLocked reloads the file if it changed since it was last read.
+func (ts *FileTokenStore) refreshLocked() {
+	info, err := os.Stat(ts.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		ts.tokens, ts.modTime, ts.size = nil, time.Time{}, 0
+	case err != nil:
+		log.Printf("Failed to stat token file %s: %v", ts.path, err)
+	case !info.ModTime().Equal(ts.modTime) || info.Size() != ts.size:
+		if err := ts.reload(); err != nil {
+			// Keep the cached tokens and do not retry until the file changes again.
+			log.Printf("Failed to reload token file %s: %v", ts.path, err)
+			ts.modTime, ts.size = info.ModTime(), info.Size()
+		}
+	}
+}
+
+func (ts *FileTokenStore) reload() error {
+	unlock, err := lockFile(ts.path+".lock", false)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	_, err = ts.loadLocked()
+	return err
+}
+
+// loadLocked reads and decrypts the token file. It reports whether the
+// file should be rewritten because it uses an older format or is not
+// encrypted with the primary key. A single token from an older format is
+// kept under LegacyUserID.
+func (ts *FileTokenStore) loadLocked() (bool, error) {
+	data, err := os.ReadFile(ts.path)
+	if errors.Is(err, os.ErrNotExist) {
+		ts.tokens, ts.modTime, ts.size = nil, time.Time{}, 0
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read token file: %w", err)
+	}
+	info, err := os.Stat(ts.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat token file: %w", err)
+	}
+
+	var file tokenFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return false, fmt.Errorf("failed to parse token file: %w", err)
+	}
+
+	if file.Version == 0 {
+		// Files written before encryption was added hold the plain token.
+		var token oauth2.Token
+		if err := json.Unmarshal(data, &token); err != nil || token.AccessToken == "" && token.RefreshToken == "" {
+			return false, fmt.Errorf("token file %s is neither an encrypted nor a plain token", ts.path)
+		}
+		ts.tokens = map[string]*StoredToken{LegacyUserID: {Token: &token}}
+		ts.modTime, ts.size = info.ModTime(), info.Size()
+		return true, nil
+	}
+	aad, ok := tokenFileAAD[file.Version]
+	if !ok {
+		return false, fmt.Errorf("token file has unsupported version %d", file.Version)
+	}
+
+	aead, ok := ts.keys[file.KeyID]
+	if !ok {
+		return false, fmt.Errorf("token file is encrypted with unknown key %s", file.KeyID)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(file.Nonce)
+	if err != nil || len(nonce) != aead.NonceSize() {
+		return false, fmt.Errorf("token file has an invalid nonce")
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(file.Ciphertext)
+	if err != nil {
+		return false, fmt.Errorf("token file has invalid ciphertext: %w", err)
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return false, fmt.Errorf("failed to decrypt token file: %w", err)
+	}
+
+	tokens := map[string]*StoredToken{}
+	if file.Version == 1 {
+		var token oauth2.Token
+		if err := json.Unmarshal(plaintext, &token); err != nil {
+			return false, fmt.Errorf("failed to parse decrypted token: %w", err)
+		}
+		tokens[LegacyUserID] = &StoredToken{Token: &token}
+	} else if err := json.Unmarshal(plaintext, &tokens); err != nil {
+		return false, fmt.Errorf("failed to parse decrypted tokens: %w", err)
+	}
+	ts.tokens, ts.modTime, ts.size = tokens, info.ModTime(), info.Size()
+	return file.Version != tokenFileVersion || file.KeyID != ts.primary, nil
+}
+
+// writeLocked atomically replaces the token file: the new content is
+// written to a temporary file in the same directory and renamed over it.
+func (ts *FileTokenStore) writeLocked() error {
+	if len(ts.tokens) == 0 {
+		if err := os.Remove(ts.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		ts.modTime, ts.size = time.Time{}, 0
+		return nil
+	}
+
+	plaintext, err := json.Marshal(ts.tokens)
+	if err != nil {
+		return err
+	}
+	aead := ts.keys[ts.primary]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	data, err := json.Marshal(tokenFile{
+		Version:    tokenFileVersion,
+		KeyID:      ts.primary,
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(aead.Seal(nil, nonce, plaintext, tokenFileAAD[tokenFileVersion])),
+	})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(ts.path), filepath.Base(ts.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), ts.path); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(ts.path); err == nil {
+		ts.modTime, ts.size = info.ModTime(), info.Size()
+	}
+	return nil
+}
+
+// newTokenCipher creates the AES-GCM cipher for key, identified by a short
+// hash of the key so the right key can be picked after a rotation.
+func newTokenCipher(key []byte) (string, cipher.AEAD, error) {
+	if len(key) != 32 {
+		return "", nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8]), aead, nil
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func testStoredToken(access string) *StoredToken {
+	return &StoredToken{
+		Token:  &oauth2.Token{AccessToken: access, RefreshToken: "refresh-" + access},
+		Email:  access + "@example.com",
+		Scopes: []string{ScopeReadOnly},
+	}
+}
+
+func readTokenFile(t *testing.T, path string) tokenFile {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file tokenFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestFileTokenStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	key := testKey(t)
+
+	store, err := NewFileTokenStore(path, key)
+	if err != nil {
+		t.Fatalf("NewFileTokenStore: %v", err)
+	}
+	if err := store.SetToken("alice", testStoredToken("alice")); err != nil {
+		t.Fatalf("SetToken: %v", err)
+	}
+	if err := store.SetToken("bob", testStoredToken("bob")); err != nil {
+		t.Fatalf("SetToken: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("refresh-alice")) {
+		t.Fatal("token file holds the refresh token in plain text")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("token file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	reopened, err := NewFileTokenStore(path, key)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	got := reopened.GetToken("alice")
+	if got == nil || got.Token.RefreshToken != "refresh-alice" || got.Email != "alice@example.com" {
+		t.Fatalf("GetToken(alice) = %+v", got)
+	}
+	if ids := reopened.UserIDs(); len(ids) != 2 || ids[0] != "alice" || ids[1] != "bob" {
+		t.Errorf("UserIDs = %v, want [alice bob]", ids)
+	}
+
+	if err := reopened.SetToken("alice", nil); err != nil {
+		t.Fatalf("SetToken(nil): %v", err)
+	}
+	if err := reopened.SetToken("bob", nil); err != nil {
+		t.Fatalf("SetToken(nil): %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("token file still exists after removing every token: %v", err)
+	}
+}
+
+func TestFileTokenStoreSeesOtherProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	key := testKey(t)
+
+	first, err := NewFileTokenStore(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewFileTokenStore(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := first.SetToken("alice", testStoredToken("alice")); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.SetToken("bob", testStoredToken("bob")); err != nil {
+		t.Fatal(err)
+	}
+	if first.GetToken("bob") == nil || second.GetToken("alice") == nil {
+		t.Fatal("a store lost the token written by the other one")
+	}
+}
+
+func TestFileTokenStoreKeyRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	oldKey, newKey := testKey(t), testKey(t)
+
+	store, err := NewFileTokenStore(path, oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetToken("alice", testStoredToken("alice")); err != nil {
+		t.Fatal(err)
+	}
+	oldKeyID := readTokenFile(t, path).KeyID
+
+	rotated, err := NewFileTokenStore(path, newKey, oldKey)
+	if err != nil {
+		t.Fatalf("NewFileTokenStore after rotation: %v", err)
+	}
+	if got := rotated.GetToken("alice"); got == nil || got.Token.AccessToken != "alice" {
+		t.Fatalf("GetToken after rotation = %+v", got)
+	}
+	if readTokenFile(t, path).KeyID == oldKeyID {
+		t.Error("token file was not re-encrypted with the new key")
+	}
+
+	// The old key is no longer needed once the file was re-encrypted.
+	if _, err := NewFileTokenStore(path, newKey); err != nil {
+		t.Errorf("NewFileTokenStore with only the new key: %v", err)
+	}
+}
+
+func TestFileTokenStoreRejectsWrongKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+
+	store, err := NewFileTokenStore(path, testKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetToken("alice", testStoredToken("alice")); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(path)
+
+	if _, err := NewFileTokenStore(path, testKey(t)); err == nil {
+		t.Fatal("NewFileTokenStore accepted a key the file was not encrypted with")
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(before, after) {
+		t.Error("token file was modified by a store with the wrong key")
+	}
+	if _, err := NewFileTokenStore(path, []byte("short")); err == nil {
+		t.Error("NewFileTokenStore accepted a key that is not 32 bytes")
+	}
+}
+
+func TestFileTokenStoreMigratesLegacyFiles(t *testing.T) {
+	key := testKey(t)
+	legacy := oauth2.Token{AccessToken: "legacy", RefreshToken: "refresh-legacy"}
+
+	plain, _ := json.Marshal(legacy)
+	keyID, aead, err := newTokenCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	rand.Read(nonce)
+	encrypted, _ := json.Marshal(tokenFile{
+		Version:    1,
+		KeyID:      keyID,
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext: base64.StdEncoding.EncodeToString(aead.Seal(nil, nonce, plain, tokenFileAAD[1])),
+	})
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"plain token", plain},
+		{"encrypted single token", encrypted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "token.json")
+			if err := os.WriteFile(path, tt.data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			store, err := NewFileTokenStore(path, key)
+			if err != nil {
+				t.Fatalf("NewFileTokenStore: %v", err)
+			}
+			got := store.GetToken(LegacyUserID)
+			if got == nil || got.Token.RefreshToken != "refresh-legacy" {
+				t.Fatalf("GetToken(LegacyUserID) = %+v", got)
+			}
+			if file := readTokenFile(t, path); file.Version != tokenFileVersion {
+				t.Errorf("file version = %d, want it rewritten as %d", file.Version, tokenFileVersion)
+			}
+		})
+	}
+}
+
+func TestFileTokenStoreDoesNotOverwriteUnreadableFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	key := testKey(t)
+
+	store, err := NewFileTokenStore(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetToken("alice", testStoredToken("alice")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another process wrote a file this one cannot decrypt.
+	other, err := NewFileTokenStore(filepath.Join(t.TempDir(), "other.json"), testKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.SetToken("bob", testStoredToken("bob")); err != nil {
+		t.Fatal(err)
+	}
+	foreign, _ := os.ReadFile(other.path)
+	if err := os.WriteFile(path, foreign, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.SetToken("carol", testStoredToken("carol")); err == nil {
+		t.Fatal("SetToken succeeded although the file could not be reloaded")
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, foreign) {
+		t.Error("SetToken overwrote a file it could not read")
+	}
+}
+
+func TestFileTokenStoreKeepsIssuingClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	key := testKey(t)
+
+	store, err := NewFileTokenStore(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	device := testStoredToken("alice")
+	device.Client = GoogleClientDevice
+	if err := store.SetToken("alice", device); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewFileTokenStore(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.GetToken("alice"); got == nil || got.Client != GoogleClientDevice {
+		t.Fatalf("GetToken(alice) = %+v, want the device client recorded", got)
+	}
+}
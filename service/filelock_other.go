@@ -0,0 +1,9 @@
+//go:build !unix
+
+package service
+
+// lockFile is a no-op where advisory file locks are unavailable. Access
+// from a single process is still serialized by the store's mutex.
+func lockFile(path string, exclusive bool) (func(), error) {
+	return func() {}, nil
+}
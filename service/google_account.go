@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// googleRevokeURL is Google's token revocation endpoint.
+const googleRevokeURL = "https://oauth2.googleapis.com/revoke"
+
+// AccountStatus describes the Google account connected for the user of a
+// request.
+type AccountStatus struct {
+	Connected   bool       `json:"connected"`
+	UserID      string     `json:"user_id,omitempty"`
+	Email       string     `json:"email,omitempty"`
+	Scopes      []string   `json:"scopes,omitempty"`
+	TokenExpiry *time.Time `json:"token_expiry,omitempty"`
+	// LoginError explains why a stored login can no longer be used.
+	LoginError string `json:"login_error,omitempty"`
+	// ClientID is the MCP client the request was made with.
+	ClientID string `json:"client_id,omitempty"`
+}
+
+// Status returns the Google connection of the principal of the request.
+func (s *GoogleOAuthService) Status(ctx context.Context) *AccountStatus {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return &AccountStatus{}
+	}
+
+	status := &AccountStatus{UserID: principal.Subject, Email: principal.Email, ClientID: principal.ClientID}
+	stored := s.tokenStore.GetToken(principal.Subject)
+	if stored == nil || stored.Token == nil {
+		return status
+	}
+	status.Connected = stored.RefreshError == ""
+	status.Scopes = stored.GrantedScopes()
+	status.LoginError = stored.RefreshError
+	if stored.Email != "" {
+		status.Email = stored.Email
+	}
+	if !stored.Token.Expiry.IsZero() {
+		expiry := stored.Token.Expiry
+		status.TokenExpiry = &expiry
+	}
+	return status
+}
+
+// Logout revokes a user's Google token at Google and removes it from the
+// store. The token is removed even if Google cannot be reached, in which
+// case the error is returned; the user can still revoke access in their
+// Google account settings. If the token cannot be removed from the store
+// it is not revoked either, so that logging out can be retried.
+func (s *GoogleOAuthService) Logout(ctx context.Context, userID string) error {
+	stored := s.tokenStore.GetToken(userID)
+	if stored == nil {
+		return nil
+	}
+	if err := s.tokenStore.SetToken(userID, nil); err != nil {
+		return fmt.Errorf("failed to remove the stored token: %w", err)
+	}
+	s.forgetTokenSource(userID)
+	log.Printf("👋 Removed the Google token of %s.", stored.Email)
+
+	if stored.Token == nil {
+		return nil
+	}
+	// Revoking the refresh token also revokes the access tokens issued for it.
+	token := stored.Token.RefreshToken
+	if token == "" {
+		token = stored.Token.AccessToken
+	}
+	if err := revokeGoogleToken(ctx, s.revokeURL, token); err != nil {
+		return fmt.Errorf("failed to revoke the token at Google: %w", err)
+	}
+	return nil
+}
+
+// revokeGoogleToken revokes a token at Google's revocation endpoint
+// revokeURL. Tokens that are already invalid count as revoked. Google
+// identifies the client from the token itself, so this works for tokens of
+// both the web and the device client.
+func revokeGoogleToken(ctx context.Context, revokeURL, token string) error {
+	form := url.Values{"token": {token}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, revokeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Google answers 400 invalid_token for tokens that were already revoked.
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("Google returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// revokeEndpoint serves Google's token revocation endpoint and records the
+// tokens revoked.
+type revokeEndpoint struct {
+	mu      sync.Mutex
+	revoked []string
+}
+
+func newRevokeEndpoint(t *testing.T, s *GoogleOAuthService) *revokeEndpoint {
+	t.Helper()
+	endpoint := &revokeEndpoint{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		endpoint.mu.Lock()
+		endpoint.revoked = append(endpoint.revoked, r.FormValue("token"))
+		endpoint.mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+	s.revokeURL = server.URL
+	return endpoint
+}
+
+func TestLogoutRemovesOnlyCallersToken(t *testing.T) {
+	store := &InMemoryTokenStore{}
+	store.SetToken("alice", userToken("alice", ScopeReadOnly))
+	store.SetToken("carol", userToken("carol", ScopeReadOnly))
+	s := NewGoogleOAuthService(store, "web-id", "web-secret", "http://localhost:8080/oauth/callback")
+	endpoint := newRevokeEndpoint(t, s)
+
+	if _, err := s.UserTokenSource(asUser("alice"), ScopeReadOnly); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Logout(context.Background(), "alice"); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+
+	if store.GetToken("alice") != nil {
+		t.Error("alice's token is still stored")
+	}
+	if store.GetToken("carol") == nil {
+		t.Error("carol's token was removed by alice's logout")
+	}
+	if !reflect.DeepEqual(endpoint.revoked, []string{"alice-refresh"}) {
+		t.Errorf("revoked %v, want only alice's refresh token", endpoint.revoked)
+	}
+	if _, err := s.UserTokenSource(asUser("alice"), ScopeReadOnly); err == nil {
+		t.Error("alice still gets a token source after logging out")
+	}
+
+	// Logging out again does nothing.
+	if err := s.Logout(context.Background(), "alice"); err != nil || len(endpoint.revoked) != 1 {
+		t.Errorf("second logout: err = %v, revoked %v", err, endpoint.revoked)
+	}
+}
+
+func TestStatusReportsScopes(t *testing.T) {
+	rejected := userToken("alice", ScopeReadOnly)
+	rejected.RefreshError = "token revoked"
+
+	tests := []struct {
+		name          string
+		token         *StoredToken
+		wantConnected bool
+		wantScopes    []string
+	}{
+		{name: "not logged in", token: nil},
+		{name: "read only", token: userToken("alice", ScopeReadOnly), wantConnected: true, wantScopes: []string{ScopeReadOnly}},
+		{name: "granted manage", token: userToken("alice", ScopeReadOnly, ScopeManage), wantConnected: true, wantScopes: []string{ScopeReadOnly, ScopeManage}},
+		{name: "stored before scopes were tracked", token: userToken("alice"), wantConnected: true, wantScopes: legacyScopes},
+		{name: "rejected login", token: rejected, wantConnected: false, wantScopes: []string{ScopeReadOnly}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &InMemoryTokenStore{}
+			if tt.token != nil {
+				store.SetToken("alice", tt.token)
+			}
+			store.SetToken("carol", userToken("carol", ScopeManage))
+			s := NewGoogleOAuthService(store, "web-id", "web-secret", "http://localhost:8080/oauth/callback")
+
+			status := s.Status(asUser("alice"))
+			if status.UserID != "alice" || status.Connected != tt.wantConnected || !reflect.DeepEqual(status.Scopes, tt.wantScopes) {
+				t.Errorf("status = %+v, want connected %v with scopes %v", status, tt.wantConnected, tt.wantScopes)
+			}
+			if tt.token != nil && status.Email != "alice@example.com" {
+				t.Errorf("email = %q, want alice's", status.Email)
+			}
+		})
+	}
+
+	if status := NewGoogleOAuthService(&InMemoryTokenStore{}, "web-id", "web-secret", "").Status(context.Background()); status.UserID != "" || status.Connected {
+		t.Errorf("status without a principal = %+v, want none", status)
+	}
+}
@@ -2,99 +2,283 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
+	googleoauth "google.golang.org/api/oauth2/v2"
 	"google.golang.org/api/option"
-	"google.golang.org/api/youtube/v3"
 )
 
 // GoogleOAuthService handles the Google OAuth2 flow and token management.
 type GoogleOAuthService struct {
-	tokenStore  *InMemoryTokenStore
+	tokenStore  TokenStore
 	oauthConfig *oauth2.Config
+	// deviceConfig is the client of device logins, if one is configured.
+	deviceConfig *oauth2.Config
+	// revokeURL is where tokens are revoked on logout.
+	revokeURL string
+
+	sourcesMu sync.Mutex
+	sources   map[string]*userTokenSource
 }
 
 // NewGoogleOAuthService creates a new GoogleOAuthService.
-func NewGoogleOAuthService(tokenStore *InMemoryTokenStore, clientID, clientSecret, redirectURI string) *GoogleOAuthService {
+func NewGoogleOAuthService(tokenStore TokenStore, clientID, clientSecret, redirectURI string) *GoogleOAuthService {
 	config := &oauth2.Config{
 		ClientID:     clientID,
 		ClientSecret: clientSecret,
 		RedirectURL:  redirectURI,
-		Scopes: []string{
-			"https://www.googleapis.com/auth/userinfo.email",
-			"https://www.googleapis.com/auth/userinfo.profile",
-			"https://www.googleapis.com/auth/youtube.force-ssl",
-			"https://www.googleapis.com/auth/youtubepartner",
-		},
-		Endpoint: google.Endpoint,
+		Scopes:       append(append([]string{}, identityScopes...), ScopeReadOnly),
+		Endpoint:     google.Endpoint,
 	}
 
 	return &GoogleOAuthService{
 		tokenStore:  tokenStore,
 		oauthConfig: config,
+		revokeURL:   googleRevokeURL,
+		sources:     make(map[string]*userTokenSource),
+	}
+}
+
+// SetDeviceClient configures the Google OAuth client used for device
+// logins, which must be of the "TVs and Limited Input devices" type. Tokens
+// it issues are refreshed with it too.
+func (s *GoogleOAuthService) SetDeviceClient(clientID, clientSecret string) {
+	s.deviceConfig = &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       append(append([]string{}, identityScopes...), ScopeReadOnly),
+		Endpoint:     google.Endpoint,
+	}
+}
+
+// clientConfig returns the configuration of the Google OAuth client that
+// issued a token, which is the only one that can refresh it.
+func (s *GoogleOAuthService) clientConfig(client string) (*oauth2.Config, error) {
+	switch client {
+	case GoogleClientWeb:
+		return s.oauthConfig, nil
+	case GoogleClientDevice:
+		if s.deviceConfig == nil {
+			return nil, fmt.Errorf("the token was issued by a device login, but GOOGLE_DEVICE_CLIENT_ID is not set")
+		}
+		return s.deviceConfig, nil
 	}
+	return nil, fmt.Errorf("the token was issued by an unknown Google client %q", client)
 }
 
-// GetAuthURL returns the Google OAuth authorization URL.
-func (s *GoogleOAuthService) GetAuthURL(state string) string {
-	return s.oauthConfig.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce)
+// RedirectURL returns the URL Google sends the user back to after a
+// browser login.
+func (s *GoogleOAuthService) RedirectURL() string {
+	return s.oauthConfig.RedirectURL
 }
 
-// ExchangeCodeForToken exchanges an authorization code for a token and stores it.
-func (s *GoogleOAuthService) ExchangeCodeForToken(ctx context.Context, code string) error {
-	token, err := s.oauthConfig.Exchange(ctx, code)
+// GetAuthURL returns the Google OAuth authorization URL. The state is
+// verified on the callback, and the PKCE challenge is derived from verifier,
+// which must be passed to ExchangeCodeForToken. Besides the read-only scope,
+// extraScope is requested if set; scopes granted before are kept. The
+// loginHint preselects the Google account.
+func (s *GoogleOAuthService) GetAuthURL(state, verifier, extraScope, loginHint string) string {
+	config := *s.oauthConfig
+	if extraScope != "" {
+		config.Scopes = append(append([]string{}, config.Scopes...), extraScope)
+	}
+	opts := []oauth2.AuthCodeOption{
+		oauth2.AccessTypeOffline,
+		oauth2.ApprovalForce,
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("include_granted_scopes", "true"),
+	}
+	if loginHint != "" {
+		opts = append(opts, oauth2.SetAuthURLParam("login_hint", loginHint))
+	}
+	return config.AuthCodeURL(state, opts...)
+}
+
+// GoogleIdentity identifies the Google account a token belongs to.
+type GoogleIdentity struct {
+	// Subject is the stable Google account ID.
+	Subject string
+	Email   string
+}
+
+// ExchangeCodeForToken exchanges an authorization code and the PKCE verifier
+// of its request for a token, stores it, and returns the Google account it
+// belongs to.
+func (s *GoogleOAuthService) ExchangeCodeForToken(ctx context.Context, code, verifier string) (*GoogleIdentity, error) {
+	token, err := s.oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(verifier))
 	if err != nil {
-		return fmt.Errorf("failed to exchange code for token: %w", err)
+		return nil, fmt.Errorf("failed to exchange code for token: %w", err)
 	}
-	s.tokenStore.SetToken(token)
-	log.Println("✅ Successfully authenticated with Google and stored token in memory.")
-	return nil
+
+	return s.storeToken(ctx, token, GoogleClientWeb)
 }
 
-// GetYouTubeService returns an authenticated YouTube service client.
-// It uses the stored token and handles automatic refresh.
-func (s *GoogleOAuthService) GetYouTubeService(ctx context.Context) (*youtube.Service, error) {
-	token := s.tokenStore.GetToken()
-	if token == nil {
-		return nil, fmt.Errorf("not authenticated with Google; please visit /oauth/authorize")
+// StartDeviceLogin starts Google's device authorization grant (RFC 8628),
+// for machines without a browser. The user enters the returned user code
+// at the verification URL on any other device. Google only offers this
+// grant to OAuth clients of the "TVs and Limited Input devices" type, and
+// only for read-only YouTube access, so it uses the client configured with
+// SetDeviceClient.
+func (s *GoogleOAuthService) StartDeviceLogin(ctx context.Context) (*oauth2.DeviceAuthResponse, error) {
+	if s.deviceConfig == nil {
+		return nil, fmt.Errorf("device login needs an OAuth client of the \"TVs and Limited Input devices\" type; set GOOGLE_DEVICE_CLIENT_ID and GOOGLE_DEVICE_CLIENT_SECRET")
+	}
+	response, err := s.deviceConfig.DeviceAuth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start device login: %w", err)
+	}
+	return response, nil
+}
+
+// CompleteDeviceLogin polls Google until the user approves or denies the
+// device login, or its code expires, then stores the token and returns the
+// Google account it belongs to.
+func (s *GoogleOAuthService) CompleteDeviceLogin(ctx context.Context, device *oauth2.DeviceAuthResponse) (*GoogleIdentity, error) {
+	if s.deviceConfig == nil {
+		return nil, fmt.Errorf("device login is not configured")
+	}
+	token, err := s.deviceConfig.DeviceAccessToken(ctx, device)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete device login: %w", err)
 	}
+	return s.storeToken(ctx, token, GoogleClientDevice)
+}
 
-	tokenSource := s.oauthConfig.TokenSource(ctx, token)
-	
-	// The TokenSource will automatically refresh the token if it's expired.
-	// We can check if it was refreshed and update our store.
-	newToken, err := tokenSource.Token()
+// storeToken stores a newly issued token, recording the client that issued
+// it, for the Google account it belongs to and returns that account.
+func (s *GoogleOAuthService) storeToken(ctx context.Context, token *oauth2.Token, client string) (*GoogleIdentity, error) {
+	config, err := s.clientConfig(client)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := s.fetchIdentity(ctx, config, token)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve or refresh token: %w", err)
+		return nil, err
 	}
 
-	// If the access token is new, update the store.
-	if newToken.AccessToken != token.AccessToken {
-		s.tokenStore.SetToken(newToken)
-		log.Println("♻️ Google OAuth token was refreshed.")
+	// Google reports the granted scopes, including those granted before when
+	// include_granted_scopes is set; the user may have declined some of
+	// those requested.
+	scopes := config.Scopes
+	if granted, ok := token.Extra("scope").(string); ok && granted != "" {
+		scopes = strings.Fields(granted)
 	}
 
-	youtubeService, err := youtube.NewService(ctx, option.WithTokenSource(tokenSource))
+	if err := s.tokenStore.SetToken(identity.Subject, &StoredToken{Token: token, Email: identity.Email, Client: client, Scopes: scopes}); err != nil {
+		return nil, fmt.Errorf("failed to store the Google token: %w", err)
+	}
+	// The new access token may carry scopes the cached one lacks.
+	s.forgetTokenSource(identity.Subject)
+	log.Printf("✅ Successfully authenticated with Google as %s and stored token.", identity.Email)
+	return identity, nil
+}
+
+// fetchIdentity looks up the Google account of a token issued to the
+// client of config.
+func (s *GoogleOAuthService) fetchIdentity(ctx context.Context, config *oauth2.Config, token *oauth2.Token) (*GoogleIdentity, error) {
+	userinfoService, err := googleoauth.NewService(ctx, option.WithTokenSource(config.TokenSource(ctx, token)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create userinfo client: %w", err)
+	}
+	userinfo, err := userinfoService.Userinfo.Get().Context(ctx).Do()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create YouTube service: %w", err)
+		return nil, fmt.Errorf("failed to get Google account info: %w", err)
+	}
+	if userinfo.Id == "" {
+		return nil, fmt.Errorf("Google did not return an account ID")
 	}
+	return &GoogleIdentity{Subject: userinfo.Id, Email: userinfo.Email}, nil
+}
+
+// UserTokenSource returns the token source of the principal of the request.
+// Each user's calls only ever use their own token, which is refreshed
+// shortly before it expires. The source is shared by all calls of the user
+// and stays the same until they log in again, so clients built on it can be
+// reused. If the user has not granted scope, a *ScopeRequiredError names
+// the page to grant it.
+func (s *GoogleOAuthService) UserTokenSource(ctx context.Context, scope string) (oauth2.TokenSource, error) {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("this action needs a Google login; connect with an authenticated MCP client")
+	}
+	stored := s.tokenStore.GetToken(principal.Subject)
+	if stored == nil || stored.Token == nil {
+		s.forgetTokenSource(principal.Subject)
+		return nil, fmt.Errorf("not authenticated with Google; please visit /oauth/authorize")
+	}
+	if stored.RefreshError != "" {
+		return nil, fmt.Errorf("Google rejected the stored login (%s); please log in again at /oauth/authorize", stored.RefreshError)
+	}
+	if !hasScope(stored.GrantedScopes(), scope) {
+		return nil, &ScopeRequiredError{Scope: scope, AuthURL: consentURL(s.oauthConfig.RedirectURL, scope, stored.Email)}
+	}
+
+	return s.sharedTokenSource(principal.Subject, stored), nil
+}
 
-	return youtubeService, nil
+// HasToken reports whether a Google token is stored for the user.
+func (s *GoogleOAuthService) HasToken(userID string) bool {
+	return s.tokenStore.GetToken(userID) != nil
 }
 
-// IsAuthenticated checks if a valid token is currently stored.
-func (s *GoogleOAuthService) IsAuthenticated() bool {
-	return s.tokenStore.GetToken() != nil
+// StoredUsers returns the users with a stored Google token.
+func (s *GoogleOAuthService) StoredUsers() []GoogleIdentity {
+	var users []GoogleIdentity
+	for _, id := range s.tokenStore.UserIDs() {
+		if id == LegacyUserID {
+			continue
+		}
+		if stored := s.tokenStore.GetToken(id); stored != nil {
+			users = append(users, GoogleIdentity{Subject: id, Email: stored.Email})
+		}
+	}
+	return users
+}
+
+// MigrateLegacyToken moves a token saved before tokens were stored per user
+// to the Google account it belongs to.
+func (s *GoogleOAuthService) MigrateLegacyToken(ctx context.Context) error {
+	stored := s.tokenStore.GetToken(LegacyUserID)
+	if stored == nil {
+		return nil
+	}
+	if stored.Token == nil {
+		return s.tokenStore.SetToken(LegacyUserID, nil)
+	}
+
+	config, err := s.clientConfig(stored.Client)
+	if err != nil {
+		return err
+	}
+	identity, err := s.fetchIdentity(ctx, config, stored.Token)
+	if err != nil {
+		return fmt.Errorf("failed to identify the account of the stored token: %w", err)
+	}
+	if s.tokenStore.GetToken(identity.Subject) == nil {
+		if err := s.tokenStore.SetToken(identity.Subject, &StoredToken{Token: stored.Token, Email: identity.Email, Client: stored.Client, Scopes: stored.Scopes}); err != nil {
+			return fmt.Errorf("failed to store the token of %s: %w", identity.Email, err)
+		}
+	}
+	if err := s.tokenStore.SetToken(LegacyUserID, nil); err != nil {
+		return fmt.Errorf("failed to remove the legacy token: %w", err)
+	}
+	log.Printf("🔑 Moved the stored Google token to the account of %s.", identity.Email)
+	return nil
 }
 
-// TokenRefresher is a background goroutine that proactively refreshes the token.
+// TokenRefresher is a background goroutine that proactively refreshes the
+// tokens of all users before they expire. It refreshes through the same
+// token sources as the users' calls, so a token is never refreshed twice.
 func (s *GoogleOAuthService) TokenRefresher(ctx context.Context) {
 	log.Println("background token refresher started")
-	ticker := time.NewTicker(15 * time.Minute) // Check every 15 minutes
+	ticker := time.NewTicker(time.Minute)
 	defer ticker.Stop()
 
 	for {
@@ -102,25 +286,48 @@ func (s *GoogleOAuthService) TokenRefresher(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			token := s.tokenStore.GetToken()
-			if token == nil {
-				continue // No token to refresh
-			}
-
-			// Proactively refresh if the token will expire in the next 30 minutes.
-			if !token.Valid() || token.Expiry.Before(time.Now().Add(30*time.Minute)) {
-				log.Println("Proactively refreshing token...")
-				tokenSource := s.oauthConfig.TokenSource(ctx, token)
-				newToken, err := tokenSource.Token()
-				if err != nil {
-					log.Printf("Error refreshing token in background: %v", err)
-					// If refresh fails, the token might be invalid. Clear it.
-					s.tokenStore.SetToken(nil)
-					continue
+			for _, userID := range s.tokenStore.UserIDs() {
+				if userID == LegacyUserID {
+					continue // Not usable until migrated.
 				}
-				s.tokenStore.SetToken(newToken)
-				log.Println("✅ Token proactively refreshed in the background.")
+				s.refreshUserToken(userID)
 			}
 		}
 	}
 }
+
+// refreshUserToken refreshes a user's token if it expires within
+// refreshBeforeExpiry; its token source does nothing otherwise.
+func (s *GoogleOAuthService) refreshUserToken(userID string) {
+	stored := s.tokenStore.GetToken(userID)
+	if stored == nil || stored.Token == nil || stored.RefreshError != "" || stored.Token.RefreshToken == "" {
+		return
+	}
+	if _, err := s.sharedTokenSource(userID, stored).Token(); err != nil {
+		// Only a rejected refresh token is final, and the token source
+		// recorded it; anything else, such as a network error, is retried
+		// on the next tick.
+		if current := s.tokenStore.GetToken(userID); current != nil && current.RefreshError == "" {
+			log.Printf("Error refreshing token of %s in background, will retry: %v", stored.Email, err)
+		}
+	}
+}
+
+// checkRefreshRejected records on the stored token if err shows that Google
+// rejected its refresh token, which happens when the user revoked access or
+// the token expired. The token is kept, so the account still shows up with
+// the reason, but is not used until the user logs in again.
+func (s *GoogleOAuthService) checkRefreshRejected(userID string, stored *StoredToken, err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	if !errors.As(err, &retrieveErr) || retrieveErr.ErrorCode != "invalid_grant" {
+		return false
+	}
+	log.Printf("⚠️ Google rejected the refresh token of %s; they need to log in again: %v", stored.Email, err)
+	rejected := copyStoredToken(stored)
+	rejected.RefreshError = "the refresh token was revoked or has expired"
+	if err := s.tokenStore.SetToken(userID, rejected); err != nil {
+		log.Printf("Failed to record the rejected refresh token of %s: %v", stored.Email, err)
+	}
+	s.forgetTokenSource(userID)
+	return true
+}
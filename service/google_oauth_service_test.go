@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func TestClientConfig(t *testing.T) {
+	s := NewGoogleOAuthService(&InMemoryTokenStore{}, "web-id", "web-secret", "http://localhost:8080/oauth/callback")
+
+	if config, err := s.clientConfig(GoogleClientWeb); err != nil || config.ClientID != "web-id" {
+		t.Fatalf("web client config = %+v, %v", config, err)
+	}
+	if _, err := s.clientConfig(GoogleClientDevice); err == nil {
+		t.Error("device tokens were given a client although none is configured")
+	}
+
+	s.SetDeviceClient("device-id", "device-secret")
+	if config, err := s.clientConfig(GoogleClientDevice); err != nil || config.ClientID != "device-id" {
+		t.Fatalf("device client config = %+v, %v", config, err)
+	}
+	if config, _ := s.clientConfig(GoogleClientWeb); config.ClientID != "web-id" {
+		t.Errorf("configuring the device client changed the web client to %s", config.ClientID)
+	}
+	if _, err := s.clientConfig("other"); err == nil {
+		t.Error("unknown client accepted")
+	}
+}
+
+// userToken is a stored token of a user that needs no refresh.
+func userToken(user string, scopes ...string) *StoredToken {
+	return &StoredToken{
+		Token: &oauth2.Token{
+			AccessToken:  user + "-access",
+			RefreshToken: user + "-refresh",
+			Expiry:       time.Now().Add(time.Hour),
+		},
+		Email:  user + "@example.com",
+		Scopes: scopes,
+	}
+}
+
+func asUser(user string) context.Context {
+	return WithPrincipal(context.Background(), &Principal{Subject: user})
+}
+
+func TestUserTokenSourceIsolatesUsers(t *testing.T) {
+	store := &InMemoryTokenStore{}
+	store.SetToken("alice", userToken("alice", ScopeReadOnly))
+	store.SetToken("carol", userToken("carol", ScopeReadOnly))
+	s := NewGoogleOAuthService(store, "web-id", "web-secret", "http://localhost:8080/oauth/callback")
+
+	for _, user := range []string{"alice", "carol"} {
+		source, err := s.UserTokenSource(asUser(user), ScopeReadOnly)
+		if err != nil {
+			t.Fatalf("%s: %v", user, err)
+		}
+		token, err := source.Token()
+		if err != nil {
+			t.Fatalf("%s: %v", user, err)
+		}
+		if token.AccessToken != user+"-access" {
+			t.Errorf("%s was given the access token %q", user, token.AccessToken)
+		}
+	}
+
+	// bob has no token, and must not be given anyone else's.
+	if source, err := s.UserTokenSource(asUser("bob"), ScopeReadOnly); err == nil {
+		token, _ := source.Token()
+		t.Fatalf("bob without a stored token got a token source with %+v", token)
+	}
+	if _, err := s.UserTokenSource(context.Background(), ScopeReadOnly); err == nil {
+		t.Fatal("request without a principal got a token source")
+	}
+
+	alice, _ := s.UserTokenSource(asUser("alice"), ScopeReadOnly)
+	carol, _ := s.UserTokenSource(asUser("carol"), ScopeReadOnly)
+	if alice == carol {
+		t.Error("alice and carol share a token source")
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// Google scopes requested by the server. Users first grant only what
+// read-only tools need; the first call that needs more asks for it through
+// incremental consent.
+const (
+	// ScopeReadOnly allows searching and reading videos, comments, channels
+	// and playlists.
+	ScopeReadOnly = youtube.YoutubeReadonlyScope
+	// ScopeManage additionally allows changing playlists and posting
+	// comment replies.
+	ScopeManage = youtube.YoutubeForceSslScope
+)
+
+// identityScopes identify the Google account a token belongs to.
+var identityScopes = []string{
+	"https://www.googleapis.com/auth/userinfo.email",
+	"https://www.googleapis.com/auth/userinfo.profile",
+}
+
+// legacyScopes were requested for every token stored before granted scopes
+// were tracked.
+var legacyScopes = []string{ScopeManage, "https://www.googleapis.com/auth/youtubepartner"}
+
+// scopeNames are the short names of the scopes that can be requested with
+// the google_scope parameter of /oauth/authorize.
+var scopeNames = map[string]string{
+	"youtube.readonly":  ScopeReadOnly,
+	"youtube.force-ssl": ScopeManage,
+}
+
+// scopeDescriptions explain the scopes on the authorization page.
+var scopeDescriptions = map[string]string{
+	ScopeReadOnly: "view your YouTube account",
+	ScopeManage:   "manage your YouTube playlists and reply to comments",
+}
+
+// ScopeRequiredError reports that a call needs a Google scope the user has
+// not granted yet. AuthURL is the page where the user can grant it.
+type ScopeRequiredError struct {
+	Scope   string
+	AuthURL string
+}
+
+func (e *ScopeRequiredError) Error() string {
+	return fmt.Sprintf("This action needs permission to %s, which has not been granted yet. Ask the user to open %s to grant it, then retry.",
+		scopeDescriptions[e.Scope], e.AuthURL)
+}
+
+// ParseGoogleScope resolves the short name of a scope that can be requested
+// incrementally, such as "youtube.force-ssl".
+func ParseGoogleScope(name string) (string, error) {
+	scope, ok := scopeNames[name]
+	if !ok {
+		return "", fmt.Errorf("unknown Google scope %q", name)
+	}
+	return scope, nil
+}
+
+// DescribeGoogleScope returns a human-readable description of a scope.
+func DescribeGoogleScope(scope string) string {
+	return scopeDescriptions[scope]
+}
+
+// hasScope reports whether the granted scopes include required. Managing
+// the account includes reading it.
+func hasScope(granted []string, required string) bool {
+	for _, scope := range granted {
+		if scope == required || required == ScopeReadOnly && (scope == ScopeManage || scope == youtube.YoutubeScope) {
+			return true
+		}
+	}
+	return false
+}
+
+// consentURL returns the page of this server where the user grants scope,
+// next to the Google callback of redirectURI.
+func consentURL(redirectURI, scope, email string) string {
+	params := url.Values{}
+	for name, value := range scopeNames {
+		if value == scope {
+			params.Set("google_scope", name)
+		}
+	}
+	if email != "" {
+		params.Set("login_hint", email)
+	}
+
+	authorize := &url.URL{Path: "authorize", RawQuery: params.Encode()}
+	base, err := url.Parse(redirectURI)
+	if err != nil {
+		return "/oauth/authorize?" + params.Encode()
+	}
+	return base.ResolveReference(authorize).String()
+}
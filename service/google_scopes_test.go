@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		granted  []string
+		required string
+		want     bool
+	}{
+		{"same scope", []string{ScopeReadOnly}, ScopeReadOnly, true},
+		{"manage covers read", []string{ScopeManage}, ScopeReadOnly, true},
+		{"full access covers read", []string{youtube.YoutubeScope}, ScopeReadOnly, true},
+		{"read does not cover manage", []string{ScopeReadOnly}, ScopeManage, false},
+		{"among other scopes", append([]string{ScopeReadOnly}, identityScopes...), ScopeReadOnly, true},
+		{"identity only", identityScopes, ScopeReadOnly, false},
+		{"nothing granted", nil, ScopeReadOnly, false},
+	}
+	for _, tt := range tests {
+		if got := hasScope(tt.granted, tt.required); got != tt.want {
+			t.Errorf("%s: hasScope = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConsentURLRequestsScope(t *testing.T) {
+	store := &InMemoryTokenStore{}
+	store.SetToken("alice", userToken("alice", ScopeReadOnly))
+	s := NewGoogleOAuthService(store, "web-id", "web-secret", "http://localhost:8080/oauth/callback")
+
+	_, err := s.UserTokenSource(asUser("alice"), ScopeManage)
+	var scopeErr *ScopeRequiredError
+	if !errors.As(err, &scopeErr) {
+		t.Fatalf("err = %v, want *ScopeRequiredError", err)
+	}
+	if scopeErr.Scope != ScopeManage || !strings.Contains(scopeErr.Error(), scopeErr.AuthURL) {
+		t.Errorf("error = %+v, want the manage scope and its consent URL in the message", scopeErr)
+	}
+
+	// The consent page of this server names the scope and the account...
+	consent, err := url.Parse(scopeErr.AuthURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if consent.Host != "localhost:8080" || consent.Path != "/oauth/authorize" {
+		t.Errorf("consent URL %s is not this server's authorize page", consent)
+	}
+	query := consent.Query()
+	if query.Get("login_hint") != "alice@example.com" {
+		t.Errorf("login_hint = %q, want alice's email", query.Get("login_hint"))
+	}
+	scope, err := ParseGoogleScope(query.Get("google_scope"))
+	if err != nil || scope != ScopeManage {
+		t.Fatalf("google_scope %q resolves to %q, %v", query.Get("google_scope"), scope, err)
+	}
+
+	// ...and sends the user to Google to add it to those granted before.
+	google, err := url.Parse(s.GetAuthURL("state", "verifier", scope, query.Get("login_hint")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := google.Query()
+	if params.Get("include_granted_scopes") != "true" {
+		t.Errorf("include_granted_scopes = %q, want true", params.Get("include_granted_scopes"))
+	}
+	requested := strings.Fields(params.Get("scope"))
+	if !slices.Contains(requested, ScopeManage) || !slices.Contains(requested, ScopeReadOnly) {
+		t.Errorf("requested scopes %v, want the new scope and read access", requested)
+	}
+	if params.Get("login_hint") != "alice@example.com" {
+		t.Errorf("Google login_hint = %q, want alice's email", params.Get("login_hint"))
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// refreshBeforeExpiry is how long before an access token expires it is
+// refreshed, both on use and by the TokenRefresher.
+const refreshBeforeExpiry = 10 * time.Minute
+
+// userTokenSource is the one token source of a user, shared by every call
+// made as that user and by the TokenRefresher. The access token is reused
+// until it nears expiry; oauth2's ReuseTokenSource makes sure only one
+// caller refreshes it at a time.
+type userTokenSource struct {
+	oauth2.TokenSource
+	// refreshToken identifies the login the source was created for. A new
+	// login, or one made by another process sharing the token file, brings
+	// a new refresh token and so a new source.
+	refreshToken string
+}
+
+// newUserTokenSource creates the shared token source of a user's stored token.
+func (s *GoogleOAuthService) newUserTokenSource(userID string, stored *StoredToken) *userTokenSource {
+	refresher := &persistingTokenSource{service: s, userID: userID, client: stored.Client, refreshToken: stored.Token.RefreshToken}
+	return &userTokenSource{
+		TokenSource:  oauth2.ReuseTokenSourceWithExpiry(stored.Token, refresher, refreshBeforeExpiry),
+		refreshToken: stored.Token.RefreshToken,
+	}
+}
+
+// sharedTokenSource returns the shared token source of a user's stored
+// token, creating it when the user logged in anew.
+func (s *GoogleOAuthService) sharedTokenSource(userID string, stored *StoredToken) *userTokenSource {
+	s.sourcesMu.Lock()
+	defer s.sourcesMu.Unlock()
+	source, ok := s.sources[userID]
+	if !ok || source.refreshToken != stored.Token.RefreshToken {
+		source = s.newUserTokenSource(userID, stored)
+		s.sources[userID] = source
+	}
+	return source
+}
+
+// forgetTokenSource drops the shared token source of a user whose token was
+// removed or can no longer be used.
+func (s *GoogleOAuthService) forgetTokenSource(userID string) {
+	s.sourcesMu.Lock()
+	defer s.sourcesMu.Unlock()
+	delete(s.sources, userID)
+}
+
+// persistingTokenSource refreshes a user's access token and stores every
+// new token, so it survives restarts and other processes sharing the token
+// file see it. A refresh token rejected by Google is recorded on the stored
+// token. Tokens are refreshed with the Google client that issued them.
+type persistingTokenSource struct {
+	service      *GoogleOAuthService
+	userID       string
+	client       string
+	refreshToken string
+}
+
+func (p *persistingTokenSource) Token() (*oauth2.Token, error) {
+	config, err := p.service.clientConfig(p.client)
+	if err != nil {
+		return nil, err
+	}
+	// A token without an access token is always refreshed.
+	token, err := config.TokenSource(context.Background(), &oauth2.Token{RefreshToken: p.refreshToken}).Token()
+	stored := p.service.tokenStore.GetToken(p.userID)
+	if stored == nil || stored.Token == nil || stored.Token.RefreshToken != p.refreshToken {
+		// The user logged out or in again meanwhile; leave their token alone.
+		return token, err
+	}
+	if err != nil {
+		p.service.checkRefreshRejected(p.userID, stored, err)
+		return nil, err
+	}
+
+	refreshed := copyStoredToken(stored)
+	refreshed.Token = token
+	if err := p.service.tokenStore.SetToken(p.userID, refreshed); err != nil {
+		// The new access token is still good for this process.
+		log.Printf("Google OAuth token of %s was refreshed but could not be stored: %v", stored.Email, err)
+		return token, nil
+	}
+	log.Printf("♻️ Google OAuth token of %s was refreshed.", stored.Email)
+	return token, nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingTokenStore counts the tokens stored in it.
+type countingTokenStore struct {
+	InMemoryTokenStore
+	sets atomic.Int64
+}
+
+func (c *countingTokenStore) SetToken(userID string, token *StoredToken) error {
+	c.sets.Add(1)
+	return c.InMemoryTokenStore.SetToken(userID, token)
+}
+
+// newTokenEndpoint serves Google's token endpoint, handing out a new access
+// token on every refresh, and counts the refreshes.
+func newTokenEndpoint(t *testing.T, refreshes *atomic.Int64) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		refreshes.Add(1)
+		time.Sleep(10 * time.Millisecond) // Let concurrent callers pile up.
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"refreshed-access","token_type":"Bearer","expires_in":3600}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSharedTokenSourceRefreshesOnce(t *testing.T) {
+	var refreshes atomic.Int64
+	endpoint := newTokenEndpoint(t, &refreshes)
+
+	store := &countingTokenStore{}
+	expiring := userToken("alice", ScopeReadOnly)
+	expiring.Token.Expiry = time.Now().Add(time.Minute) // Within refreshBeforeExpiry.
+	store.InMemoryTokenStore.SetToken("alice", expiring)
+	s := NewGoogleOAuthService(store, "web-id", "web-secret", "http://localhost:8080/oauth/callback")
+	s.oauthConfig.Endpoint.TokenURL = endpoint.URL
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			source, err := s.UserTokenSource(asUser("alice"), ScopeReadOnly)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			token, err := source.Token()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if token.AccessToken != "refreshed-access" {
+				t.Errorf("got access token %q, want the refreshed one", token.AccessToken)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := refreshes.Load(); n != 1 {
+		t.Errorf("token was refreshed %d times, want once", n)
+	}
+	if n := store.sets.Load(); n != 1 {
+		t.Errorf("refreshed token was stored %d times, want once", n)
+	}
+	stored := store.GetToken("alice")
+	if stored.Token.AccessToken != "refreshed-access" || stored.Token.RefreshToken != "alice-refresh" || stored.Email != "alice@example.com" {
+		t.Errorf("stored token = %+v, want the refreshed access token on alice's login", stored)
+	}
+}
+
+func TestSharedTokenSourceFollowsLogins(t *testing.T) {
+	store := &InMemoryTokenStore{}
+	store.SetToken("alice", userToken("alice", ScopeReadOnly))
+	s := NewGoogleOAuthService(store, "web-id", "web-secret", "http://localhost:8080/oauth/callback")
+
+	first, _ := s.UserTokenSource(asUser("alice"), ScopeReadOnly)
+	again, _ := s.UserTokenSource(asUser("alice"), ScopeReadOnly)
+	if first != again {
+		t.Error("repeated calls got different token sources")
+	}
+
+	relogin := userToken("alice", ScopeReadOnly)
+	relogin.Token.RefreshToken = "alice-refresh-2"
+	store.SetToken("alice", relogin)
+	after, _ := s.UserTokenSource(asUser("alice"), ScopeReadOnly)
+	if after == first {
+		t.Error("a new login kept the old token source")
+	}
+}
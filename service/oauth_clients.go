@@ -0,0 +1,192 @@
+package service
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxClients bounds the number of registered clients, since registration
+// is open to anyone who can reach the server.
+const maxClients = 1000
+
+// ClientRegistration is a dynamic client registration request (RFC 7591).
+type ClientRegistration struct {
+	RedirectURIs            []string `json:"redirect_uris"`
+	ClientName              string   `json:"client_name,omitempty"`
+	ClientURI               string   `json:"client_uri,omitempty"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method,omitempty"`
+	GrantTypes              []string `json:"grant_types,omitempty"`
+	ResponseTypes           []string `json:"response_types,omitempty"`
+	Scope                   string   `json:"scope,omitempty"`
+}
+
+// OAuthClient is a registered client, as returned from registration.
+type OAuthClient struct {
+	ClientID              string `json:"client_id"`
+	ClientSecret          string `json:"client_secret,omitempty"`
+	ClientIDIssuedAt      int64  `json:"client_id_issued_at"`
+	ClientSecretExpiresAt *int64 `json:"client_secret_expires_at,omitempty"`
+	ClientRegistration
+
+	secretHash string
+}
+
+// RegisterClient registers a new client. Public clients use the "none"
+// authentication method and PKCE; others receive a client secret.
+func (s *OAuthService) RegisterClient(req ClientRegistration) (*OAuthClient, error) {
+	if len(req.RedirectURIs) == 0 {
+		return nil, oauthError("invalid_redirect_uri", "at least one redirect_uri is required")
+	}
+	for _, uri := range req.RedirectURIs {
+		if err := validateRedirectURI(uri); err != nil {
+			return nil, err
+		}
+	}
+
+	switch req.TokenEndpointAuthMethod {
+	case "":
+		req.TokenEndpointAuthMethod = "client_secret_basic"
+	case "none", "client_secret_post", "client_secret_basic":
+	default:
+		return nil, oauthError("invalid_client_metadata", "unsupported token_endpoint_auth_method %q", req.TokenEndpointAuthMethod)
+	}
+	if len(req.GrantTypes) == 0 {
+		req.GrantTypes = []string{"authorization_code", "refresh_token"}
+	}
+	for _, grant := range req.GrantTypes {
+		if grant != "authorization_code" && grant != "refresh_token" {
+			return nil, oauthError("invalid_client_metadata", "unsupported grant type %q", grant)
+		}
+	}
+	if len(req.ResponseTypes) == 0 {
+		req.ResponseTypes = []string{"code"}
+	}
+	for _, responseType := range req.ResponseTypes {
+		if responseType != "code" {
+			return nil, oauthError("invalid_client_metadata", "unsupported response type %q", responseType)
+		}
+	}
+	if req.Scope != "" {
+		if _, err := parseScopes(req.Scope); err != nil {
+			return nil, oauthError("invalid_client_metadata", "unsupported scope %q", req.Scope)
+		}
+	}
+
+	client := &OAuthClient{
+		ClientID:           randomToken(),
+		ClientIDIssuedAt:   time.Now().Unix(),
+		ClientRegistration: req,
+	}
+	if req.TokenEndpointAuthMethod != "none" {
+		client.ClientSecret = randomToken()
+		client.secretHash = hashToken(client.ClientSecret)
+		never := int64(0)
+		client.ClientSecretExpiresAt = &never
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.clients) >= maxClients {
+		return nil, &OAuthError{Code: "temporarily_unavailable", Description: "too many registered clients", Status: http.StatusServiceUnavailable}
+	}
+	stored := *client
+	stored.ClientSecret = ""
+	s.clients[client.ClientID] = &stored
+	return client, nil
+}
+
+// ClientForRedirect looks up a client and checks the redirect URI of an
+// authorization request against its registration, returning the redirect
+// URI to use. Errors from this check must be shown to the user rather than
+// sent to the redirect URI.
+func (s *OAuthService) ClientForRedirect(clientID, redirectURI string) (*OAuthClient, string, error) {
+	s.mu.Lock()
+	client, ok := s.clients[clientID]
+	s.mu.Unlock()
+	if !ok {
+		return nil, "", oauthError("invalid_client", "unknown client_id")
+	}
+
+	if redirectURI == "" {
+		if len(client.RedirectURIs) != 1 {
+			return nil, "", oauthError("invalid_request", "redirect_uri is required")
+		}
+		return client, client.RedirectURIs[0], nil
+	}
+	for _, registered := range client.RedirectURIs {
+		if redirectURIMatches(registered, redirectURI) {
+			return client, redirectURI, nil
+		}
+	}
+	return nil, "", oauthError("invalid_request", "redirect_uri is not registered for this client")
+}
+
+// AuthenticateClient authenticates a client at the token endpoint. Public
+// clients only send their client_id.
+func (s *OAuthService) AuthenticateClient(clientID, clientSecret string) (*OAuthClient, error) {
+	s.mu.Lock()
+	client, ok := s.clients[clientID]
+	s.mu.Unlock()
+	if !ok {
+		return nil, oauthError("invalid_client", "unknown client_id")
+	}
+
+	if client.TokenEndpointAuthMethod == "none" {
+		return client, nil
+	}
+	if clientSecret == "" || subtle.ConstantTimeCompare([]byte(hashToken(clientSecret)), []byte(client.secretHash)) != 1 {
+		return nil, oauthError("invalid_client", "client authentication failed")
+	}
+	return client, nil
+}
+
+// validateRedirectURI accepts HTTPS URIs, HTTP URIs on a loopback address,
+// and private-use schemes of native apps. Fragments are not allowed.
+func validateRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme == "" || u.Fragment != "" {
+		return oauthError("invalid_redirect_uri", "invalid redirect_uri %q", uri)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "https":
+		if u.Host == "" {
+			return oauthError("invalid_redirect_uri", "invalid redirect_uri %q", uri)
+		}
+	case "http":
+		if !isLoopbackHost(u.Hostname()) {
+			return oauthError("invalid_redirect_uri", "http redirect_uri must use a loopback address: %q", uri)
+		}
+	case "javascript", "data", "file":
+		return oauthError("invalid_redirect_uri", "invalid redirect_uri scheme in %q", uri)
+	}
+	return nil
+}
+
+// redirectURIMatches compares redirect URIs exactly, except that the port
+// of a loopback URI may differ, as native apps pick a free port at runtime
+// (RFC 8252 section 7.3).
+func redirectURIMatches(registered, requested string) bool {
+	if registered == requested {
+		return true
+	}
+	r, err1 := url.Parse(registered)
+	q, err2 := url.Parse(requested)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return r.Scheme == "http" && q.Scheme == "http" &&
+		isLoopbackHost(r.Hostname()) && r.Hostname() == q.Hostname() &&
+		r.Path == q.Path && r.RawQuery == q.RawQuery
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
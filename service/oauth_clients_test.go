@@ -0,0 +1,92 @@
+package service
+
+import "testing"
+
+func TestRedirectURIMatches(t *testing.T) {
+	tests := []struct {
+		registered, requested string
+		want                  bool
+	}{
+		{"https://client.example/cb", "https://client.example/cb", true},
+		{"https://client.example/cb", "https://client.example/cb/", false},
+		{"https://client.example/cb", "https://client.example:8443/cb", false},
+		{"https://client.example/cb", "https://evil.example/cb", false},
+		{"http://127.0.0.1/cb", "http://127.0.0.1:53682/cb", true},
+		{"http://localhost:3000/cb", "http://localhost:49152/cb", true},
+		{"http://[::1]/cb", "http://[::1]:8080/cb", true},
+		{"http://127.0.0.1/cb", "http://127.0.0.1:53682/other", false},
+		{"http://127.0.0.1/cb?x=1", "http://127.0.0.1:1/cb?x=2", false},
+		{"http://127.0.0.1/cb", "http://localhost:53682/cb", false},
+		{"http://127.0.0.1/cb", "https://127.0.0.1:53682/cb", false},
+		{"https://client.example/cb", "https://client.example:1/cb", false},
+		{"com.example.app:/cb", "com.example.app:/cb", true},
+	}
+	for _, tt := range tests {
+		if got := redirectURIMatches(tt.registered, tt.requested); got != tt.want {
+			t.Errorf("redirectURIMatches(%q, %q) = %v, want %v", tt.registered, tt.requested, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterClientValidatesRedirectURIs(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want bool
+	}{
+		{"https://client.example/cb", true},
+		{"http://127.0.0.1:8080/cb", true},
+		{"http://localhost/cb", true},
+		{"com.example.app:/oauth", true},
+		{"http://client.example/cb", false},
+		{"https://client.example/cb#frag", false},
+		{"javascript:alert(1)", false},
+		{"/relative", false},
+	}
+	s := NewOAuthService("https://mcp.example")
+	for _, tt := range tests {
+		_, err := s.RegisterClient(ClientRegistration{RedirectURIs: []string{tt.uri}})
+		if (err == nil) != tt.want {
+			t.Errorf("RegisterClient(%q) error = %v, want accepted %v", tt.uri, err, tt.want)
+		}
+	}
+}
+
+func TestClientForRedirect(t *testing.T) {
+	s := NewOAuthService("https://mcp.example")
+	client, err := s.RegisterClient(ClientRegistration{RedirectURIs: []string{"http://127.0.0.1/cb"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, uri, err := s.ClientForRedirect(client.ClientID, ""); err != nil || uri != "http://127.0.0.1/cb" {
+		t.Errorf("omitted redirect_uri = %q, %v; want the registered one", uri, err)
+	}
+	if _, uri, err := s.ClientForRedirect(client.ClientID, "http://127.0.0.1:5000/cb"); err != nil || uri != "http://127.0.0.1:5000/cb" {
+		t.Errorf("loopback port = %q, %v; want the requested URI", uri, err)
+	}
+	if _, _, err := s.ClientForRedirect(client.ClientID, "https://evil.example/cb"); err == nil {
+		t.Error("unregistered redirect_uri accepted")
+	}
+	if _, _, err := s.ClientForRedirect("unknown", ""); err == nil {
+		t.Error("unknown client accepted")
+	}
+}
+
+func TestAuthenticateClient(t *testing.T) {
+	s := NewOAuthService("https://mcp.example")
+	confidential, err := s.RegisterClient(ClientRegistration{RedirectURIs: []string{"https://client.example/cb"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if confidential.ClientSecret == "" {
+		t.Fatal("confidential client got no secret")
+	}
+
+	if _, err := s.AuthenticateClient(confidential.ClientID, confidential.ClientSecret); err != nil {
+		t.Errorf("correct secret rejected: %v", err)
+	}
+	_, err = s.AuthenticateClient(confidential.ClientID, "wrong")
+	wantOAuthError(t, err, "invalid_client")
+	_, err = s.AuthenticateClient(confidential.ClientID, "")
+	wantOAuthError(t, err, "invalid_client")
+}
@@ -1,38 +1,499 @@
 package service
 
-// OAuthService is a simplified service for a single-user setup.
-// It primarily provides the necessary OAuth discovery metadata.
-// In this simplified model, we don't need dynamic client registration
-// or complex token management for the MCP client itself.
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Lifetimes of the artifacts issued by the authorization server.
+const (
+	pendingAuthorizationTTL = 10 * time.Minute
+	authorizationCodeTTL    = 5 * time.Minute
+	accessTokenTTL          = time.Hour
+	refreshTokenTTL         = 30 * 24 * time.Hour
+)
+
+// mcpScope is the only scope the server issues; it grants use of the MCP tools.
+const mcpScope = "youtube"
+
+var codeVerifierPattern = regexp.MustCompile(`^[A-Za-z0-9._~-]{43,128}$`)
+
+// OAuthError is an OAuth 2.0 error response (RFC 6749 section 5.2).
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	// Status is the HTTP status to answer with.
+	Status int `json:"-"`
+}
+
+func (e *OAuthError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+func oauthError(code string, format string, args ...interface{}) *OAuthError {
+	status := http.StatusBadRequest
+	if code == "invalid_client" {
+		status = http.StatusUnauthorized
+	}
+	return &OAuthError{Code: code, Description: fmt.Sprintf(format, args...), Status: status}
+}
+
+// AuthorizationRequest holds the parameters of an /oauth/authorize request.
+type AuthorizationRequest struct {
+	ClientID            string
+	RedirectURI         string
+	ResponseType        string
+	State               string
+	Scope               string
+	Resource            string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// PendingAuthorization is an authorization request waiting for the user to
+// log in with Google. A pending authorization without a client is a plain
+// browser login that only stores the Google token.
+type PendingAuthorization struct {
+	ID            string
+	ClientID      string
+	ClientName    string
+	RedirectURI   string
+	State         string
+	Scopes        []string
+	CodeChallenge string
+	// GoogleVerifier is the PKCE code verifier of the Google login.
+	GoogleVerifier string
+	ExpiresAt      time.Time
+}
+
+// TokenResponse is a successful token endpoint response (RFC 6749 section 5.1).
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+type authorizationCode struct {
+	clientID      string
+	redirectURI   string
+	codeChallenge string
+	scopes        []string
+	identity      GoogleIdentity
+	expiresAt     time.Time
+}
+
+type issuedToken struct {
+	clientID  string
+	scopes    []string
+	identity  GoogleIdentity
+	expiresAt time.Time
+}
+
+// OAuthService is the MCP authorization server (OAuth 2.1). MCP clients
+// register dynamically, send the user through the authorization code flow
+// with PKCE, and receive access and refresh tokens bound to the Google
+// account the user logged in with. Google login itself is delegated to
+// GoogleOAuthService.
+//
+// Clients and tokens are kept in memory, so clients re-register and users
+// log in again after a restart. Tokens are stored by hash only.
 type OAuthService struct {
 	serverURL string
+
+	mu            sync.Mutex
+	clients       map[string]*OAuthClient
+	pending       map[string]*PendingAuthorization
+	codes         map[string]*authorizationCode
+	accessTokens  map[string]*issuedToken
+	refreshTokens map[string]*issuedToken
 }
 
-// NewOAuthService creates a new simplified OAuthService.
+// NewOAuthService creates a new OAuthService for the server at serverURL.
 func NewOAuthService(serverURL string) *OAuthService {
 	return &OAuthService{
-		serverURL: serverURL,
+		serverURL:     strings.TrimRight(serverURL, "/"),
+		clients:       make(map[string]*OAuthClient),
+		pending:       make(map[string]*PendingAuthorization),
+		codes:         make(map[string]*authorizationCode),
+		accessTokens:  make(map[string]*issuedToken),
+		refreshTokens: make(map[string]*issuedToken),
 	}
 }
 
 // GetAuthServerMetadata returns OAuth authorization server metadata (RFC 8414).
-// For our simplified case, many of these endpoints won't be fully implemented
-// as we bypass the MCP-level OAuth flow in favor of a direct Google login check.
 func (s *OAuthService) GetAuthServerMetadata() map[string]interface{} {
 	return map[string]interface{}{
-		"issuer":                   s.serverURL,
-		"authorization_endpoint":   s.serverURL + "/oauth/authorize",
-		"token_endpoint":           s.serverURL + "/oauth/token", // This will not be used
-		"scopes_supported":         []string{"youtube:transcripts"},
-		"response_types_supported": []string{"code"},
-		"grant_types_supported":    []string{"authorization_code"},
+		"issuer":                                     s.serverURL,
+		"authorization_endpoint":                     s.serverURL + "/oauth/authorize",
+		"token_endpoint":                             s.serverURL + "/oauth/token",
+		"registration_endpoint":                      s.serverURL + "/oauth/register",
+		"revocation_endpoint":                        s.serverURL + "/oauth/revoke",
+		"scopes_supported":                           []string{mcpScope},
+		"response_types_supported":                   []string{"code"},
+		"grant_types_supported":                      []string{"authorization_code", "refresh_token"},
+		"code_challenge_methods_supported":           []string{"S256"},
+		"token_endpoint_auth_methods_supported":      []string{"none", "client_secret_post", "client_secret_basic"},
+		"revocation_endpoint_auth_methods_supported": []string{"none", "client_secret_post", "client_secret_basic"},
 	}
 }
 
 // GetProtectedResourceMetadata returns resource server metadata (RFC 9728).
 func (s *OAuthService) GetProtectedResourceMetadata() map[string]interface{} {
 	return map[string]interface{}{
-		"resource":              s.serverURL,
-		"authorization_servers": []string{s.serverURL},
+		"resource":                 s.serverURL,
+		"authorization_servers":    []string{s.serverURL},
+		"scopes_supported":         []string{mcpScope},
+		"bearer_methods_supported": []string{"header"},
+	}
+}
+
+// ProtectedResourceMetadataURL is the URL of the resource metadata document,
+// advertised in WWW-Authenticate challenges.
+func (s *OAuthService) ProtectedResourceMetadataURL() string {
+	return s.serverURL + "/.well-known/oauth-protected-resource"
+}
+
+// StartAuthorization validates the parameters of an authorization request
+// from a registered client and records it until the user has logged in.
+// The client and redirect URI must already have been checked with
+// ClientForRedirect; errors returned here may be sent to the redirect URI.
+func (s *OAuthService) StartAuthorization(client *OAuthClient, req AuthorizationRequest) (*PendingAuthorization, error) {
+	if req.ResponseType != "code" {
+		return nil, oauthError("unsupported_response_type", "only the code response type is supported")
+	}
+	if req.CodeChallenge == "" {
+		return nil, oauthError("invalid_request", "code_challenge is required (PKCE)")
+	}
+	if req.CodeChallengeMethod != "S256" {
+		return nil, oauthError("invalid_request", "code_challenge_method must be S256")
+	}
+	scopes, err := parseScopes(req.Scope)
+	if err != nil {
+		return nil, err
+	}
+	if req.Resource != "" && !s.isResource(req.Resource) {
+		return nil, oauthError("invalid_target", "unknown resource %q", req.Resource)
+	}
+
+	return s.addPending(&PendingAuthorization{
+		ClientID:      client.ClientID,
+		ClientName:    client.ClientName,
+		RedirectURI:   req.RedirectURI,
+		State:         req.State,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+	}), nil
+}
+
+// StartLogin records a plain browser login that only stores the Google
+// token, for use by the stdio transport.
+func (s *OAuthService) StartLogin() *PendingAuthorization {
+	return s.addPending(&PendingAuthorization{})
+}
+
+func (s *OAuthService) addPending(pending *PendingAuthorization) *PendingAuthorization {
+	pending.ID = randomToken()
+	pending.GoogleVerifier = oauth2.GenerateVerifier()
+	pending.ExpiresAt = time.Now().Add(pendingAuthorizationTTL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[pending.ID] = pending
+	return pending
+}
+
+// TakePendingAuthorization removes and returns the pending authorization
+// with the given ID. It fails if there is none or it has expired, so each
+// one can be completed only once.
+func (s *OAuthService) TakePendingAuthorization(id string) (*PendingAuthorization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending, ok := s.pending[id]
+	if !ok {
+		return nil, fmt.Errorf("this login request is unknown or was already completed")
+	}
+	delete(s.pending, id)
+	if time.Now().After(pending.ExpiresAt) {
+		return nil, fmt.Errorf("this login request expired after %v", pendingAuthorizationTTL)
+	}
+	return pending, nil
+}
+
+// PendingAuthorizationTTL is how long the user has to complete a login.
+func (s *OAuthService) PendingAuthorizationTTL() time.Duration {
+	return pendingAuthorizationTTL
+}
+
+// SecureCookies reports whether cookies must be marked Secure, which is the
+// case when the server is reached over HTTPS.
+func (s *OAuthService) SecureCookies() bool {
+	return strings.HasPrefix(s.serverURL, "https://")
+}
+
+// IssueAuthorizationCode completes a pending authorization for the Google
+// account the user logged in with, returning the code for the client.
+func (s *OAuthService) IssueAuthorizationCode(pending *PendingAuthorization, identity GoogleIdentity) string {
+	code := randomToken()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codes[hashToken(code)] = &authorizationCode{
+		clientID:      pending.ClientID,
+		redirectURI:   pending.RedirectURI,
+		codeChallenge: pending.CodeChallenge,
+		scopes:        pending.Scopes,
+		identity:      identity,
+		expiresAt:     time.Now().Add(authorizationCodeTTL),
+	}
+	return code
+}
+
+// ExchangeAuthorizationCode redeems an authorization code for tokens,
+// verifying the PKCE code verifier. Codes can be used once. The redirect
+// URI may be omitted, but must match the authorization request if sent.
+func (s *OAuthService) ExchangeAuthorizationCode(client *OAuthClient, code, redirectURI, codeVerifier string) (*TokenResponse, error) {
+	if code == "" {
+		return nil, oauthError("invalid_request", "code is required")
+	}
+	if !codeVerifierPattern.MatchString(codeVerifier) {
+		return nil, oauthError("invalid_request", "code_verifier is missing or malformed")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := hashToken(code)
+	issued, ok := s.codes[key]
+	if !ok || time.Now().After(issued.expiresAt) {
+		return nil, oauthError("invalid_grant", "authorization code is invalid or expired")
+	}
+	delete(s.codes, key)
+
+	if issued.clientID != client.ClientID {
+		return nil, oauthError("invalid_grant", "authorization code was issued to another client")
+	}
+	if redirectURI != "" && issued.redirectURI != redirectURI {
+		return nil, oauthError("invalid_grant", "redirect_uri does not match the authorization request")
+	}
+	sum := sha256.Sum256([]byte(codeVerifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(issued.codeChallenge)) != 1 {
+		return nil, oauthError("invalid_grant", "code_verifier does not match the code challenge")
+	}
+
+	return s.issueTokensLocked(client.ClientID, issued.scopes, issued.identity), nil
+}
+
+// RefreshAccessToken issues new tokens for a refresh token. Refresh tokens
+// are rotated: the one presented stops working.
+func (s *OAuthService) RefreshAccessToken(client *OAuthClient, refreshToken, scope string) (*TokenResponse, error) {
+	if refreshToken == "" {
+		return nil, oauthError("invalid_request", "refresh_token is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := hashToken(refreshToken)
+	issued, ok := s.refreshTokens[key]
+	if !ok || time.Now().After(issued.expiresAt) {
+		return nil, oauthError("invalid_grant", "refresh token is invalid or expired")
+	}
+	if issued.clientID != client.ClientID {
+		return nil, oauthError("invalid_grant", "refresh token was issued to another client")
+	}
+
+	scopes := issued.scopes
+	if scope != "" {
+		requested, err := parseScopes(scope)
+		if err != nil {
+			return nil, err
+		}
+		for _, sc := range requested {
+			if !containsString(issued.scopes, sc) {
+				return nil, oauthError("invalid_scope", "scope %q was not granted", sc)
+			}
+		}
+		scopes = requested
+	}
+
+	delete(s.refreshTokens, key)
+	return s.issueTokensLocked(client.ClientID, scopes, issued.identity), nil
+}
+
+func (s *OAuthService) issueTokensLocked(clientID string, scopes []string, identity GoogleIdentity) *TokenResponse {
+	now := time.Now()
+	accessToken, refreshToken := randomToken(), randomToken()
+	s.accessTokens[hashToken(accessToken)] = &issuedToken{clientID: clientID, scopes: scopes, identity: identity, expiresAt: now.Add(accessTokenTTL)}
+	s.refreshTokens[hashToken(refreshToken)] = &issuedToken{clientID: clientID, scopes: scopes, identity: identity, expiresAt: now.Add(refreshTokenTTL)}
+
+	return &TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(accessTokenTTL / time.Second),
+		RefreshToken: refreshToken,
+		Scope:        strings.Join(scopes, " "),
+	}
+}
+
+// ValidateAccessToken returns the principal an access token was issued for.
+func (s *OAuthService) ValidateAccessToken(accessToken string) (*Principal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issued, ok := s.accessTokens[hashToken(accessToken)]
+	if !ok {
+		return nil, fmt.Errorf("unknown access token")
+	}
+	if time.Now().After(issued.expiresAt) {
+		return nil, fmt.Errorf("access token expired")
+	}
+	return &Principal{
+		Subject:  issued.identity.Subject,
+		Email:    issued.identity.Email,
+		ClientID: issued.clientID,
+		Scopes:   issued.scopes,
+	}, nil
+}
+
+// RevokeToken revokes an access or refresh token issued to client (RFC 7009).
+// Revoking a refresh token also revokes the client's access tokens for the
+// same user. Unknown tokens and tokens of other clients are ignored, as the
+// endpoint must not reveal whether a token exists. Both kinds of tokens are
+// looked up, so the token_type_hint is not needed.
+func (s *OAuthService) RevokeToken(client *OAuthClient, token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := hashToken(token)
+	if issued, ok := s.refreshTokens[key]; ok {
+		if issued.clientID == client.ClientID {
+			s.revokeLocked(func(t *issuedToken) bool {
+				return t.clientID == client.ClientID && t.identity.Subject == issued.identity.Subject
+			})
+		}
+		return
+	}
+	if issued, ok := s.accessTokens[key]; ok && issued.clientID == client.ClientID {
+		delete(s.accessTokens, key)
+	}
+}
+
+// RevokeUser revokes every token and authorization code issued for a Google
+// account, across all clients.
+func (s *OAuthService) RevokeUser(subject string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revokeLocked(func(t *issuedToken) bool { return t.identity.Subject == subject })
+	for key, code := range s.codes {
+		if code.identity.Subject == subject {
+			delete(s.codes, key)
+		}
+	}
+}
+
+// revokeLocked removes the access and refresh tokens matching match.
+func (s *OAuthService) revokeLocked(match func(*issuedToken) bool) {
+	for _, tokens := range []map[string]*issuedToken{s.accessTokens, s.refreshTokens} {
+		for key, token := range tokens {
+			if match(token) {
+				delete(tokens, key)
+			}
+		}
+	}
+}
+
+// Reaper periodically removes expired authorizations, codes and tokens.
+func (s *OAuthService) Reaper(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			for id, pending := range s.pending {
+				if now.After(pending.ExpiresAt) {
+					delete(s.pending, id)
+				}
+			}
+			for key, code := range s.codes {
+				if now.After(code.expiresAt) {
+					delete(s.codes, key)
+				}
+			}
+			for _, tokens := range []map[string]*issuedToken{s.accessTokens, s.refreshTokens} {
+				for key, token := range tokens {
+					if now.After(token.expiresAt) {
+						delete(tokens, key)
+					}
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// isResource reports whether an RFC 8707 resource indicator names this server.
+func (s *OAuthService) isResource(resource string) bool {
+	resource = strings.TrimRight(resource, "/")
+	return resource == s.serverURL || resource == s.serverURL+"/mcp"
+}
+
+// parseScopes parses a space-separated scope parameter. An empty scope
+// means the default scope.
+func parseScopes(scope string) ([]string, error) {
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		return []string{mcpScope}, nil
+	}
+	for _, sc := range scopes {
+		if sc != mcpScope {
+			return nil, oauthError("invalid_scope", "unsupported scope %q", sc)
+		}
+	}
+	return scopes, nil
+}
+
+// randomToken returns an unguessable 256-bit token.
+func randomToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Panicf("crypto/rand failed: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// hashToken is the key tokens are stored under, so a memory dump does not
+// reveal usable tokens.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
 	}
+	return false
 }
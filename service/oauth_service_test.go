@@ -0,0 +1,206 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+const testVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+func s256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func wantOAuthError(t *testing.T, err error, code string) {
+	t.Helper()
+	var oauthErr *OAuthError
+	if !errors.As(err, &oauthErr) || oauthErr.Code != code {
+		t.Fatalf("error = %v, want OAuth error %q", err, code)
+	}
+}
+
+// authorize registers a public client and runs an authorization request
+// for alice through to the authorization code.
+func authorize(t *testing.T, s *OAuthService, challenge string) (*OAuthClient, string) {
+	t.Helper()
+	client, err := s.RegisterClient(ClientRegistration{
+		RedirectURIs:            []string{"https://client.example/callback"},
+		TokenEndpointAuthMethod: "none",
+	})
+	if err != nil {
+		t.Fatalf("RegisterClient: %v", err)
+	}
+	pending, err := s.StartAuthorization(client, AuthorizationRequest{
+		ClientID:            client.ClientID,
+		RedirectURI:         "https://client.example/callback",
+		ResponseType:        "code",
+		CodeChallenge:       challenge,
+		CodeChallengeMethod: "S256",
+	})
+	if err != nil {
+		t.Fatalf("StartAuthorization: %v", err)
+	}
+	pending, err = s.TakePendingAuthorization(pending.ID)
+	if err != nil {
+		t.Fatalf("TakePendingAuthorization: %v", err)
+	}
+	return client, s.IssueAuthorizationCode(pending, GoogleIdentity{Subject: "alice", Email: "alice@example.com"})
+}
+
+func TestStartAuthorizationRequiresS256(t *testing.T) {
+	s := NewOAuthService("https://mcp.example")
+	client := &OAuthClient{ClientID: "client"}
+	base := AuthorizationRequest{ClientID: "client", ResponseType: "code", CodeChallenge: s256(testVerifier), CodeChallengeMethod: "S256"}
+
+	tests := []struct {
+		name   string
+		modify func(*AuthorizationRequest)
+		code   string
+	}{
+		{"plain method", func(r *AuthorizationRequest) { r.CodeChallengeMethod = "plain" }, "invalid_request"},
+		{"no challenge", func(r *AuthorizationRequest) { r.CodeChallenge = "" }, "invalid_request"},
+		{"token response type", func(r *AuthorizationRequest) { r.ResponseType = "token" }, "unsupported_response_type"},
+		{"unknown scope", func(r *AuthorizationRequest) { r.Scope = "admin" }, "invalid_scope"},
+		{"foreign resource", func(r *AuthorizationRequest) { r.Resource = "https://other.example" }, "invalid_target"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := base
+			tt.modify(&req)
+			_, err := s.StartAuthorization(client, req)
+			wantOAuthError(t, err, tt.code)
+		})
+	}
+
+	if _, err := s.StartAuthorization(client, base); err != nil {
+		t.Errorf("valid request rejected: %v", err)
+	}
+}
+
+func TestPendingAuthorizationSingleUse(t *testing.T) {
+	s := NewOAuthService("https://mcp.example")
+	pending := s.StartLogin()
+	if _, err := s.TakePendingAuthorization(pending.ID); err != nil {
+		t.Fatalf("TakePendingAuthorization: %v", err)
+	}
+	if _, err := s.TakePendingAuthorization(pending.ID); err == nil {
+		t.Error("a pending authorization was completed twice")
+	}
+}
+
+func TestExchangeAuthorizationCodeVerifiesPKCE(t *testing.T) {
+	s := NewOAuthService("https://mcp.example")
+
+	client, code := authorize(t, s, s256(testVerifier))
+	_, err := s.ExchangeAuthorizationCode(client, code, "", "wrong-verifier-wrong-verifier-wrong-verifier")
+	wantOAuthError(t, err, "invalid_grant")
+
+	// A failed attempt burns the code, so the verifier cannot be guessed.
+	_, err = s.ExchangeAuthorizationCode(client, code, "", testVerifier)
+	wantOAuthError(t, err, "invalid_grant")
+
+	_, err = s.ExchangeAuthorizationCode(client, "code", "", "short")
+	wantOAuthError(t, err, "invalid_request")
+}
+
+func TestExchangeAuthorizationCodeSingleUse(t *testing.T) {
+	s := NewOAuthService("https://mcp.example")
+	client, code := authorize(t, s, s256(testVerifier))
+
+	tokens, err := s.ExchangeAuthorizationCode(client, code, "https://client.example/callback", testVerifier)
+	if err != nil {
+		t.Fatalf("ExchangeAuthorizationCode: %v", err)
+	}
+	principal, err := s.ValidateAccessToken(tokens.AccessToken)
+	if err != nil || principal.Subject != "alice" || principal.ClientID != client.ClientID {
+		t.Fatalf("ValidateAccessToken = %+v, %v", principal, err)
+	}
+
+	_, err = s.ExchangeAuthorizationCode(client, code, "", testVerifier)
+	wantOAuthError(t, err, "invalid_grant")
+}
+
+func TestExchangeAuthorizationCodeChecksClientAndRedirect(t *testing.T) {
+	s := NewOAuthService("https://mcp.example")
+
+	_, code := authorize(t, s, s256(testVerifier))
+	_, err := s.ExchangeAuthorizationCode(&OAuthClient{ClientID: "other"}, code, "", testVerifier)
+	wantOAuthError(t, err, "invalid_grant")
+
+	client, code := authorize(t, s, s256(testVerifier))
+	_, err = s.ExchangeAuthorizationCode(client, code, "https://client.example/other", testVerifier)
+	wantOAuthError(t, err, "invalid_grant")
+}
+
+func TestRefreshAccessTokenRotates(t *testing.T) {
+	s := NewOAuthService("https://mcp.example")
+	client, code := authorize(t, s, s256(testVerifier))
+	first, err := s.ExchangeAuthorizationCode(client, code, "", testVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := s.RefreshAccessToken(client, first.RefreshToken, "")
+	if err != nil {
+		t.Fatalf("RefreshAccessToken: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == first.AccessToken {
+		t.Fatal("refresh did not issue new tokens")
+	}
+	if _, err := s.ValidateAccessToken(second.AccessToken); err != nil {
+		t.Errorf("new access token rejected: %v", err)
+	}
+
+	_, err = s.RefreshAccessToken(client, first.RefreshToken, "")
+	wantOAuthError(t, err, "invalid_grant")
+
+	_, err = s.RefreshAccessToken(&OAuthClient{ClientID: "other"}, second.RefreshToken, "")
+	wantOAuthError(t, err, "invalid_grant")
+
+	_, err = s.RefreshAccessToken(client, second.RefreshToken, "admin")
+	wantOAuthError(t, err, "invalid_scope")
+}
+
+func TestRevokeToken(t *testing.T) {
+	s := NewOAuthService("https://mcp.example")
+	client, code := authorize(t, s, s256(testVerifier))
+	tokens, err := s.ExchangeAuthorizationCode(client, code, "", testVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tokens of other clients and unknown tokens are silently ignored.
+	s.RevokeToken(&OAuthClient{ClientID: "other"}, tokens.RefreshToken)
+	s.RevokeToken(client, "unknown")
+	if _, err := s.ValidateAccessToken(tokens.AccessToken); err != nil {
+		t.Fatalf("token revoked by another client: %v", err)
+	}
+
+	// Revoking the refresh token also revokes its access tokens.
+	s.RevokeToken(client, tokens.RefreshToken)
+	if _, err := s.ValidateAccessToken(tokens.AccessToken); err == nil {
+		t.Error("access token still valid after its refresh token was revoked")
+	}
+	_, err = s.RefreshAccessToken(client, tokens.RefreshToken, "")
+	wantOAuthError(t, err, "invalid_grant")
+}
+
+func TestRevokeAccessTokenKeepsRefreshToken(t *testing.T) {
+	s := NewOAuthService("https://mcp.example")
+	client, code := authorize(t, s, s256(testVerifier))
+	tokens, err := s.ExchangeAuthorizationCode(client, code, "", testVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.RevokeToken(client, tokens.AccessToken)
+	if _, err := s.ValidateAccessToken(tokens.AccessToken); err == nil {
+		t.Error("access token still valid after revocation")
+	}
+	if _, err := s.RefreshAccessToken(client, tokens.RefreshToken, ""); err != nil {
+		t.Errorf("refresh token revoked along with an access token: %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+)
+
+// PageRequest describes which results a paginated call should return.
+type PageRequest struct {
+	// PageToken continues from a previous call; empty starts at the beginning.
+	PageToken string
+	// PageSize is the number of results to request per API call.
+	PageSize int64
+	// MaxTotal is the total number of results to collect, following
+	// nextPageToken across as many API calls as needed. Zero means one page.
+	MaxTotal int64
+}
+
+// ProgressFunc receives the progress of a long-running call: how many items
+// it has collected so far, and how many it will collect in all, or 0 if
+// that is not known.
+type ProgressFunc func(progress, total int64, message string)
+
+type progressKey struct{}
+
+// WithProgress returns a context whose paginated calls report their progress
+// to report after every page.
+func WithProgress(ctx context.Context, report ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, report)
+}
+
+// reportProgress reports progress to the ProgressFunc of ctx, if any.
+func reportProgress(ctx context.Context, progress, total int64, message string) {
+	if report, ok := ctx.Value(progressKey{}).(ProgressFunc); ok {
+		report(progress, total, message)
+	}
+}
+
+// collectPages calls fetch repeatedly, following page tokens, until
+// page.MaxTotal results have been collected or there are no more pages.
+// Each call asks for no more than the remaining budget, so the returned
+// page token always continues exactly after the last returned item.
+//
+// fetch returns a page of results, the token of the next page, and the
+// number of results YouTube reports for the whole listing, or 0 if it does
+// not report a usable count. Progress is reported to the ProgressFunc of ctx
+// after every page. Its total is only known when YouTube reported a count
+// and collecting started at the first page; page.MaxTotal is merely an
+// upper bound, which the results may fall short of.
+func collectPages[T any](ctx context.Context, page PageRequest, fetch func(pageToken string, size int64) ([]T, string, int64, error)) ([]T, string, error) {
+	total := page.MaxTotal
+	if total <= 0 {
+		total = page.PageSize
+	}
+
+	var items []T
+	pageToken := page.PageToken
+	for pages := 1; ; pages++ {
+		size := page.PageSize
+		if remaining := total - int64(len(items)); remaining < size {
+			size = remaining
+		}
+
+		batch, next, available, err := fetch(pageToken, size)
+		if err != nil {
+			return nil, "", err
+		}
+		items = append(items, batch...)
+		pageToken = next
+
+		var expected int64
+		if available > 0 && page.PageToken == "" {
+			expected = min(available, total)
+		}
+		message := fmt.Sprintf("Fetched page %d: %d results", pages, len(items))
+		if expected > 0 {
+			message = fmt.Sprintf("Fetched page %d: %d of %d results", pages, len(items), expected)
+		}
+		reportProgress(ctx, int64(len(items)), expected, message)
+
+		if pageToken == "" || int64(len(items)) >= total || len(batch) == 0 {
+			return items, pageToken, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, "", err
+		}
+	}
+}
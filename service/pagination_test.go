@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// pagedResults serves count numbered items in pages, like a list call,
+// reporting the count if countKnown is set.
+type pagedResults struct {
+	count      int
+	countKnown bool
+	sizes      []int64
+}
+
+func (p *pagedResults) fetch(pageToken string, size int64) ([]int, string, int64, error) {
+	p.sizes = append(p.sizes, size)
+	start := 0
+	if pageToken != "" {
+		fmt.Sscanf(pageToken, "from-%d", &start)
+	}
+	end := min(start+int(size), p.count)
+	var items []int
+	for i := start; i < end; i++ {
+		items = append(items, i)
+	}
+	next := ""
+	if end < p.count {
+		next = fmt.Sprintf("from-%d", end)
+	}
+	var available int64
+	if p.countKnown {
+		available = int64(p.count)
+	}
+	return items, next, available, nil
+}
+
+type progressReport struct {
+	progress, total int64
+}
+
+func TestCollectPages(t *testing.T) {
+	tests := []struct {
+		name         string
+		count        int
+		countKnown   bool
+		page         PageRequest
+		wantItems    int
+		wantNext     string
+		wantSizes    []int64
+		wantProgress []progressReport
+	}{
+		{
+			name:         "one page without max_total",
+			count:        100,
+			page:         PageRequest{PageSize: 20},
+			wantItems:    20,
+			wantNext:     "from-20",
+			wantSizes:    []int64{20},
+			wantProgress: []progressReport{{20, 0}},
+		},
+		{
+			name:         "stops at max_total within a page",
+			count:        100,
+			page:         PageRequest{PageSize: 20, MaxTotal: 45},
+			wantItems:    45,
+			wantNext:     "from-45",
+			wantSizes:    []int64{20, 20, 5},
+			wantProgress: []progressReport{{20, 0}, {40, 0}, {45, 0}},
+		},
+		{
+			// max_total is only a budget, so it is not reported as the total.
+			name:         "runs out of results",
+			count:        30,
+			page:         PageRequest{PageSize: 20, MaxTotal: 100},
+			wantItems:    30,
+			wantNext:     "",
+			wantSizes:    []int64{20, 20},
+			wantProgress: []progressReport{{20, 0}, {30, 0}},
+		},
+		{
+			name:         "known count below max_total",
+			count:        30,
+			countKnown:   true,
+			page:         PageRequest{PageSize: 20, MaxTotal: 100},
+			wantItems:    30,
+			wantNext:     "",
+			wantSizes:    []int64{20, 20},
+			wantProgress: []progressReport{{20, 30}, {30, 30}},
+		},
+		{
+			name:         "known count above max_total",
+			count:        100,
+			countKnown:   true,
+			page:         PageRequest{PageSize: 20, MaxTotal: 45},
+			wantItems:    45,
+			wantNext:     "from-45",
+			wantSizes:    []int64{20, 20, 5},
+			wantProgress: []progressReport{{20, 45}, {40, 45}, {45, 45}},
+		},
+		{
+			// Part of the count lies before the cursor, so it is no total.
+			name:         "continues from a page token",
+			count:        100,
+			countKnown:   true,
+			page:         PageRequest{PageToken: "from-90", PageSize: 20, MaxTotal: 50},
+			wantItems:    10,
+			wantNext:     "",
+			wantSizes:    []int64{20},
+			wantProgress: []progressReport{{10, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := &pagedResults{count: tt.count, countKnown: tt.countKnown}
+			var reports []progressReport
+			ctx := WithProgress(context.Background(), func(progress, total int64, message string) {
+				reports = append(reports, progressReport{progress, total})
+			})
+
+			items, next, err := collectPages(ctx, tt.page, results.fetch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != tt.wantItems || next != tt.wantNext {
+				t.Errorf("got %d items and next %q, want %d and %q", len(items), next, tt.wantItems, tt.wantNext)
+			}
+			if fmt.Sprint(results.sizes) != fmt.Sprint(tt.wantSizes) {
+				t.Errorf("requested page sizes %v, want %v", results.sizes, tt.wantSizes)
+			}
+			if fmt.Sprint(reports) != fmt.Sprint(tt.wantProgress) {
+				t.Errorf("reported progress %v, want %v", reports, tt.wantProgress)
+			}
+		})
+	}
+}
+
+func TestCollectPagesStopsWhenCanceled(t *testing.T) {
+	results := &pagedResults{count: 100}
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = WithProgress(ctx, func(progress, total int64, message string) {
+		cancel()
+	})
+
+	_, _, err := collectPages(ctx, PageRequest{PageSize: 10, MaxTotal: 100}, results.fetch)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if len(results.sizes) != 1 {
+		t.Errorf("fetched %d pages after the call was canceled", len(results.sizes)-1)
+	}
+}
+
+func TestCollectPagesWithoutProgress(t *testing.T) {
+	results := &pagedResults{count: 5}
+	items, _, err := collectPages(context.Background(), PageRequest{PageSize: 2, MaxTotal: 5}, results.fetch)
+	if err != nil || len(items) != 5 {
+		t.Errorf("got %d items, %v; want all 5", len(items), err)
+	}
+}
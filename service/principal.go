@@ -0,0 +1,26 @@
+package service
+
+import "context"
+
+// Principal is the authenticated user behind an MCP request: the Google
+// account the access token was issued for, and the client using it.
+type Principal struct {
+	// Subject is the stable Google account ID.
+	Subject  string
+	Email    string
+	ClientID string
+	Scopes   []string
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the authenticated principal.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated principal of the request, if any.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
@@ -0,0 +1,322 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // The quota day follows Pacific time on every platform.
+)
+
+// quotaCosts are the unit costs of the YouTube Data API methods, from
+// https://developers.google.com/youtube/v3/determine_quota_cost. Methods
+// not listed cost one unit, like all other list calls.
+var quotaCosts = map[string]int64{
+	"search.list":           100,
+	"comments.insert":       50,
+	"commentThreads.insert": 50,
+	"playlists.insert":      50,
+	"playlists.update":      50,
+	"playlists.delete":      50,
+	"playlistItems.insert":  50,
+	"playlistItems.update":  50,
+	"playlistItems.delete":  50,
+	"videos.rate":           50,
+}
+
+// quotaLocation is the time zone in which the YouTube quota resets at midnight.
+var quotaLocation = mustLoadLocation("America/Los_Angeles")
+
+// MethodUsage is the usage of one API method on the current quota day.
+type MethodUsage struct {
+	Calls int64 `json:"calls"`
+	Units int64 `json:"units"`
+	// Revalidations are the calls, included in Calls and Units, that only
+	// revalidated a cached response and were answered 304 Not Modified.
+	Revalidations int64 `json:"revalidations"`
+}
+
+// QuotaUsage is a snapshot of the quota used on the current quota day.
+type QuotaUsage struct {
+	Day       string                 `json:"day"`
+	Used      int64                  `json:"used"`
+	Budget    int64                  `json:"budget"`
+	Remaining int64                  `json:"remaining"`
+	Refused   int64                  `json:"refused"`
+	ResetsAt  time.Time              `json:"resets_at"`
+	Methods   map[string]MethodUsage `json:"methods"`
+}
+
+// QuotaExceededError is returned instead of making a call that would take
+// the day's usage over the budget.
+type QuotaExceededError struct {
+	Method   string
+	Cost     int64
+	Used     int64
+	Budget   int64
+	ResetsAt time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("Daily YouTube API quota budget reached: %s costs %d units, and %d of %d units are used today. The quota resets at %s.",
+		e.Method, e.Cost, e.Used, e.Budget, e.ResetsAt.Format("2006-01-02 15:04 MST"))
+}
+
+// quotaFile is the persisted state of a QuotaTracker.
+type quotaFile struct {
+	Day     string                  `json:"day"`
+	Used    int64                   `json:"used"`
+	Refused int64                   `json:"refused"`
+	Methods map[string]*MethodUsage `json:"methods"`
+}
+
+// QuotaTracker accounts the YouTube Data API quota used by the server and
+// refuses calls that would exceed a daily budget. The quota belongs to the
+// Google Cloud project, so one tracker covers all users and the API key.
+// Usage is kept in a file, if one is configured, so it survives restarts.
+type QuotaTracker struct {
+	budget int64
+	path   string
+	now    func() time.Time
+
+	mu       sync.Mutex
+	state    quotaFile
+	reserved int64 // Units set aside for calls in flight.
+}
+
+// NewQuotaTracker creates a tracker with the given daily budget. If path is
+// not empty, usage is loaded from and saved to that file.
+func NewQuotaTracker(budget int64, path string) (*QuotaTracker, error) {
+	return newQuotaTracker(budget, path, time.Now)
+}
+
+// newQuotaTracker creates a tracker that reads the time from now.
+func newQuotaTracker(budget int64, path string, now func() time.Time) (*QuotaTracker, error) {
+	q := &QuotaTracker{budget: budget, path: path, now: now}
+	q.state = quotaFile{Day: quotaDay(now()), Methods: map[string]*MethodUsage{}}
+	if path == "" {
+		return q, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read quota file: %w", err)
+	}
+	var state quotaFile
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse quota file: %w", err)
+	}
+	if state.Day == q.state.Day {
+		if state.Methods == nil {
+			state.Methods = map[string]*MethodUsage{}
+		}
+		q.state = state
+	}
+	return q, nil
+}
+
+// reserve sets aside the cost of a call to an API method until settle is
+// called with its outcome, or refuses the call with a *QuotaExceededError
+// if it could take the day's usage over the budget. Units reserved by calls
+// in flight count against the budget, so concurrent calls cannot overshoot
+// it together.
+func (q *QuotaTracker) reserve(method string) (int64, error) {
+	cost, ok := quotaCosts[method]
+	if !ok {
+		cost = 1
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.now()
+	q.rolloverLocked(now)
+
+	if q.budget > 0 && q.state.Used+q.reserved+cost > q.budget {
+		q.state.Refused++
+		q.saveLocked()
+		return 0, &QuotaExceededError{Method: method, Cost: cost, Used: q.state.Used, Budget: q.budget, ResetsAt: nextQuotaReset(now)}
+	}
+	q.reserved += cost
+	return cost, nil
+}
+
+// settle ends the reservation of a call and charges it if YouTube answered.
+// YouTube bills every request it answers, errors and 304 Not Modified
+// included, so those are charged in full; revalidations are counted apart.
+// A call that failed before YouTube answered, such as on a network error,
+// is not charged, although YouTube may have received it.
+func (q *QuotaTracker) settle(method string, cost int64, resp *http.Response) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.reserved -= cost
+	if resp == nil {
+		return
+	}
+	q.rolloverLocked(q.now())
+
+	q.state.Used += cost
+	usage := q.state.Methods[method]
+	if usage == nil {
+		usage = &MethodUsage{}
+		q.state.Methods[method] = usage
+	}
+	usage.Calls++
+	usage.Units += cost
+	if resp.StatusCode == http.StatusNotModified {
+		usage.Revalidations++
+	}
+	q.saveLocked()
+}
+
+// Usage returns the usage of the current quota day.
+func (q *QuotaTracker) Usage() *QuotaUsage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.now()
+	q.rolloverLocked(now)
+
+	usage := &QuotaUsage{
+		Day:      q.state.Day,
+		Used:     q.state.Used,
+		Budget:   q.budget,
+		Refused:  q.state.Refused,
+		ResetsAt: nextQuotaReset(now),
+		Methods:  make(map[string]MethodUsage, len(q.state.Methods)),
+	}
+	if q.budget > 0 {
+		usage.Remaining = max(q.budget-q.state.Used, 0)
+	}
+	for method, m := range q.state.Methods {
+		usage.Methods[method] = *m
+	}
+	return usage
+}
+
+// SortedMethods returns the methods of a usage snapshot in a stable order.
+func (u *QuotaUsage) SortedMethods() []string {
+	methods := make([]string, 0, len(u.Methods))
+	for method := range u.Methods {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// Transport returns an http.RoundTripper that charges every YouTube Data
+// API request passed on to base once it is answered. Wrapped in a retrying
+// transport, it charges each attempt, as YouTube does.
+func (q *QuotaTracker) Transport(base http.RoundTripper) http.RoundTripper {
+	return &quotaTransport{quota: q, base: base}
+}
+
+// rolloverLocked starts a new quota day once midnight Pacific time passed.
+func (q *QuotaTracker) rolloverLocked(now time.Time) {
+	if day := quotaDay(now); day != q.state.Day {
+		q.state = quotaFile{Day: day, Methods: map[string]*MethodUsage{}}
+	}
+}
+
+// saveLocked writes the usage to the quota file. Failures are logged, as
+// losing the count must not break the API call being made.
+func (q *QuotaTracker) saveLocked() {
+	if q.path == "" {
+		return
+	}
+	data, err := json.Marshal(q.state)
+	if err == nil {
+		err = writeFileAtomic(q.path, data)
+	}
+	if err != nil {
+		log.Printf("Failed to save quota usage to %s: %v", q.path, err)
+	}
+}
+
+// quotaTransport charges requests against a QuotaTracker.
+type quotaTransport struct {
+	quota *QuotaTracker
+	base  http.RoundTripper
+}
+
+func (t *quotaTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := apiMethod(req)
+	cost, err := t.quota.reserve(method)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	t.quota.settle(method, cost, resp)
+	return resp, err
+}
+
+// apiMethod names the API method of a YouTube Data API request, such as
+// "search.list" for GET /youtube/v3/search.
+func apiMethod(req *http.Request) string {
+	path := strings.TrimPrefix(req.URL.Path, "/youtube/v3/")
+	resource, action, _ := strings.Cut(path, "/")
+	if action != "" {
+		return resource + "." + action
+	}
+	switch req.Method {
+	case http.MethodPost:
+		return resource + ".insert"
+	case http.MethodPut:
+		return resource + ".update"
+	case http.MethodDelete:
+		return resource + ".delete"
+	default:
+		return resource + ".list"
+	}
+}
+
+// quotaDay returns the quota day of a point in time.
+func quotaDay(t time.Time) string {
+	return t.In(quotaLocation).Format("2006-01-02")
+}
+
+// nextQuotaReset returns the next midnight Pacific time.
+func nextQuotaReset(t time.Time) time.Time {
+	local := t.In(quotaLocation)
+	return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, quotaLocation)
+}
+
+// writeFileAtomic replaces a file by writing a temporary file in the same
+// directory and renaming it over the original.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func mustLoadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(fmt.Sprintf("failed to load time zone %s: %v", name, err))
+	}
+	return location
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testClock is a settable clock for a QuotaTracker.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+// pacific returns a time on the quota clock.
+func pacific(year int, month time.Month, day, hour, minute, second int) time.Time {
+	return time.Date(year, month, day, hour, minute, second, 0, quotaLocation)
+}
+
+// charge makes a call to method that YouTube answers.
+func charge(q *QuotaTracker, method string) error {
+	cost, err := q.reserve(method)
+	if err != nil {
+		return err
+	}
+	q.settle(method, cost, &http.Response{StatusCode: http.StatusOK})
+	return nil
+}
+
+func TestQuotaDayRollover(t *testing.T) {
+	tests := []struct {
+		name         string
+		before       time.Time
+		after        time.Time
+		wantRollover bool
+		// wantResetsAt is the reset reported after the clock moved.
+		wantResetsAt time.Time
+	}{
+		{
+			name:         "Pacific midnight",
+			before:       pacific(2026, time.June, 10, 23, 59, 59),
+			after:        pacific(2026, time.June, 11, 0, 0, 0),
+			wantRollover: true,
+			wantResetsAt: time.Date(2026, time.June, 12, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "UTC midnight",
+			before:       time.Date(2026, time.June, 10, 23, 59, 0, 0, time.UTC),
+			after:        time.Date(2026, time.June, 11, 0, 1, 0, 0, time.UTC),
+			wantRollover: false,
+			wantResetsAt: time.Date(2026, time.June, 11, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			// Clocks spring forward at 2:00, so the day lasts 23 hours.
+			name:         "start of daylight saving time",
+			before:       pacific(2026, time.March, 7, 23, 59, 59),
+			after:        pacific(2026, time.March, 8, 0, 0, 0),
+			wantRollover: true,
+			wantResetsAt: time.Date(2026, time.March, 9, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "after the spring forward",
+			before:       pacific(2026, time.March, 8, 1, 59, 0),
+			after:        pacific(2026, time.March, 8, 3, 0, 0),
+			wantRollover: false,
+			wantResetsAt: time.Date(2026, time.March, 9, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			// Clocks fall back at 2:00, so the day lasts 25 hours.
+			name:         "end of daylight saving time",
+			before:       pacific(2026, time.October, 31, 23, 59, 59),
+			after:        pacific(2026, time.November, 1, 0, 0, 0),
+			wantRollover: true,
+			wantResetsAt: time.Date(2026, time.November, 2, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "repeated hour after the fall back",
+			before:       time.Date(2026, time.November, 1, 8, 30, 0, 0, time.UTC), // 1:30 PDT
+			after:        time.Date(2026, time.November, 1, 9, 30, 0, 0, time.UTC), // 1:30 PST
+			wantRollover: false,
+			wantResetsAt: time.Date(2026, time.November, 2, 8, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &testClock{now: tt.before}
+			q, err := newQuotaTracker(0, "", clock.Now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := charge(q, "search.list"); err != nil {
+				t.Fatal(err)
+			}
+			if got := q.Usage().ResetsAt; !got.After(tt.before) {
+				t.Errorf("reset at %v is not after %v", got, tt.before)
+			}
+
+			clock.now = tt.after
+			usage := q.Usage()
+			if rolledOver := usage.Used == 0; rolledOver != tt.wantRollover {
+				t.Errorf("used %d units after moving to %v, want rollover %v", usage.Used, tt.after, tt.wantRollover)
+			}
+			if wantDay := tt.after.In(quotaLocation).Format("2006-01-02"); usage.Day != wantDay {
+				t.Errorf("day = %s, want %s", usage.Day, wantDay)
+			}
+			if !usage.ResetsAt.Equal(tt.wantResetsAt) {
+				t.Errorf("resets at %v, want %v", usage.ResetsAt.UTC(), tt.wantResetsAt)
+			}
+		})
+	}
+}
+
+func TestQuotaRefusesBeforeExceedingBudget(t *testing.T) {
+	clock := &testClock{now: pacific(2026, time.June, 10, 12, 0, 0)}
+	q, err := newQuotaTracker(201, "", clock.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := charge(q, "search.list"); err != nil {
+		t.Fatalf("first search refused: %v", err)
+	}
+
+	// A call in flight holds its units, so a concurrent one cannot use them.
+	cost, err := q.reserve("search.list")
+	if err != nil {
+		t.Fatalf("second search refused: %v", err)
+	}
+	var exceeded *QuotaExceededError
+	if err := charge(q, "search.list"); !errors.As(err, &exceeded) {
+		t.Fatalf("third search while the second is in flight: err = %v, want *QuotaExceededError", err)
+	}
+	if exceeded.Cost != 100 || exceeded.Budget != 201 || !exceeded.ResetsAt.Equal(pacific(2026, time.June, 11, 0, 0, 0)) {
+		t.Errorf("error = %+v", exceeded)
+	}
+	q.settle("search.list", cost, &http.Response{StatusCode: http.StatusOK})
+
+	// The last unit of the budget can be used, but no more.
+	if err := charge(q, "videos.list"); err != nil {
+		t.Fatalf("call using the last unit refused: %v", err)
+	}
+	if err := charge(q, "videos.list"); !errors.As(err, &exceeded) {
+		t.Fatalf("call over the budget: err = %v, want *QuotaExceededError", err)
+	}
+
+	usage := q.Usage()
+	if usage.Used != 201 || usage.Remaining != 0 || usage.Refused != 2 {
+		t.Errorf("usage = %+v, want 201 used, 0 remaining, 2 refused", usage)
+	}
+
+	// Refused calls are not made, so they cost nothing.
+	if m := usage.Methods["search.list"]; m.Calls != 2 || m.Units != 200 {
+		t.Errorf("search.list usage = %+v, want 2 calls for 200 units", m)
+	}
+}
+
+func TestQuotaPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quota.json")
+	clock := &testClock{now: pacific(2026, time.June, 10, 12, 0, 0)}
+
+	q, err := newQuotaTracker(1000, path, clock.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	charge(q, "search.list")
+	charge(q, "videos.list")
+
+	reloaded, err := newQuotaTracker(1000, path, clock.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage := reloaded.Usage()
+	if usage.Used != 101 || usage.Methods["search.list"].Calls != 1 || usage.Methods["videos.list"].Units != 1 {
+		t.Errorf("reloaded usage = %+v, want the 101 units used before", usage)
+	}
+
+	// A file from an earlier quota day is not carried over.
+	clock.now = pacific(2026, time.June, 11, 0, 0, 1)
+	nextDay, err := newQuotaTracker(1000, path, clock.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage := nextDay.Usage(); usage.Used != 0 || len(usage.Methods) != 0 {
+		t.Errorf("usage on the next day = %+v, want none", usage)
+	}
+
+	if err := os.WriteFile(path, []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newQuotaTracker(1000, path, clock.Now); err == nil {
+		t.Error("corrupt quota file accepted")
+	}
+}
+
+func TestQuotaTransportChargesAnsweredCalls(t *testing.T) {
+	failures := 1
+	api := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		switch req.URL.Query().Get("q") {
+		case "unreachable":
+			return nil, errors.New("connection refused")
+		case "flaky":
+			if failures > 0 {
+				failures--
+				return newResponse(req, http.StatusServiceUnavailable, `{"error":{"code":503,"errors":[{"reason":"backendError"}]}}`), nil
+			}
+		case "unchanged":
+			return newResponse(req, http.StatusNotModified, ""), nil
+		}
+		return newResponse(req, http.StatusOK, `{}`), nil
+	})
+	quota, err := NewQuotaTracker(0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &retryTransport{base: quota.Transport(api)}
+
+	roundTrip(t, transport, http.MethodGet, "search?q=flaky")
+	roundTrip(t, transport, http.MethodGet, "search?q=unchanged")
+	req, _ := http.NewRequest(http.MethodGet, "https://youtube.googleapis.com/youtube/v3/search?q=unreachable", nil)
+	if _, err := transport.RoundTrip(req); err == nil {
+		t.Fatal("transport error was swallowed")
+	}
+
+	usage := quota.Usage()
+	// The failed attempt and its retry are both charged, as YouTube bills
+	// both; the call that never reached YouTube is not.
+	want := MethodUsage{Calls: 3, Units: 300, Revalidations: 1}
+	if got := usage.Methods["search.list"]; got != want {
+		t.Errorf("search.list usage = %+v, want %+v", got, want)
+	}
+	if usage.Used != 300 {
+		t.Errorf("used %d units, want 300", usage.Used)
+	}
+}
+
+func TestQuotaTransportRefusesRetryOverBudget(t *testing.T) {
+	api := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return newResponse(req, http.StatusServiceUnavailable, `{"error":{"code":503,"errors":[{"reason":"backendError"}]}}`), nil
+	})
+	quota, err := NewQuotaTracker(150, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &retryTransport{base: quota.Transport(api)}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://youtube.googleapis.com/youtube/v3/search?q=go", nil)
+	var exceeded *QuotaExceededError
+	if _, err := transport.RoundTrip(req); !errors.As(err, &exceeded) {
+		t.Fatalf("err = %v, want the retry refused with *QuotaExceededError", err)
+	}
+	if usage := quota.Usage(); usage.Used != 100 || usage.Refused != 1 {
+		t.Errorf("usage = %+v, want the first attempt charged and the retry refused", usage)
+	}
+}
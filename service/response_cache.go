@@ -0,0 +1,323 @@
+package service
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheTTLs are how long a cached response of each API resource is served
+// without asking YouTube again. After that it is revalidated with its ETag.
+var cacheTTLs = map[string]time.Duration{
+	"search":         10 * time.Minute,
+	"videos":         5 * time.Minute,
+	"commentThreads": 2 * time.Minute,
+	"channels":       time.Hour,
+	"playlists":      10 * time.Minute,
+	"playlistItems":  5 * time.Minute,
+}
+
+// defaultCacheTTL applies to resources not listed in cacheTTLs.
+const defaultCacheTTL = 5 * time.Minute
+
+// cacheInvalidates lists the resources whose cached responses a write to a
+// resource makes stale, besides the resource itself.
+var cacheInvalidates = map[string][]string{
+	"playlistItems": {"playlists"}, // Playlists count their items.
+	"comments":      {"commentThreads"},
+}
+
+// CacheStats describes the contents and effectiveness of a ResponseCache.
+type CacheStats struct {
+	Entries  int   `json:"entries"`
+	Bytes    int64 `json:"bytes"`
+	MaxBytes int64 `json:"max_bytes"`
+	// Hits were answered from the cache without calling the API.
+	Hits int64 `json:"hits"`
+	// Revalidated were answered from the cache after YouTube confirmed,
+	// with a 304 Not Modified, that they had not changed.
+	Revalidated int64 `json:"revalidated"`
+	Misses      int64 `json:"misses"`
+}
+
+// cacheEntry is a cached response to a GET request.
+type cacheEntry struct {
+	key      string
+	resource string
+	header   http.Header
+	body     []byte
+	etag     string
+	expires  time.Time
+}
+
+func (e *cacheEntry) size() int64 {
+	return int64(len(e.key) + len(e.body))
+}
+
+// ResponseCache caches YouTube Data API read responses, keyed by the user
+// or API key that made the request and the request URL, which holds the API
+// method and all its arguments. Entries are served until their resource's
+// TTL passes, then revalidated with If-None-Match. The least recently used
+// entries are evicted once the cache exceeds its size.
+type ResponseCache struct {
+	maxBytes int64
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // Most recently used at the front.
+	bytes   int64
+	stats   CacheStats
+}
+
+// NewResponseCache creates a cache holding up to maxBytes of responses. A
+// size of 0 disables caching.
+func NewResponseCache(maxBytes int64) *ResponseCache {
+	return &ResponseCache{
+		maxBytes: maxBytes,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+type noCacheKey struct{}
+
+// WithoutCache returns a context whose YouTube reads skip the cache and go
+// to the API. Their responses still replace what is cached.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+// cacheBypassed reports whether the reads of a request skip the cache.
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(noCacheKey{}).(bool)
+	return bypass
+}
+
+// Stats returns the current cache statistics.
+func (c *ResponseCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	stats.Bytes = c.bytes
+	stats.MaxBytes = c.maxBytes
+	return stats
+}
+
+// Transport returns an http.RoundTripper that answers the GET requests of
+// owner from the cache and passes everything else on to base. With bypass
+// set, reads always go to base. Successful writes drop the cached responses
+// they make stale, for every owner.
+func (c *ResponseCache) Transport(owner string, bypass bool, base http.RoundTripper) http.RoundTripper {
+	if c.maxBytes <= 0 {
+		return base
+	}
+	return &cacheTransport{cache: c, owner: owner, bypass: bypass, base: base}
+}
+
+// cacheTransport serves requests of one owner from a ResponseCache.
+type cacheTransport struct {
+	cache  *ResponseCache
+	owner  string
+	bypass bool
+	base   http.RoundTripper
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resource := apiResource(req)
+	if req.Method != http.MethodGet {
+		resp, err := t.base.RoundTrip(req)
+		if err == nil && resp.StatusCode < 300 {
+			t.cache.invalidate(resource)
+		}
+		return resp, err
+	}
+	// Requests that are conditional already are the caller's business.
+	if req.Header.Get("If-None-Match") != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	key := t.owner + " " + req.URL.String()
+	var entry *cacheEntry
+	if !t.bypass {
+		entry = t.cache.lookup(key)
+	}
+	if entry != nil && time.Now().Before(entry.expires) {
+		t.cache.count(func(s *CacheStats) { s.Hits++ })
+		return entry.response(req), nil
+	}
+
+	if entry != nil && entry.etag != "" {
+		conditional := req.Clone(req.Context())
+		conditional.Header.Set("If-None-Match", entry.etag)
+		resp, err := t.base.RoundTrip(conditional)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusNotModified {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			t.cache.touch(key, time.Now().Add(cacheTTL(resource)))
+			t.cache.count(func(s *CacheStats) { s.Revalidated++ })
+			return entry.response(req), nil
+		}
+		t.cache.count(func(s *CacheStats) { s.Misses++ })
+		return t.store(key, resource, resp)
+	}
+
+	t.cache.count(func(s *CacheStats) { s.Misses++ })
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	return t.store(key, resource, resp)
+}
+
+// store caches a successful response and returns it with its body intact.
+func (t *cacheTransport) store(key, resource string, resp *http.Response) (*http.Response, error) {
+	if resp.StatusCode != http.StatusOK {
+		t.cache.remove(key)
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.cache.add(&cacheEntry{
+		key:      key,
+		resource: resource,
+		header:   resp.Header.Clone(),
+		body:     body,
+		etag:     responseETag(resp.Header, body),
+		expires:  time.Now().Add(cacheTTL(resource)),
+	})
+	return resp, nil
+}
+
+// response builds a response to req from a cached entry.
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+func (c *ResponseCache) lookup(key string) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.lru.MoveToFront(element)
+	// A copy, as touch may update the entry while the caller reads it.
+	entry := *element.Value.(*cacheEntry)
+	return &entry
+}
+
+func (c *ResponseCache) touch(key string, expires time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*cacheEntry).expires = expires
+		c.lru.MoveToFront(element)
+	}
+}
+
+func (c *ResponseCache) add(entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(entry.key)
+	if entry.size() > c.maxBytes {
+		return
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	c.bytes += entry.size()
+	for c.bytes > c.maxBytes {
+		c.removeLocked(c.lru.Back().Value.(*cacheEntry).key)
+	}
+}
+
+func (c *ResponseCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(key)
+}
+
+func (c *ResponseCache) removeLocked(key string) {
+	element, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	c.lru.Remove(element)
+	delete(c.entries, key)
+	c.bytes -= element.Value.(*cacheEntry).size()
+}
+
+// invalidate drops the cached responses a write to resource makes stale.
+func (c *ResponseCache) invalidate(resource string) {
+	stale := map[string]bool{resource: true}
+	for _, related := range cacheInvalidates[resource] {
+		stale[related] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, element := range c.entries {
+		if stale[element.Value.(*cacheEntry).resource] {
+			c.removeLocked(key)
+		}
+	}
+}
+
+func (c *ResponseCache) count(update func(*CacheStats)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	update(&c.stats)
+}
+
+// cacheTTL returns how long responses of a resource are fresh.
+func cacheTTL(resource string) time.Duration {
+	if ttl, ok := cacheTTLs[resource]; ok {
+		return ttl
+	}
+	return defaultCacheTTL
+}
+
+// apiResource names the API resource of a YouTube Data API request, such
+// as "videos" for GET /youtube/v3/videos.
+func apiResource(req *http.Request) string {
+	resource, _, _ := strings.Cut(apiMethod(req), ".")
+	return resource
+}
+
+// responseETag returns the ETag of a response: the header if YouTube sent
+// one, or else the etag every YouTube list response carries in its body.
+func responseETag(header http.Header, body []byte) string {
+	if etag := header.Get("ETag"); etag != "" {
+		return etag
+	}
+	var resource struct {
+		Etag string `json:"etag"`
+	}
+	if json.Unmarshal(body, &resource) != nil {
+		return ""
+	}
+	return resource.Etag
+}
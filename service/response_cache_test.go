@@ -0,0 +1,219 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+}
+
+// fakeYouTube answers reads with a list response whose etag is the current
+// version, and 304 Not Modified to an If-None-Match of that version.
+type fakeYouTube struct {
+	version  int
+	requests []*http.Request
+}
+
+func (f *fakeYouTube) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.requests = append(f.requests, req)
+	etag := fmt.Sprintf("v%d", f.version)
+	if req.Method != http.MethodGet {
+		return newResponse(req, http.StatusOK, `{}`), nil
+	}
+	if req.Header.Get("If-None-Match") == etag {
+		return newResponse(req, http.StatusNotModified, ""), nil
+	}
+	return newResponse(req, http.StatusOK, fmt.Sprintf(`{"etag":%q,"path":%q}`, etag, req.URL.Path)), nil
+}
+
+func roundTrip(t *testing.T, transport http.RoundTripper, method, path string) string {
+	t.Helper()
+	req, err := http.NewRequest(method, "https://youtube.googleapis.com/youtube/v3/"+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+// expire makes every cached entry stale, so the next read revalidates it.
+func (c *ResponseCache) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, element := range c.entries {
+		element.Value.(*cacheEntry).expires = time.Now().Add(-time.Second)
+	}
+}
+
+func TestResponseCacheHit(t *testing.T) {
+	api := &fakeYouTube{version: 1}
+	cache := NewResponseCache(1 << 20)
+	transport := cache.Transport("alice", false, api)
+
+	first := roundTrip(t, transport, http.MethodGet, "videos?id=a")
+	second := roundTrip(t, transport, http.MethodGet, "videos?id=a")
+	if first != second {
+		t.Errorf("cached body %q differs from %q", second, first)
+	}
+	if len(api.requests) != 1 {
+		t.Errorf("made %d API requests, want 1", len(api.requests))
+	}
+
+	// Other owners do not see alice's entries.
+	roundTrip(t, cache.Transport("bob", false, api), http.MethodGet, "videos?id=a")
+	if len(api.requests) != 2 {
+		t.Errorf("bob's read was answered from alice's cache")
+	}
+
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 2 || stats.Entries != 2 {
+		t.Errorf("stats = %+v, want 1 hit, 2 misses, 2 entries", stats)
+	}
+}
+
+func TestResponseCacheRevalidates(t *testing.T) {
+	api := &fakeYouTube{version: 1}
+	cache := NewResponseCache(1 << 20)
+	transport := cache.Transport("alice", false, api)
+
+	want := roundTrip(t, transport, http.MethodGet, "videos?id=a")
+	cache.expire()
+	if got := roundTrip(t, transport, http.MethodGet, "videos?id=a"); got != want {
+		t.Errorf("revalidated body = %q, want %q", got, want)
+	}
+	if len(api.requests) != 2 || api.requests[1].Header.Get("If-None-Match") != "v1" {
+		t.Fatalf("stale entry was not revalidated with its ETag")
+	}
+	if stats := cache.Stats(); stats.Revalidated != 1 {
+		t.Errorf("stats = %+v, want 1 revalidation", stats)
+	}
+
+	// A 304 makes the entry fresh again.
+	roundTrip(t, transport, http.MethodGet, "videos?id=a")
+	if len(api.requests) != 2 {
+		t.Errorf("revalidated entry was not served from the cache")
+	}
+
+	// A changed resource replaces the entry.
+	api.version = 2
+	cache.expire()
+	if got := roundTrip(t, transport, http.MethodGet, "videos?id=a"); !strings.Contains(got, `"v2"`) {
+		t.Errorf("changed resource served as %q", got)
+	}
+	if got := roundTrip(t, transport, http.MethodGet, "videos?id=a"); !strings.Contains(got, `"v2"`) {
+		t.Errorf("cache kept the old response: %q", got)
+	}
+}
+
+func TestResponseCacheBypass(t *testing.T) {
+	api := &fakeYouTube{version: 1}
+	cache := NewResponseCache(1 << 20)
+	cached := cache.Transport("alice", false, api)
+	bypass := cache.Transport("alice", true, api)
+
+	roundTrip(t, cached, http.MethodGet, "videos?id=a")
+	api.version = 2
+	if got := roundTrip(t, bypass, http.MethodGet, "videos?id=a"); !strings.Contains(got, `"v2"`) {
+		t.Errorf("no_cache read was answered from the cache: %q", got)
+	}
+	if api.requests[1].Header.Get("If-None-Match") != "" {
+		t.Error("no_cache read was sent as a revalidation")
+	}
+
+	// The fresh response replaces the cached one.
+	if got := roundTrip(t, cached, http.MethodGet, "videos?id=a"); !strings.Contains(got, `"v2"`) {
+		t.Errorf("cache still serves the old response: %q", got)
+	}
+	if len(api.requests) != 2 {
+		t.Errorf("made %d API requests, want 2", len(api.requests))
+	}
+}
+
+func TestResponseCacheWriteInvalidates(t *testing.T) {
+	api := &fakeYouTube{version: 1}
+	cache := NewResponseCache(1 << 20)
+	alice := cache.Transport("alice", false, api)
+	bob := cache.Transport("bob", false, api)
+
+	roundTrip(t, alice, http.MethodGet, "playlistItems?playlistId=p")
+	roundTrip(t, alice, http.MethodGet, "playlists?id=p")
+	roundTrip(t, bob, http.MethodGet, "playlistItems?playlistId=p")
+	roundTrip(t, alice, http.MethodGet, "videos?id=a")
+
+	roundTrip(t, alice, http.MethodPost, "playlistItems?part=snippet")
+
+	// Writes drop the stale entries of every owner, and no others.
+	if stats := cache.Stats(); stats.Entries != 1 {
+		t.Errorf("%d entries left after the write, want only the video", stats.Entries)
+	}
+	requests := len(api.requests)
+	roundTrip(t, alice, http.MethodGet, "videos?id=a")
+	if len(api.requests) != requests {
+		t.Error("write to playlist items dropped the cached video")
+	}
+}
+
+func TestResponseCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	api := &fakeYouTube{version: 1}
+	probe := NewResponseCache(1 << 20)
+	roundTrip(t, probe.Transport("alice", false, api), http.MethodGet, "videos?id=a")
+	entrySize := probe.Stats().Bytes
+
+	// Room for two entries of the same size.
+	cache := NewResponseCache(2*entrySize + entrySize/2)
+	transport := cache.Transport("alice", false, api)
+	roundTrip(t, transport, http.MethodGet, "videos?id=a")
+	roundTrip(t, transport, http.MethodGet, "videos?id=b")
+	roundTrip(t, transport, http.MethodGet, "videos?id=a") // a is now the most recently used.
+	roundTrip(t, transport, http.MethodGet, "videos?id=c")
+
+	stats := cache.Stats()
+	if stats.Entries != 2 || stats.Bytes > stats.MaxBytes {
+		t.Fatalf("stats = %+v, want 2 entries within the size", stats)
+	}
+	api.requests = nil
+	roundTrip(t, transport, http.MethodGet, "videos?id=a")
+	roundTrip(t, transport, http.MethodGet, "videos?id=c")
+	if len(api.requests) != 0 {
+		t.Error("evicted a recently used entry")
+	}
+	roundTrip(t, transport, http.MethodGet, "videos?id=b")
+	if len(api.requests) != 1 {
+		t.Error("least recently used entry was not evicted")
+	}
+}
+
+func TestResponseCacheDisabled(t *testing.T) {
+	api := &fakeYouTube{version: 1}
+	transport := NewResponseCache(0).Transport("alice", false, api)
+	roundTrip(t, transport, http.MethodGet, "videos?id=a")
+	roundTrip(t, transport, http.MethodGet, "videos?id=a")
+	if len(api.requests) != 2 {
+		t.Errorf("disabled cache answered %d of 2 reads", 2-len(api.requests))
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	// retryAttempts is how often a call is tried before its error is returned.
+	retryAttempts = 4
+	// retryBaseDelay is the largest delay before the first retry; it doubles
+	// with every further retry, up to retryMaxDelay.
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 8 * time.Second
+)
+
+// retryTransport retries YouTube Data API calls that failed transiently,
+// with jittered exponential backoff. Reads are retried on rate limiting and
+// backend errors. Writes are only retried on rate limiting, as YouTube may
+// have applied a write that failed with a backend error.
+type retryTransport struct {
+	base http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil || attempt == retryAttempts {
+			return resp, err
+		}
+		apiErr := responseError(resp)
+		if apiErr == nil || !apiErr.Retryable {
+			return resp, nil
+		}
+		if req.Method != http.MethodGet && apiErr.Code != ErrCodeRateLimited {
+			return resp, nil
+		}
+		if req.Body != nil && req.GetBody == nil {
+			return resp, nil // The body cannot be sent again.
+		}
+
+		delay := retryDelay(attempt, resp.Header)
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return resp, nil // Waiting would outlast the request.
+		}
+		log.Printf("YouTube API %s failed with %s (%s), retrying in %v", apiMethod(req), apiErr.Code, apiErr.Reason, delay.Round(time.Millisecond))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, nil
+		case <-timer.C:
+		}
+		resp.Body.Close()
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
+
+// responseError classifies a failed response, leaving its body readable
+// for the caller. It returns nil for successful responses.
+func responseError(resp *http.Response) *APIError {
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	return ClassifyAPIError(googleapi.CheckResponseWithBody(resp, body))
+}
+
+// retryDelay returns how long to wait before retrying after the given
+// attempt: the server's Retry-After if it sent one, or else a random delay
+// of up to retryBaseDelay doubled for every earlier attempt ("full jitter").
+func retryDelay(attempt int, header http.Header) time.Duration {
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil && seconds > 0 {
+		return min(time.Duration(seconds)*time.Second, retryMaxDelay)
+	}
+	ceiling := min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+	return time.Duration(rand.Int64N(int64(ceiling))) + time.Millisecond
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name       string
+		attempt    int
+		retryAfter string
+		// The delay must be in (min, max].
+		min, max time.Duration
+	}{
+		{"Retry-After honored", 1, "3", 3*time.Second - 1, 3 * time.Second},
+		{"Retry-After capped", 1, "60", retryMaxDelay - 1, retryMaxDelay},
+		{"Retry-After as a date ignored", 1, "Wed, 21 Oct 2026 07:28:00 GMT", 0, retryBaseDelay + time.Millisecond},
+		{"Retry-After of zero ignored", 1, "0", 0, retryBaseDelay + time.Millisecond},
+		{"first retry", 1, "", 0, retryBaseDelay + time.Millisecond},
+		{"third retry doubles twice", 3, "", 0, 4*retryBaseDelay + time.Millisecond},
+		{"late retry capped", 10, "", 0, retryMaxDelay + time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.retryAfter != "" {
+				header.Set("Retry-After", tt.retryAfter)
+			}
+			for range 100 {
+				if delay := retryDelay(tt.attempt, header); delay <= tt.min || delay > tt.max {
+					t.Fatalf("retryDelay = %v, want in (%v, %v]", delay, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+// flakyAPI fails the first failures requests with status and reason, and
+// records the bodies of all requests.
+type flakyAPI struct {
+	failures   int
+	status     int
+	reason     string
+	retryAfter string
+	bodies     []string
+}
+
+func (f *flakyAPI) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body string
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		body = string(data)
+	}
+	f.bodies = append(f.bodies, body)
+
+	if len(f.bodies) > f.failures {
+		return newResponse(req, http.StatusOK, `{}`), nil
+	}
+	resp := newResponse(req, f.status, fmt.Sprintf(`{"error":{"code":%d,"errors":[{"reason":%q}]}}`, f.status, f.reason))
+	if f.retryAfter != "" {
+		resp.Header.Set("Retry-After", f.retryAfter)
+	}
+	return resp, nil
+}
+
+func TestRetryTransport(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		status       int
+		reason       string
+		wantAttempts int
+		wantStatus   int
+	}{
+		{"read retried on backend error", http.MethodGet, http.StatusServiceUnavailable, "backendError", 2, http.StatusOK},
+		{"read retried on rate limiting", http.MethodGet, http.StatusForbidden, "rateLimitExceeded", 2, http.StatusOK},
+		{"write retried on rate limiting", http.MethodPost, http.StatusTooManyRequests, "rateLimitExceeded", 2, http.StatusOK},
+		{"write not retried on backend error", http.MethodPost, http.StatusInternalServerError, "backendError", 1, http.StatusInternalServerError},
+		{"quota exhaustion not retried", http.MethodGet, http.StatusForbidden, "quotaExceeded", 1, http.StatusForbidden},
+		{"not found not retried", http.MethodGet, http.StatusNotFound, "videoNotFound", 1, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &flakyAPI{failures: 1, status: tt.status, reason: tt.reason}
+			req, err := http.NewRequest(tt.method, "https://youtube.googleapis.com/youtube/v3/playlistItems", strings.NewReader(`{"snippet":{}}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := (&retryTransport{base: api}).RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if len(api.bodies) != tt.wantAttempts || resp.StatusCode != tt.wantStatus {
+				t.Errorf("got %d attempts ending in %d, want %d ending in %d", len(api.bodies), resp.StatusCode, tt.wantAttempts, tt.wantStatus)
+			}
+			// Every attempt sends the whole body.
+			for i, body := range api.bodies {
+				if body != `{"snippet":{}}` {
+					t.Errorf("attempt %d sent body %q", i+1, body)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryTransportGivesUp(t *testing.T) {
+	api := &flakyAPI{failures: retryAttempts + 1, status: http.StatusForbidden, reason: "rateLimitExceeded"}
+	req, _ := http.NewRequest(http.MethodGet, "https://youtube.googleapis.com/youtube/v3/videos", nil)
+
+	resp, err := (&retryTransport{base: api}).RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(api.bodies) != retryAttempts || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("got %d attempts ending in %d, want %d ending in 403", len(api.bodies), resp.StatusCode, retryAttempts)
+	}
+}
+
+func TestRetryTransportStopsAtDeadline(t *testing.T) {
+	api := &flakyAPI{failures: 1, status: http.StatusServiceUnavailable, reason: "backendError", retryAfter: "5"}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://youtube.googleapis.com/youtube/v3/videos", nil)
+
+	start := time.Now()
+	resp, err := (&retryTransport{base: api}).RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(api.bodies) != 1 || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("got %d attempts ending in %d, want the first failure", len(api.bodies), resp.StatusCode)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("waited %v for a retry the deadline did not leave time for", elapsed)
+	}
+}
+
+func TestRetryTransportStopsWhenCanceled(t *testing.T) {
+	api := &flakyAPI{failures: 1, status: http.StatusServiceUnavailable, reason: "backendError", retryAfter: "5"}
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://youtube.googleapis.com/youtube/v3/videos", nil)
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	resp, err := (&retryTransport{base: api}).RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(api.bodies) != 1 {
+		t.Errorf("retried %d times after the request was canceled", len(api.bodies)-1)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("kept waiting %v after the request was canceled", elapsed)
+	}
+}
+
+func TestRetryTransportNeedsReplayableBody(t *testing.T) {
+	api := &flakyAPI{failures: 1, status: http.StatusTooManyRequests, reason: "rateLimitExceeded"}
+	req, _ := http.NewRequest(http.MethodPost, "https://youtube.googleapis.com/youtube/v3/playlistItems", strings.NewReader(`{}`))
+	req.GetBody = nil
+
+	resp, err := (&retryTransport{base: api}).RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(api.bodies) != 1 || resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("retried a request whose body cannot be sent again")
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxThumbnailBytes caps the size of a fetched thumbnail. The largest
+// (maxres) video thumbnails are well below this.
+const maxThumbnailBytes = 2 << 20
+
+var thumbnailClient = &http.Client{Timeout: 10 * time.Second}
+
+// Thumbnail is an image fetched from YouTube's image servers.
+type Thumbnail struct {
+	Data     []byte
+	MimeType string
+}
+
+// FetchThumbnail downloads a thumbnail returned by the API. Only YouTube's
+// image hosts are contacted, so the server cannot be used to fetch arbitrary URLs.
+func (s *YouTubeService) FetchThumbnail(ctx context.Context, thumbnailURL string) (*Thumbnail, error) {
+	u, err := url.Parse(thumbnailURL)
+	if err != nil || u.Scheme != "https" || !isThumbnailHost(u.Hostname()) {
+		return nil, fmt.Errorf("not a YouTube thumbnail URL: %q", thumbnailURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create thumbnail request: %w", err)
+	}
+	resp, err := thumbnailClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch thumbnail: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch thumbnail: %s", resp.Status)
+	}
+	mimeType := strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0])
+	if !strings.HasPrefix(mimeType, "image/") {
+		return nil, fmt.Errorf("thumbnail has unexpected content type %q", mimeType)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxThumbnailBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read thumbnail: %w", err)
+	}
+	if len(data) > maxThumbnailBytes {
+		return nil, fmt.Errorf("thumbnail is larger than %d bytes", maxThumbnailBytes)
+	}
+
+	return &Thumbnail{Data: data, MimeType: mimeType}, nil
+}
+
+func isThumbnailHost(host string) bool {
+	host = strings.ToLower(host)
+	return strings.HasSuffix(host, ".ytimg.com") || strings.HasSuffix(host, ".ggpht.com")
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// ErrChannelNotFound is returned when a channel reference does not match any channel.
+var ErrChannelNotFound = errors.New("channel not found")
+
+// ErrInvalidChannelRef is returned when input is not a channel ID, handle
+// or youtube.com channel URL.
+var ErrInvalidChannelRef = errors.New("invalid channel reference")
+
+var channelIDPattern = regexp.MustCompile(`^UC[A-Za-z0-9_-]{22}$`)
+
+// ChannelRefKind identifies how a channel is referenced.
+type ChannelRefKind string
+
+const (
+	ChannelRefID       ChannelRefKind = "id"
+	ChannelRefHandle   ChannelRefKind = "handle"
+	ChannelRefUsername ChannelRefKind = "username"
+	// ChannelRefCustom is a legacy /c/name or bare youtube.com/name URL,
+	// which the API cannot look up directly.
+	ChannelRefCustom ChannelRefKind = "custom"
+)
+
+// ChannelRef is a parsed reference to a channel.
+type ChannelRef struct {
+	Kind  ChannelRefKind
+	Value string
+}
+
+// ChannelQuery selects the channel to fetch in GetChannel. Exactly one field must be set.
+type ChannelQuery struct {
+	ID       string
+	Handle   string
+	Username string
+	Mine     bool
+}
+
+// ParseChannelRef parses a channel ID, an @handle, or any youtube.com
+// channel URL (/channel/ID, /@handle, /user/name, /c/name or /name).
+func ParseChannelRef(input string) (ChannelRef, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return ChannelRef{}, fmt.Errorf("%w: channel reference is empty", ErrInvalidChannelRef)
+	}
+	if channelIDPattern.MatchString(input) {
+		return ChannelRef{Kind: ChannelRefID, Value: input}, nil
+	}
+	if strings.HasPrefix(input, "@") {
+		return ChannelRef{Kind: ChannelRefHandle, Value: input}, nil
+	}
+
+	raw := input
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ChannelRef{}, fmt.Errorf("%w: not a channel ID, handle or URL: %q", ErrInvalidChannelRef, input)
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	host = strings.TrimPrefix(host, "m.")
+	if host != "youtube.com" && host != "music.youtube.com" {
+		return ChannelRef{}, fmt.Errorf("%w: not a youtube.com URL: %q", ErrInvalidChannelRef, input)
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) == 0 || segments[0] == "" {
+		return ChannelRef{}, fmt.Errorf("%w: URL does not point to a channel: %q", ErrInvalidChannelRef, input)
+	}
+
+	first, second := segments[0], ""
+	if len(segments) > 1 {
+		second = segments[1]
+	}
+
+	switch {
+	case strings.HasPrefix(first, "@"):
+		return ChannelRef{Kind: ChannelRefHandle, Value: first}, nil
+	case first == "channel" && channelIDPattern.MatchString(second):
+		return ChannelRef{Kind: ChannelRefID, Value: second}, nil
+	case first == "user" && second != "":
+		return ChannelRef{Kind: ChannelRefUsername, Value: second}, nil
+	case first == "c" && second != "":
+		return ChannelRef{Kind: ChannelRefCustom, Value: second}, nil
+	case isReservedPath(first):
+		return ChannelRef{}, fmt.Errorf("%w: URL does not point to a channel: %q", ErrInvalidChannelRef, input)
+	default:
+		return ChannelRef{Kind: ChannelRefCustom, Value: first}, nil
+	}
+}
+
+// isReservedPath reports whether a first path segment is a YouTube page
+// rather than a legacy vanity channel name.
+func isReservedPath(segment string) bool {
+	switch segment {
+	case "watch", "shorts", "playlist", "results", "feed", "live", "embed", "channel", "user", "c", "hashtag", "post":
+		return true
+	}
+	return false
+}
+
+// GetChannel retrieves a channel by ID, handle, legacy username, or the
+// authenticated user's own channel, which needs a Google login.
+func (s *YouTubeService) GetChannel(ctx context.Context, query ChannelQuery) (*youtube.Channel, error) {
+	if !query.Mine {
+		return read(ctx, s, func(youtubeService *youtube.Service) (*youtube.Channel, error) {
+			return getChannel(ctx, youtubeService, query)
+		})
+	}
+
+	youtubeService, err := s.userClient(ctx, ScopeReadOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get YouTube service: %w", err)
+	}
+	return getChannel(ctx, youtubeService, query)
+}
+
+func getChannel(ctx context.Context, youtubeService *youtube.Service, query ChannelQuery) (*youtube.Channel, error) {
+	call := youtubeService.Channels.List([]string{"snippet", "statistics", "contentDetails", "brandingSettings"})
+	switch {
+	case query.ID != "":
+		call.Id(query.ID)
+	case query.Handle != "":
+		call.ForHandle(query.Handle)
+	case query.Username != "":
+		call.ForUsername(query.Username)
+	case query.Mine:
+		call.Mine(true)
+	default:
+		return nil, fmt.Errorf("one of channel ID, handle, username or mine is required")
+	}
+
+	response, err := call.Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel: %w", err)
+	}
+	if len(response.Items) == 0 {
+		return nil, ErrChannelNotFound
+	}
+
+	return response.Items[0], nil
+}
+
+// ResolveChannel resolves a channel ID, @handle or youtube.com channel URL
+// to the channel it refers to.
+func (s *YouTubeService) ResolveChannel(ctx context.Context, input string) (*youtube.Channel, error) {
+	ref, err := ParseChannelRef(input)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ref.Kind {
+	case ChannelRefID:
+		return s.GetChannel(ctx, ChannelQuery{ID: ref.Value})
+	case ChannelRefHandle:
+		return s.GetChannel(ctx, ChannelQuery{Handle: ref.Value})
+	case ChannelRefUsername:
+		return s.GetChannel(ctx, ChannelQuery{Username: ref.Value})
+	}
+
+	// Legacy custom URLs have no lookup of their own. Most of them were
+	// migrated to a handle of the same name, and some match a username.
+	channel, err := s.GetChannel(ctx, ChannelQuery{Handle: "@" + ref.Value})
+	if !errors.Is(err, ErrChannelNotFound) {
+		return channel, err
+	}
+	return s.GetChannel(ctx, ChannelQuery{Username: ref.Value})
+}
+
+// ListChannelUploads lists a channel's uploaded videos, newest first, by
+// paging through the channel's uploads playlist.
+func (s *YouTubeService) ListChannelUploads(ctx context.Context, channelID string, page PageRequest) (*PlaylistItemPage, error) {
+	return read(ctx, s, func(youtubeService *youtube.Service) (*PlaylistItemPage, error) {
+		return s.listChannelUploads(ctx, youtubeService, channelID, page)
+	})
+}
+
+func (s *YouTubeService) listChannelUploads(ctx context.Context, youtubeService *youtube.Service, channelID string, page PageRequest) (*PlaylistItemPage, error) {
+	channels, err := youtubeService.Channels.List([]string{"contentDetails"}).Id(channelID).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel: %w", err)
+	}
+	if len(channels.Items) == 0 {
+		return nil, ErrChannelNotFound
+	}
+
+	details := channels.Items[0].ContentDetails
+	if details == nil || details.RelatedPlaylists == nil || details.RelatedPlaylists.Uploads == "" {
+		return nil, fmt.Errorf("channel %s has no uploads playlist", channelID)
+	}
+
+	return s.listPlaylistItems(ctx, youtubeService, details.RelatedPlaylists.Uploads, page)
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseChannelRef(t *testing.T) {
+	const id = "UC_x5XG1OV2P6uZZ5FSM9Ttw"
+
+	tests := []struct {
+		input   string
+		want    ChannelRef
+		wantErr bool
+	}{
+		{input: id, want: ChannelRef{Kind: ChannelRefID, Value: id}},
+		{input: "  " + id + "  ", want: ChannelRef{Kind: ChannelRefID, Value: id}},
+		{input: "@GoogleDevelopers", want: ChannelRef{Kind: ChannelRefHandle, Value: "@GoogleDevelopers"}},
+		{input: "https://www.youtube.com/@GoogleDevelopers/videos", want: ChannelRef{Kind: ChannelRefHandle, Value: "@GoogleDevelopers"}},
+		{input: "https://www.youtube.com/channel/" + id, want: ChannelRef{Kind: ChannelRefID, Value: id}},
+		{input: "youtube.com/channel/" + id + "/featured", want: ChannelRef{Kind: ChannelRefID, Value: id}},
+		{input: "https://m.youtube.com/user/GoogleDevelopers", want: ChannelRef{Kind: ChannelRefUsername, Value: "GoogleDevelopers"}},
+		{input: "https://www.youtube.com/c/GoogleDevelopers", want: ChannelRef{Kind: ChannelRefCustom, Value: "GoogleDevelopers"}},
+		{input: "https://youtube.com/GoogleDevelopers", want: ChannelRef{Kind: ChannelRefCustom, Value: "GoogleDevelopers"}},
+		{input: "https://music.youtube.com/channel/" + id, want: ChannelRef{Kind: ChannelRefID, Value: id}},
+
+		{input: "", wantErr: true},
+		{input: "https://vimeo.com/GoogleDevelopers", wantErr: true},
+		{input: "https://youtube.com.evil.example/@GoogleDevelopers", wantErr: true},
+		{input: "https://www.youtube.com/", wantErr: true},
+		{input: "https://www.youtube.com/watch?v=dQw4w9WgXcQ", wantErr: true},
+		{input: "https://www.youtube.com/channel/not-an-id", wantErr: true},
+		{input: "https://www.youtube.com/user/", wantErr: true},
+		{input: "https://youtube.com/%zz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseChannelRef(tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidChannelRef) {
+					t.Fatalf("ParseChannelRef(%q) error = %v, want ErrInvalidChannelRef", tt.input, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseChannelRef(%q): %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseChannelRef(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
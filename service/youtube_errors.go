@@ -0,0 +1,108 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/api/googleapi"
+)
+
+// ErrorCode is a machine-readable classification of a failed YouTube call.
+type ErrorCode string
+
+const (
+	ErrCodeQuotaExceeded    ErrorCode = "quota_exceeded"
+	ErrCodeRateLimited      ErrorCode = "rate_limited"
+	ErrCodeCommentsDisabled ErrorCode = "comments_disabled"
+	ErrCodeNotFound         ErrorCode = "not_found"
+	ErrCodeForbidden        ErrorCode = "forbidden"
+	ErrCodeUnauthorized     ErrorCode = "unauthorized"
+	ErrCodeInvalidArgument  ErrorCode = "invalid_argument"
+	ErrCodeBackendError     ErrorCode = "backend_error"
+	ErrCodeUnknown          ErrorCode = "api_error"
+)
+
+// errorReasons maps the reasons the YouTube Data API reports in its errors
+// to codes. Reasons not listed are classified by their HTTP status.
+var errorReasons = map[string]ErrorCode{
+	"quotaExceeded":           ErrCodeQuotaExceeded,
+	"dailyLimitExceeded":      ErrCodeQuotaExceeded,
+	"rateLimitExceeded":       ErrCodeRateLimited,
+	"userRateLimitExceeded":   ErrCodeRateLimited,
+	"commentsDisabled":        ErrCodeCommentsDisabled,
+	"videoNotFound":           ErrCodeNotFound,
+	"channelNotFound":         ErrCodeNotFound,
+	"playlistNotFound":        ErrCodeNotFound,
+	"playlistItemNotFound":    ErrCodeNotFound,
+	"commentNotFound":         ErrCodeNotFound,
+	"commentThreadNotFound":   ErrCodeNotFound,
+	"forbidden":               ErrCodeForbidden,
+	"insufficientPermissions": ErrCodeForbidden,
+	"authError":               ErrCodeUnauthorized,
+	"backendError":            ErrCodeBackendError,
+	"internalError":           ErrCodeBackendError,
+}
+
+// APIError is a failed YouTube Data API call, classified.
+type APIError struct {
+	Code ErrorCode
+	// Status is the HTTP status of the response.
+	Status int
+	// Reason is the reason YouTube gave, such as "videoNotFound".
+	Reason string
+	// Retryable reports whether the same call may succeed later.
+	Retryable bool
+	Err       *googleapi.Error
+}
+
+func (e *APIError) Error() string {
+	if e.Err.Message != "" {
+		return e.Err.Message
+	}
+	return http.StatusText(e.Status)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyAPIError returns the classified YouTube Data API error in the
+// chain of err, or nil if there is none.
+func ClassifyAPIError(err error) *APIError {
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) {
+		return nil
+	}
+	apiErr := &APIError{Status: gerr.Code, Err: gerr}
+	if len(gerr.Errors) > 0 {
+		apiErr.Reason = gerr.Errors[0].Reason
+	}
+
+	if code, ok := errorReasons[apiErr.Reason]; ok {
+		apiErr.Code = code
+	} else {
+		apiErr.Code = statusErrorCode(gerr.Code)
+	}
+	apiErr.Retryable = apiErr.Code == ErrCodeRateLimited || apiErr.Code == ErrCodeBackendError
+	return apiErr
+}
+
+// statusErrorCode classifies an error without a known reason by its HTTP
+// status.
+func statusErrorCode(status int) ErrorCode {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return ErrCodeRateLimited
+	case status == http.StatusNotFound:
+		return ErrCodeNotFound
+	case status == http.StatusForbidden:
+		return ErrCodeForbidden
+	case status == http.StatusUnauthorized:
+		return ErrCodeUnauthorized
+	case status == http.StatusBadRequest:
+		return ErrCodeInvalidArgument
+	case status >= 500:
+		return ErrCodeBackendError
+	}
+	return ErrCodeUnknown
+}
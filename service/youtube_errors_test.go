@@ -0,0 +1,71 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/api/googleapi"
+)
+
+func TestClassifyAPIError(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		reason        string
+		wantCode      ErrorCode
+		wantRetryable bool
+	}{
+		{"quotaExceeded", http.StatusForbidden, "quotaExceeded", ErrCodeQuotaExceeded, false},
+		{"dailyLimitExceeded", http.StatusForbidden, "dailyLimitExceeded", ErrCodeQuotaExceeded, false},
+		{"rateLimitExceeded", http.StatusForbidden, "rateLimitExceeded", ErrCodeRateLimited, true},
+		{"userRateLimitExceeded", http.StatusForbidden, "userRateLimitExceeded", ErrCodeRateLimited, true},
+		{"commentsDisabled", http.StatusForbidden, "commentsDisabled", ErrCodeCommentsDisabled, false},
+		{"videoNotFound", http.StatusNotFound, "videoNotFound", ErrCodeNotFound, false},
+		{"playlistNotFound", http.StatusNotFound, "playlistNotFound", ErrCodeNotFound, false},
+		{"forbidden", http.StatusForbidden, "forbidden", ErrCodeForbidden, false},
+		{"insufficientPermissions", http.StatusForbidden, "insufficientPermissions", ErrCodeForbidden, false},
+		{"authError", http.StatusUnauthorized, "authError", ErrCodeUnauthorized, false},
+		{"backendError", http.StatusInternalServerError, "backendError", ErrCodeBackendError, true},
+		{"internalError", http.StatusInternalServerError, "internalError", ErrCodeBackendError, true},
+
+		{"unknown reason by status 429", http.StatusTooManyRequests, "somethingNew", ErrCodeRateLimited, true},
+		{"unknown reason by status 400", http.StatusBadRequest, "invalidParameter", ErrCodeInvalidArgument, false},
+		{"no reason 404", http.StatusNotFound, "", ErrCodeNotFound, false},
+		{"no reason 503", http.StatusServiceUnavailable, "", ErrCodeBackendError, true},
+		{"no reason 409", http.StatusConflict, "", ErrCodeUnknown, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gerr := &googleapi.Error{Code: tt.status, Message: "message"}
+			if tt.reason != "" {
+				gerr.Errors = []googleapi.ErrorItem{{Reason: tt.reason}}
+			}
+
+			apiErr := ClassifyAPIError(fmt.Errorf("failed to get video: %w", gerr))
+			if apiErr == nil {
+				t.Fatal("wrapped googleapi error not classified")
+			}
+			if apiErr.Code != tt.wantCode || apiErr.Retryable != tt.wantRetryable {
+				t.Errorf("got code %s, retryable %v; want %s, %v", apiErr.Code, apiErr.Retryable, tt.wantCode, tt.wantRetryable)
+			}
+			if apiErr.Status != tt.status || apiErr.Reason != tt.reason {
+				t.Errorf("got status %d, reason %q", apiErr.Status, apiErr.Reason)
+			}
+			if !errors.Is(apiErr, gerr) {
+				t.Error("classified error does not unwrap to the googleapi error")
+			}
+		})
+	}
+}
+
+func TestClassifyAPIErrorIgnoresOtherErrors(t *testing.T) {
+	if apiErr := ClassifyAPIError(errors.New("connection refused")); apiErr != nil {
+		t.Errorf("classified a non-API error as %+v", apiErr)
+	}
+	if apiErr := ClassifyAPIError(nil); apiErr != nil {
+		t.Errorf("classified nil as %+v", apiErr)
+	}
+}
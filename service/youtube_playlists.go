@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/youtube/v3"
+)
+
+// PlaylistUpdate holds the playlist fields to change. Nil fields are left as they are.
+type PlaylistUpdate struct {
+	Title       *string
+	Description *string
+	Privacy     *string
+}
+
+// CreatePlaylist creates a new playlist on the authenticated user's channel.
+func (s *YouTubeService) CreatePlaylist(ctx context.Context, title string, description string, privacy string) (*youtube.Playlist, error) {
+	youtubeService, err := s.userClient(ctx, ScopeManage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get YouTube service: %w", err)
+	}
+
+	playlist := &youtube.Playlist{
+		Snippet: &youtube.PlaylistSnippet{
+			Title:       title,
+			Description: description,
+		},
+		Status: &youtube.PlaylistStatus{
+			PrivacyStatus: privacy,
+		},
+	}
+
+	response, err := youtubeService.Playlists.Insert([]string{"snippet", "status"}, playlist).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create playlist: %w", err)
+	}
+
+	return response, nil
+}
+
+// UpdatePlaylist changes the title, description or privacy of a playlist.
+// This is an owner-only action.
+func (s *YouTubeService) UpdatePlaylist(ctx context.Context, playlistID string, update PlaylistUpdate) (*youtube.Playlist, error) {
+	youtubeService, err := s.userClient(ctx, ScopeManage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get YouTube service: %w", err)
+	}
+
+	if err := s.ensureOwnsPlaylist(ctx, youtubeService, playlistID); err != nil {
+		return nil, err
+	}
+
+	// playlists.update replaces the whole snippet and status, so start from
+	// the current values and only overwrite what was asked for.
+	current, err := youtubeService.Playlists.List([]string{"snippet", "status"}).Id(playlistID).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up playlist: %w", err)
+	}
+	if len(current.Items) == 0 {
+		return nil, fmt.Errorf("playlist %s not found", playlistID)
+	}
+	existing := current.Items[0]
+
+	playlist := &youtube.Playlist{
+		Id: playlistID,
+		Snippet: &youtube.PlaylistSnippet{
+			Title:           existing.Snippet.Title,
+			Description:     existing.Snippet.Description,
+			DefaultLanguage: existing.Snippet.DefaultLanguage,
+		},
+		Status: &youtube.PlaylistStatus{
+			PrivacyStatus: existing.Status.PrivacyStatus,
+		},
+	}
+	if update.Title != nil {
+		playlist.Snippet.Title = *update.Title
+	}
+	if update.Description != nil {
+		playlist.Snippet.Description = *update.Description
+		// An empty description must be sent explicitly to clear it.
+		playlist.Snippet.ForceSendFields = []string{"Description"}
+	}
+	if update.Privacy != nil {
+		playlist.Status.PrivacyStatus = *update.Privacy
+	}
+
+	response, err := youtubeService.Playlists.Update([]string{"snippet", "status"}, playlist).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update playlist: %w", err)
+	}
+
+	return response, nil
+}
+
+// DeletePlaylist deletes a playlist. This is an owner-only action.
+func (s *YouTubeService) DeletePlaylist(ctx context.Context, playlistID string) error {
+	youtubeService, err := s.userClient(ctx, ScopeManage)
+	if err != nil {
+		return fmt.Errorf("failed to get YouTube service: %w", err)
+	}
+
+	if err := s.ensureOwnsPlaylist(ctx, youtubeService, playlistID); err != nil {
+		return err
+	}
+
+	if err := youtubeService.Playlists.Delete(playlistID).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to delete playlist: %w", err)
+	}
+
+	return nil
+}
+
+// ListMyPlaylists lists the playlists of the authenticated user's channel.
+func (s *YouTubeService) ListMyPlaylists(ctx context.Context, pageToken string, limit int64) (*youtube.PlaylistListResponse, error) {
+	youtubeService, err := s.userClient(ctx, ScopeReadOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get YouTube service: %w", err)
+	}
+
+	call := youtubeService.Playlists.List([]string{"id", "snippet", "status", "contentDetails"}).Mine(true).MaxResults(limit)
+	if pageToken != "" {
+		call.PageToken(pageToken)
+	}
+
+	response, err := call.Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list playlists: %w", err)
+	}
+
+	return response, nil
+}
+
+// PlaylistItemPage is one or more pages of playlist items.
+type PlaylistItemPage struct {
+	Items         []*youtube.PlaylistItem
+	NextPageToken string
+	// TotalResults is the number of items in the playlist.
+	TotalResults int64
+}
+
+// ListPlaylistItems lists the items of a playlist.
+// Logged-in users read it with their own token, so their private playlists
+// can be listed too. If their token turns out not to work, public
+// playlists are still listed with the API key.
+func (s *YouTubeService) ListPlaylistItems(ctx context.Context, playlistID string, page PageRequest) (*PlaylistItemPage, error) {
+	return read(ctx, s, func(youtubeService *youtube.Service) (*PlaylistItemPage, error) {
+		return s.listPlaylistItems(ctx, youtubeService, playlistID, page)
+	})
+}
+
+func (s *YouTubeService) listPlaylistItems(ctx context.Context, youtubeService *youtube.Service, playlistID string, page PageRequest) (*PlaylistItemPage, error) {
+	result := &PlaylistItemPage{}
+	items, next, err := collectPages(ctx, page, func(pageToken string, size int64) ([]*youtube.PlaylistItem, string, int64, error) {
+		call := youtubeService.PlaylistItems.List([]string{"id", "snippet", "contentDetails"}).PlaylistId(playlistID).MaxResults(size)
+		if pageToken != "" {
+			call.PageToken(pageToken)
+		}
+
+		response, err := call.Context(ctx).Do()
+		if err != nil {
+			return nil, "", 0, fmt.Errorf("failed to list playlist items: %w", err)
+		}
+		if response.PageInfo != nil {
+			result.TotalResults = response.PageInfo.TotalResults
+		}
+		return response.Items, response.NextPageToken, result.TotalResults, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Items = items
+	result.NextPageToken = next
+	return result, nil
+}
+
+// RemovePlaylistItem removes an item from a playlist.
+// This is an owner-only action.
+func (s *YouTubeService) RemovePlaylistItem(ctx context.Context, playlistItemID string) error {
+	youtubeService, err := s.userClient(ctx, ScopeManage)
+	if err != nil {
+		return fmt.Errorf("failed to get YouTube service: %w", err)
+	}
+
+	if _, err := s.ownedPlaylistItem(ctx, youtubeService, playlistItemID); err != nil {
+		return err
+	}
+
+	if err := youtubeService.PlaylistItems.Delete(playlistItemID).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to remove playlist item: %w", err)
+	}
+
+	return nil
+}
+
+// MovePlaylistItem moves a playlist item to a new zero-based position.
+// This is an owner-only action.
+func (s *YouTubeService) MovePlaylistItem(ctx context.Context, playlistItemID string, position int64) (*youtube.PlaylistItem, error) {
+	youtubeService, err := s.userClient(ctx, ScopeManage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get YouTube service: %w", err)
+	}
+
+	item, err := s.ownedPlaylistItem(ctx, youtubeService, playlistItemID)
+	if err != nil {
+		return nil, err
+	}
+
+	playlistItem := &youtube.PlaylistItem{
+		Id: playlistItemID,
+		Snippet: &youtube.PlaylistItemSnippet{
+			PlaylistId: item.Snippet.PlaylistId,
+			ResourceId: item.Snippet.ResourceId,
+			Position:   position,
+			// Position 0 is meaningful, so it must not be omitted.
+			ForceSendFields: []string{"Position"},
+		},
+	}
+
+	response, err := youtubeService.PlaylistItems.Update([]string{"snippet"}, playlistItem).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to move playlist item: %w", err)
+	}
+
+	return response, nil
+}
+
+// ownedPlaylistItem looks up a playlist item and checks that its playlist
+// belongs to the authenticated user.
+func (s *YouTubeService) ownedPlaylistItem(ctx context.Context, youtubeService *youtube.Service, playlistItemID string) (*youtube.PlaylistItem, error) {
+	response, err := youtubeService.PlaylistItems.List([]string{"snippet"}).Id(playlistItemID).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up playlist item: %w", err)
+	}
+	if len(response.Items) == 0 {
+		return nil, fmt.Errorf("playlist item %s not found", playlistItemID)
+	}
+
+	item := response.Items[0]
+	if err := s.ensureOwnsPlaylist(ctx, youtubeService, item.Snippet.PlaylistId); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
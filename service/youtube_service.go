@@ -2,83 +2,328 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"net/http"
+	"sync"
 
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi/transport"
+	"google.golang.org/api/option"
 	"google.golang.org/api/youtube/v3"
 )
 
+// ErrNotOwner is returned when an owner-only action targets a video or
+// playlist that does not belong to the authenticated channel.
+var ErrNotOwner = errors.New("the authenticated channel does not own this resource")
+
 type YouTubeService struct {
 	googleOAuth *GoogleOAuthService
+	// apiKey, if set, is used to read public data for users without a
+	// usable Google login.
+	apiKey string
+	quota  *QuotaTracker
+	cache  *ResponseCache
+	// endpoint, if set, replaces the YouTube Data API endpoint in tests.
+	endpoint string
+
+	clientsMu sync.Mutex
+	clients   map[clientKey]*pooledClient
+}
+
+// clientKey identifies a pooled client: whose cache entries it uses, and
+// whether it reads past the cache.
+type clientKey struct {
+	cacheOwner  string
+	bypassCache bool
+}
+
+// pooledClient is a YouTube client kept for reuse, with the token source it
+// was built on; nil for the API key.
+type pooledClient struct {
+	source  oauth2.TokenSource
+	service *youtube.Service
 }
 
-func NewYouTubeService(googleOAuth *GoogleOAuthService) *YouTubeService {
+func NewYouTubeService(googleOAuth *GoogleOAuthService, apiKey string, quota *QuotaTracker, cache *ResponseCache) *YouTubeService {
 	return &YouTubeService{
 		googleOAuth: googleOAuth,
+		apiKey:      apiKey,
+		quota:       quota,
+		cache:       cache,
+		clients:     make(map[clientKey]*pooledClient),
 	}
 }
 
-// SearchVideos searches for videos on YouTube.
-func (s *YouTubeService) SearchVideos(ctx context.Context, query string, channelID string, limit int64) (*youtube.SearchListResponse, error) {
-	youtubeService, err := s.googleOAuth.GetYouTubeService(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get YouTube service: %w", err)
+// apiKeyCacheOwner keys the cached responses of reads made with the API key.
+const apiKeyCacheOwner = "key"
+
+// QuotaUsage returns the YouTube Data API quota used today.
+func (s *YouTubeService) QuotaUsage() *QuotaUsage {
+	return s.quota.Usage()
+}
+
+// HasAPIKey reports whether public data can be read without a Google login.
+func (s *YouTubeService) HasAPIKey() bool {
+	return s.apiKey != ""
+}
+
+// HasUserAccess reports whether the request has a user with a stored Google
+// token, who can use every tool.
+func (s *YouTubeService) HasUserAccess(ctx context.Context) bool {
+	principal, ok := PrincipalFromContext(ctx)
+	return ok && s.googleOAuth.HasToken(principal.Subject)
+}
+
+// AccountStatus returns the Google account connected for the request.
+func (s *YouTubeService) AccountStatus(ctx context.Context) *AccountStatus {
+	return s.googleOAuth.Status(ctx)
+}
+
+// Logout disconnects the user's Google account, as GoogleOAuthService.Logout
+// does, and drops the YouTube clients pooled for the user.
+func (s *YouTubeService) Logout(ctx context.Context, userID string) error {
+	err := s.googleOAuth.Logout(ctx, userID)
+	s.forgetClients("user:" + userID)
+	return err
+}
+
+// publicClient returns a client for reading public data. It uses the API
+// key if one is configured, and the user's token otherwise.
+func (s *YouTubeService) publicClient(ctx context.Context) (*youtube.Service, error) {
+	if s.apiKey == "" {
+		return s.userClient(ctx, ScopeReadOnly)
 	}
+	return s.pooledClient(clientKey{apiKeyCacheOwner, cacheBypassed(ctx)}, nil, func() http.RoundTripper {
+		return &transport.APIKey{Key: s.apiKey, Transport: http.DefaultTransport}
+	})
+}
 
-	call := youtubeService.Search.List([]string{"id", "snippet"}).Q(query).Type("video").MaxResults(limit)
-	if channelID != "" {
-		call.ChannelId(channelID)
+// readClient returns a client for reading data, public or the user's own,
+// such as their private playlists. It reads with the user's token, and
+// reports so, unless the token is unusable because it was rejected by
+// Google or lacks read access; the API key is used then, and for users who
+// are not logged in.
+func (s *YouTubeService) readClient(ctx context.Context) (*youtube.Service, bool, error) {
+	if s.HasUserAccess(ctx) {
+		youtubeService, err := s.userClient(ctx, ScopeReadOnly)
+		if err == nil || s.apiKey == "" {
+			return youtubeService, true, err
+		}
+		log.Printf("Reading public data with the API key, as the user's token cannot be used: %v", err)
 	}
+	youtubeService, err := s.publicClient(ctx)
+	return youtubeService, false, err
+}
 
-	response, err := call.Do()
+// read makes a read with the client of readClient. If the user's token is
+// rejected during the read, it is made again with the API key.
+func read[T any](ctx context.Context, s *YouTubeService, do func(*youtube.Service) (T, error)) (T, error) {
+	youtubeService, asUser, err := s.readClient(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search videos: %w", err)
+		var zero T
+		return zero, fmt.Errorf("failed to get YouTube service: %w", err)
 	}
 
-	return response, nil
+	result, err := do(youtubeService)
+	if err != nil && asUser && s.apiKey != "" && tokenRejected(err) {
+		if youtubeService, err := s.publicClient(ctx); err == nil {
+			return do(youtubeService)
+		}
+	}
+	return result, err
 }
 
-// GetVideoMetadata retrieves detailed information about a specific video.
-func (s *YouTubeService) GetVideoMetadata(ctx context.Context, videoID string) (*youtube.VideoListResponse, error) {
-	youtubeService, err := s.googleOAuth.GetYouTubeService(ctx)
+// tokenRejected reports whether a call made with a user's token failed
+// because the token no longer works: Google refused to refresh it, or the
+// API did not accept it.
+func tokenRejected(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		return true
+	}
+	apiErr := ClassifyAPIError(err)
+	return apiErr != nil && apiErr.Code == ErrCodeUnauthorized
+}
+
+// userClient returns a client authenticated as the principal of the
+// request, which must have granted scope. Clients for changing the user's
+// account read past the cache, so ownership checks see the current state.
+func (s *YouTubeService) userClient(ctx context.Context, scope string) (*youtube.Service, error) {
+	tokenSource, err := s.googleOAuth.UserTokenSource(ctx, scope)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get YouTube service: %w", err)
+		var scopeErr *ScopeRequiredError
+		if principal, ok := PrincipalFromContext(ctx); ok && !errors.As(err, &scopeErr) {
+			// The user logged out or must log in again.
+			s.forgetClients("user:" + principal.Subject)
+		}
+		return nil, err
 	}
+	principal, _ := PrincipalFromContext(ctx)
+	key := clientKey{"user:" + principal.Subject, scope != ScopeReadOnly || cacheBypassed(ctx)}
+	return s.pooledClient(key, tokenSource, func() http.RoundTripper {
+		return &oauth2.Transport{Source: tokenSource, Base: http.DefaultTransport}
+	})
+}
 
-	call := youtubeService.Videos.List([]string{"snippet", "statistics", "contentDetails"}).Id(videoID)
+// pooledClient returns the pooled client for key, creating it with the
+// authorization of auth if there is none yet, or if the user's token source
+// changed because they logged in again.
+func (s *YouTubeService) pooledClient(key clientKey, source oauth2.TokenSource, auth func() http.RoundTripper) (*youtube.Service, error) {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	if pooled, ok := s.clients[key]; ok && pooled.source == source {
+		return pooled.service, nil
+	}
 
-	response, err := call.Do()
+	youtubeService, err := s.newClient(key, auth())
 	if err != nil {
-		return nil, fmt.Errorf("failed to get video metadata: %w", err)
+		return nil, err
 	}
+	s.clients[key] = &pooledClient{source: source, service: youtubeService}
+	return youtubeService, nil
+}
 
-	return response, nil
+// forgetClients drops the pooled clients of a cache owner.
+func (s *YouTubeService) forgetClients(cacheOwner string) {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	for key := range s.clients {
+		if key.cacheOwner == cacheOwner {
+			delete(s.clients, key)
+		}
+	}
 }
 
-// GetVideoComments retrieves top-level comment threads for a video.
-func (s *YouTubeService) GetVideoComments(ctx context.Context, videoID string, sortBy string, limit int64) (*youtube.CommentThreadListResponse, error) {
-	youtubeService, err := s.googleOAuth.GetYouTubeService(ctx)
+// newClient creates a YouTube client whose requests are authorized by auth,
+// charged against the daily quota, and retried if they fail transiently.
+// Every attempt YouTube answers is charged. Reads are answered from the
+// cache entries of the key's owner, unless the key bypasses the cache.
+func (s *YouTubeService) newClient(key clientKey, auth http.RoundTripper) (*youtube.Service, error) {
+	client := &http.Client{Transport: s.cache.Transport(key.cacheOwner, key.bypassCache, &retryTransport{base: s.quota.Transport(auth)})}
+	options := []option.ClientOption{option.WithHTTPClient(client)}
+	if s.endpoint != "" {
+		options = append(options, option.WithEndpoint(s.endpoint))
+	}
+	youtubeService, err := youtube.NewService(context.Background(), options...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get YouTube service: %w", err)
+		return nil, fmt.Errorf("failed to create YouTube service: %w", err)
 	}
+	return youtubeService, nil
+}
 
-	call := youtubeService.CommentThreads.List([]string{"snippet", "replies"}).VideoId(videoID).Order(sortBy).MaxResults(limit)
+// SearchPage is one or more pages of search results.
+type SearchPage struct {
+	Items         []*youtube.SearchResult
+	NextPageToken string
+	TotalResults  int64
+}
 
-	response, err := call.Do()
+// SearchVideos searches for videos on YouTube.
+func (s *YouTubeService) SearchVideos(ctx context.Context, query string, channelID string, page PageRequest) (*SearchPage, error) {
+	return read(ctx, s, func(youtubeService *youtube.Service) (*SearchPage, error) {
+		return searchVideos(ctx, youtubeService, query, channelID, page)
+	})
+}
+
+func searchVideos(ctx context.Context, youtubeService *youtube.Service, query string, channelID string, page PageRequest) (*SearchPage, error) {
+	result := &SearchPage{}
+	items, next, err := collectPages(ctx, page, func(pageToken string, size int64) ([]*youtube.SearchResult, string, int64, error) {
+		call := youtubeService.Search.List([]string{"id", "snippet"}).Q(query).Type("video").MaxResults(size)
+		if channelID != "" {
+			call.ChannelId(channelID)
+		}
+		if pageToken != "" {
+			call.PageToken(pageToken)
+		}
+
+		response, err := call.Context(ctx).Do()
+		if err != nil {
+			return nil, "", 0, fmt.Errorf("failed to search videos: %w", err)
+		}
+		if response.PageInfo != nil {
+			result.TotalResults = response.PageInfo.TotalResults
+		}
+		return response.Items, response.NextPageToken, result.TotalResults, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get video comments: %w", err)
+		return nil, err
 	}
 
-	return response, nil
+	result.Items = items
+	result.NextPageToken = next
+	return result, nil
+}
+
+// GetVideoMetadata retrieves detailed information about a specific video.
+func (s *YouTubeService) GetVideoMetadata(ctx context.Context, videoID string) (*youtube.VideoListResponse, error) {
+	return read(ctx, s, func(youtubeService *youtube.Service) (*youtube.VideoListResponse, error) {
+		call := youtubeService.Videos.List([]string{"snippet", "statistics", "contentDetails"}).Id(videoID)
+
+		response, err := call.Context(ctx).Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get video metadata: %w", err)
+		}
+
+		return response, nil
+	})
+}
+
+// CommentPage is one or more pages of comment threads.
+type CommentPage struct {
+	Items         []*youtube.CommentThread
+	NextPageToken string
+}
+
+// GetVideoComments retrieves top-level comment threads for a video.
+func (s *YouTubeService) GetVideoComments(ctx context.Context, videoID string, sortBy string, page PageRequest) (*CommentPage, error) {
+	return read(ctx, s, func(youtubeService *youtube.Service) (*CommentPage, error) {
+		return getVideoComments(ctx, youtubeService, videoID, sortBy, page)
+	})
+}
+
+func getVideoComments(ctx context.Context, youtubeService *youtube.Service, videoID string, sortBy string, page PageRequest) (*CommentPage, error) {
+	items, next, err := collectPages(ctx, page, func(pageToken string, size int64) ([]*youtube.CommentThread, string, int64, error) {
+		call := youtubeService.CommentThreads.List([]string{"snippet", "replies"}).VideoId(videoID).Order(sortBy).MaxResults(size)
+		if pageToken != "" {
+			call.PageToken(pageToken)
+		}
+
+		response, err := call.Context(ctx).Do()
+		if err != nil {
+			return nil, "", 0, fmt.Errorf("failed to get video comments: %w", err)
+		}
+		// The totalResults of comment threads only counts the page.
+		return response.Items, response.NextPageToken, 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CommentPage{Items: items, NextPageToken: next}, nil
 }
 
 // ReplyToComment posts a reply to a specific comment.
-// This is an owner-only action.
+// This is an owner-only action: the comment must be on one of the
+// authenticated user's videos.
 func (s *YouTubeService) ReplyToComment(ctx context.Context, parentID string, text string) (*youtube.Comment, error) {
-	youtubeService, err := s.googleOAuth.GetYouTubeService(ctx)
+	youtubeService, err := s.userClient(ctx, ScopeManage)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get YouTube service: %w", err)
 	}
 
+	threads, err := youtubeService.CommentThreads.List([]string{"snippet"}).Id(parentID).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up comment: %w", err)
+	}
+	if len(threads.Items) == 0 {
+		return nil, fmt.Errorf("comment %s not found or is not a top-level comment", parentID)
+	}
+	if err := s.ensureOwnsVideo(ctx, youtubeService, threads.Items[0].Snippet.VideoId); err != nil {
+		return nil, err
+	}
+
 	comment := &youtube.Comment{
 		Snippet: &youtube.CommentSnippet{
 			ParentId:     parentID,
@@ -87,7 +332,7 @@ func (s *YouTubeService) ReplyToComment(ctx context.Context, parentID string, te
 	}
 
 	call := youtubeService.Comments.Insert([]string{"snippet"}, comment)
-	response, err := call.Do()
+	response, err := call.Context(ctx).Do()
 	if err != nil {
 		return nil, fmt.Errorf("failed to post comment: %w", err)
 	}
@@ -96,13 +341,18 @@ func (s *YouTubeService) ReplyToComment(ctx context.Context, parentID string, te
 }
 
 // AddVideoToPlaylist adds a video to a specific playlist.
-// This is an owner-only action.
+// This is an owner-only action: the playlist must belong to the
+// authenticated user's channel.
 func (s *YouTubeService) AddVideoToPlaylist(ctx context.Context, playlistID string, videoID string) (*youtube.PlaylistItem, error) {
-	youtubeService, err := s.googleOAuth.GetYouTubeService(ctx)
+	youtubeService, err := s.userClient(ctx, ScopeManage)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get YouTube service: %w", err)
 	}
 
+	if err := s.ensureOwnsPlaylist(ctx, youtubeService, playlistID); err != nil {
+		return nil, err
+	}
+
 	playlistItem := &youtube.PlaylistItem{
 		Snippet: &youtube.PlaylistItemSnippet{
 			PlaylistId: playlistID,
@@ -114,10 +364,66 @@ func (s *YouTubeService) AddVideoToPlaylist(ctx context.Context, playlistID stri
 	}
 
 	call := youtubeService.PlaylistItems.Insert([]string{"snippet"}, playlistItem)
-	response, err := call.Do()
+	response, err := call.Context(ctx).Do()
 	if err != nil {
 		return nil, fmt.Errorf("failed to add video to playlist: %w", err)
 	}
 
 	return response, nil
 }
+
+// myChannelIDs returns the IDs of the channels owned by the authenticated user.
+func (s *YouTubeService) myChannelIDs(ctx context.Context, youtubeService *youtube.Service) (map[string]bool, error) {
+	response, err := youtubeService.Channels.List([]string{"id"}).Mine(true).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list your channels: %w", err)
+	}
+
+	ids := make(map[string]bool, len(response.Items))
+	for _, channel := range response.Items {
+		ids[channel.Id] = true
+	}
+	return ids, nil
+}
+
+// ensureOwnsVideo returns ErrNotOwner unless the video belongs to one of the
+// authenticated user's channels.
+func (s *YouTubeService) ensureOwnsVideo(ctx context.Context, youtubeService *youtube.Service, videoID string) error {
+	response, err := youtubeService.Videos.List([]string{"snippet"}).Id(videoID).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to look up video: %w", err)
+	}
+	if len(response.Items) == 0 {
+		return fmt.Errorf("video %s not found", videoID)
+	}
+
+	mine, err := s.myChannelIDs(ctx, youtubeService)
+	if err != nil {
+		return err
+	}
+	if owner := response.Items[0].Snippet.ChannelId; !mine[owner] {
+		return fmt.Errorf("%w: video %s belongs to channel %s", ErrNotOwner, videoID, owner)
+	}
+	return nil
+}
+
+// ensureOwnsPlaylist returns ErrNotOwner unless the playlist belongs to one
+// of the authenticated user's channels.
+func (s *YouTubeService) ensureOwnsPlaylist(ctx context.Context, youtubeService *youtube.Service, playlistID string) error {
+	response, err := youtubeService.Playlists.List([]string{"snippet"}).Id(playlistID).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("failed to look up playlist: %w", err)
+	}
+	if len(response.Items) == 0 {
+		return fmt.Errorf("playlist %s not found", playlistID)
+	}
+
+	mine, err := s.myChannelIDs(ctx, youtubeService)
+	if err != nil {
+		return err
+	}
+	if owner := response.Items[0].Snippet.ChannelId; !mine[owner] {
+		return fmt.Errorf("%w: playlist %s belongs to channel %s", ErrNotOwner, playlistID, owner)
+	}
+	return nil
+}
@@ -0,0 +1,449 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+)
+
+func newTestYouTubeService(t *testing.T, store TokenStore, apiKey string) *YouTubeService {
+	t.Helper()
+	quota, err := NewQuotaTracker(0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	google := NewGoogleOAuthService(store, "client", "secret", "http://localhost:8080/oauth/callback")
+	return NewYouTubeService(google, apiKey, quota, NewResponseCache(0))
+}
+
+func TestReadClientFallsBackToAPIKey(t *testing.T) {
+	usable := &StoredToken{Token: &oauth2.Token{AccessToken: "a", RefreshToken: "r"}, Scopes: []string{ScopeReadOnly}}
+	rejected := &StoredToken{Token: &oauth2.Token{AccessToken: "a", RefreshToken: "r"}, Scopes: []string{ScopeReadOnly}, RefreshError: "revoked"}
+	noRead := &StoredToken{Token: &oauth2.Token{AccessToken: "a", RefreshToken: "r"}, Scopes: []string{"openid"}}
+
+	tests := []struct {
+		name       string
+		token      *StoredToken
+		apiKey     string
+		wantAsUser bool
+		wantErr    bool
+	}{
+		{name: "usable token", token: usable, apiKey: "key", wantAsUser: true},
+		{name: "rejected token", token: rejected, apiKey: "key", wantAsUser: false},
+		{name: "missing scope", token: noRead, apiKey: "key", wantAsUser: false},
+		{name: "not logged in", token: nil, apiKey: "key", wantAsUser: false},
+		{name: "rejected token without key", token: rejected, apiKey: "", wantAsUser: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &InMemoryTokenStore{}
+			if tt.token != nil {
+				store.SetToken("alice", tt.token)
+			}
+			s := newTestYouTubeService(t, store, tt.apiKey)
+			ctx := WithPrincipal(context.Background(), &Principal{Subject: "alice"})
+
+			_, asUser, err := s.readClient(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readClient error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if asUser != tt.wantAsUser {
+				t.Errorf("readClient used the user's token = %v, want %v", asUser, tt.wantAsUser)
+			}
+		})
+	}
+}
+
+// youtubeStub is a YouTube Data API server that records who made each
+// request: "key" for the API key, or the user whose token was sent. It
+// answers every read with an empty list, but for channels, and refuses
+// the tokens of users in rejected.
+type youtubeStub struct {
+	rejected map[string]bool
+
+	mu       sync.Mutex
+	callers  []string
+	requests []*http.Request
+}
+
+func newYouTubeStub(t *testing.T, s *YouTubeService, rejected ...string) *youtubeStub {
+	t.Helper()
+	stub := &youtubeStub{rejected: map[string]bool{}}
+	for _, user := range rejected {
+		stub.rejected[user] = true
+	}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	s.endpoint = server.URL + "/"
+	return stub
+}
+
+func (f *youtubeStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	caller := "key"
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		caller = strings.TrimSuffix(token, "-access")
+	}
+	f.mu.Lock()
+	f.callers = append(f.callers, caller)
+	f.requests = append(f.requests, r)
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if f.rejected[caller] {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"code":401,"message":"Invalid Credentials","errors":[{"reason":"authError"}]}}`)
+		return
+	}
+	if r.Method != http.MethodGet {
+		fmt.Fprint(w, `{}`)
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, "/channels") {
+		fmt.Fprint(w, `{"items":[{"id":"UCxxxxxxxxxxxxxxxxxxxxxx","contentDetails":{"relatedPlaylists":{"uploads":"UUxxxxxxxxxxxxxxxxxxxxxx"}}}]}`)
+		return
+	}
+	fmt.Fprint(w, `{"items":[]}`)
+}
+
+// distinctCallers returns who made the stub's requests, in order, without
+// repeating the same caller twice in a row.
+func (f *youtubeStub) distinctCallers() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var callers []string
+	for _, caller := range f.callers {
+		if len(callers) == 0 || callers[len(callers)-1] != caller {
+			callers = append(callers, caller)
+		}
+	}
+	return callers
+}
+
+func TestPublicReadsUseReadClient(t *testing.T) {
+	reads := map[string]func(context.Context, *YouTubeService) error{
+		"search": func(ctx context.Context, s *YouTubeService) error {
+			_, err := s.SearchVideos(ctx, "go", "", PageRequest{PageSize: 5})
+			return err
+		},
+		"video metadata": func(ctx context.Context, s *YouTubeService) error {
+			_, err := s.GetVideoMetadata(ctx, "v")
+			return err
+		},
+		"comments": func(ctx context.Context, s *YouTubeService) error {
+			_, err := s.GetVideoComments(ctx, "v", "relevance", PageRequest{PageSize: 5})
+			return err
+		},
+		"channel": func(ctx context.Context, s *YouTubeService) error {
+			_, err := s.GetChannel(ctx, ChannelQuery{Handle: "@go"})
+			return err
+		},
+		"channel uploads": func(ctx context.Context, s *YouTubeService) error {
+			_, err := s.ListChannelUploads(ctx, "UCxxxxxxxxxxxxxxxxxxxxxx", PageRequest{PageSize: 5})
+			return err
+		},
+		"playlist items": func(ctx context.Context, s *YouTubeService) error {
+			_, err := s.ListPlaylistItems(ctx, "PL", PageRequest{PageSize: 5})
+			return err
+		},
+	}
+
+	// alice's token works, dave's is refused by YouTube, and bob has none.
+	users := []struct {
+		user        string
+		wantCallers []string
+	}{
+		{user: "alice", wantCallers: []string{"alice"}},
+		{user: "dave", wantCallers: []string{"dave", "key"}},
+		{user: "bob", wantCallers: []string{"key"}},
+	}
+
+	for name, read := range reads {
+		for _, u := range users {
+			t.Run(name+"/"+u.user, func(t *testing.T) {
+				store := &InMemoryTokenStore{}
+				store.SetToken("alice", userToken("alice", ScopeReadOnly))
+				store.SetToken("dave", userToken("dave", ScopeReadOnly))
+				s := newTestYouTubeService(t, store, "key")
+				stub := newYouTubeStub(t, s, "dave")
+
+				if err := read(asUser(u.user), s); err != nil {
+					t.Fatal(err)
+				}
+				if got := stub.distinctCallers(); strings.Join(got, ",") != strings.Join(u.wantCallers, ",") {
+					t.Errorf("read by %v, want %v", got, u.wantCallers)
+				}
+			})
+		}
+	}
+}
+
+func TestTokenRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"refresh rejected", fmt.Errorf("Get: %w", &oauth2.RetrieveError{ErrorCode: "invalid_grant"}), true},
+		{"unauthorized", fmt.Errorf("failed: %w", &googleapi.Error{Code: http.StatusUnauthorized}), true},
+		{"not found", &googleapi.Error{Code: http.StatusNotFound}, false},
+		{"network", fmt.Errorf("dial tcp: connection refused"), false},
+	}
+	for _, tt := range tests {
+		if got := tokenRejected(tt.err); got != tt.want {
+			t.Errorf("%s: tokenRejected = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUserClientIsolatesUsers(t *testing.T) {
+	store := &InMemoryTokenStore{}
+	store.SetToken("alice", userToken("alice", ScopeReadOnly))
+	store.SetToken("carol", userToken("carol", ScopeReadOnly))
+	s := newTestYouTubeService(t, store, "key")
+
+	alice, err := s.userClient(asUser("alice"), ScopeReadOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	carol, err := s.userClient(asUser("carol"), ScopeReadOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice == carol {
+		t.Error("alice and carol share a YouTube client")
+	}
+
+	// bob has no token, so gets no user client at all.
+	if client, err := s.userClient(asUser("bob"), ScopeReadOnly); err == nil || client != nil {
+		t.Fatalf("bob without a stored token got client %p, err %v", client, err)
+	}
+	public, err := s.publicClient(asUser("bob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if public == alice || public == carol {
+		t.Error("bob's public reads use a user's client")
+	}
+
+	for key, pooled := range s.clients {
+		if key.cacheOwner == "user:bob" {
+			t.Errorf("a client was pooled for bob: %+v", pooled)
+		}
+	}
+}
+
+func pooledOwners(s *YouTubeService) map[string]bool {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	owners := map[string]bool{}
+	for key := range s.clients {
+		owners[key.cacheOwner] = true
+	}
+	return owners
+}
+
+func TestPooledClients(t *testing.T) {
+	store := &InMemoryTokenStore{}
+	store.SetToken("alice", userToken("alice", ScopeReadOnly))
+	store.SetToken("carol", userToken("carol", ScopeReadOnly))
+	s := newTestYouTubeService(t, store, "key")
+
+	first, err := s.userClient(asUser("alice"), ScopeReadOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := s.userClient(asUser("alice"), ScopeReadOnly); again != first {
+		t.Error("repeated calls built a new client")
+	}
+	if bypass, _ := s.userClient(WithoutCache(asUser("alice")), ScopeReadOnly); bypass == first {
+		t.Error("reads past the cache share the cached client")
+	}
+	publicFirst, _ := s.publicClient(context.Background())
+	if publicAgain, _ := s.publicClient(context.Background()); publicAgain != publicFirst {
+		t.Error("repeated API key calls built a new client")
+	}
+
+	// A new login brings a new token source, and so a new client.
+	relogin := userToken("alice", ScopeReadOnly)
+	relogin.Token.RefreshToken = "alice-refresh-2"
+	store.SetToken("alice", relogin)
+	if after, _ := s.userClient(asUser("alice"), ScopeReadOnly); after == first {
+		t.Error("a new login kept the client of the old one")
+	}
+
+	// A logout drops the user's clients, and only theirs.
+	s.userClient(asUser("carol"), ScopeReadOnly)
+	if err := store.SetToken("alice", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.userClient(asUser("alice"), ScopeReadOnly); err == nil {
+		t.Fatal("logged out user got a client")
+	}
+	owners := pooledOwners(s)
+	if owners["user:alice"] || !owners["user:carol"] || !owners[apiKeyCacheOwner] {
+		t.Errorf("pooled clients after alice logged out: %v", owners)
+	}
+
+	// So does a token Google rejected.
+	rejected := userToken("carol", ScopeReadOnly)
+	rejected.RefreshError = "revoked"
+	store.SetToken("carol", rejected)
+	if _, err := s.userClient(asUser("carol"), ScopeReadOnly); err == nil {
+		t.Fatal("user with a rejected token got a client")
+	}
+	if owners := pooledOwners(s); owners["user:carol"] {
+		t.Errorf("pooled clients after carol's token was rejected: %v", owners)
+	}
+}
+
+func TestLogoutForgetsClients(t *testing.T) {
+	store := &InMemoryTokenStore{}
+	store.SetToken("alice", userToken("alice", ScopeReadOnly))
+	store.SetToken("carol", userToken("carol", ScopeReadOnly))
+	s := newTestYouTubeService(t, store, "key")
+	newRevokeEndpoint(t, s.googleOAuth)
+
+	s.userClient(asUser("alice"), ScopeReadOnly)
+	s.userClient(WithoutCache(asUser("alice")), ScopeReadOnly)
+	s.userClient(asUser("carol"), ScopeReadOnly)
+
+	if err := s.Logout(context.Background(), "alice"); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if owners := pooledOwners(s); owners["user:alice"] || !owners["user:carol"] {
+		t.Errorf("pooled clients after alice logged out: %v", owners)
+	}
+	if store.GetToken("alice") != nil || store.GetToken("carol") == nil {
+		t.Error("logout did not remove exactly alice's token")
+	}
+}
+
+// newOwnershipStub serves a YouTube Data API on which alice owns channel
+// UCalice and its playlist PLmine, and carol owns playlist PLother, its item
+// ITother and video Vother, on which comment Cother was left. It records the
+// writes it is sent.
+func newOwnershipStub(t *testing.T, s *YouTubeService) *[]string {
+	t.Helper()
+	bodies := map[string]string{
+		"channels":              `{"items":[{"id":"UCalice"}]}`,
+		"playlists?PLmine":      `{"items":[{"id":"PLmine","snippet":{"channelId":"UCalice","title":"Mine"},"status":{"privacyStatus":"private"}}]}`,
+		"playlists?PLother":     `{"items":[{"id":"PLother","snippet":{"channelId":"UCcarol","title":"Carol's"},"status":{"privacyStatus":"public"}}]}`,
+		"playlistItems?ITother": `{"items":[{"id":"ITother","snippet":{"playlistId":"PLother","resourceId":{"kind":"youtube#video","videoId":"Vother"}}}]}`,
+		"commentThreads?Cother": `{"items":[{"id":"Cother","snippet":{"videoId":"Vother"}}]}`,
+		"videos?Vother":         `{"items":[{"id":"Vother","snippet":{"channelId":"UCcarol"}}]}`,
+	}
+	var writes []string
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		method := apiMethod(r)
+		if r.Method != http.MethodGet {
+			mu.Lock()
+			writes = append(writes, method)
+			mu.Unlock()
+			fmt.Fprint(w, `{}`)
+			return
+		}
+		resource := strings.TrimSuffix(method, ".list")
+		body, ok := bodies[resource+"?"+r.URL.Query().Get("id")]
+		if !ok {
+			body, ok = bodies[resource]
+		}
+		if !ok {
+			body = `{"items":[]}`
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	s.endpoint = server.URL + "/"
+	return &writes
+}
+
+func TestOwnerOnlyActionsRefuseOthersResources(t *testing.T) {
+	title := "Renamed"
+	actions := []struct {
+		name string
+		call func(ctx context.Context, s *YouTubeService) error
+		// write is the method sent once ownership is confirmed.
+		write string
+	}{
+		{"add video to playlist", func(ctx context.Context, s *YouTubeService) error {
+			_, err := s.AddVideoToPlaylist(ctx, "PLother", "v")
+			return err
+		}, "playlistItems.insert"},
+		{"update playlist", func(ctx context.Context, s *YouTubeService) error {
+			_, err := s.UpdatePlaylist(ctx, "PLother", PlaylistUpdate{Title: &title})
+			return err
+		}, "playlists.update"},
+		{"delete playlist", func(ctx context.Context, s *YouTubeService) error {
+			return s.DeletePlaylist(ctx, "PLother")
+		}, "playlists.delete"},
+		{"remove playlist item", func(ctx context.Context, s *YouTubeService) error {
+			return s.RemovePlaylistItem(ctx, "ITother")
+		}, "playlistItems.delete"},
+		{"move playlist item", func(ctx context.Context, s *YouTubeService) error {
+			_, err := s.MovePlaylistItem(ctx, "ITother", 0)
+			return err
+		}, "playlistItems.update"},
+		{"reply to comment", func(ctx context.Context, s *YouTubeService) error {
+			_, err := s.ReplyToComment(ctx, "Cother", "Thanks!")
+			return err
+		}, "comments.insert"},
+	}
+
+	for _, action := range actions {
+		t.Run(action.name, func(t *testing.T) {
+			store := &InMemoryTokenStore{}
+			store.SetToken("alice", userToken("alice", ScopeReadOnly, ScopeManage))
+			s := newTestYouTubeService(t, store, "")
+			writes := newOwnershipStub(t, s)
+
+			err := action.call(asUser("alice"), s)
+			if !errors.Is(err, ErrNotOwner) {
+				t.Fatalf("err = %v, want ErrNotOwner", err)
+			}
+			if len(*writes) != 0 {
+				t.Errorf("sent %v for a resource alice does not own", *writes)
+			}
+			usage := s.QuotaUsage()
+			if _, charged := usage.Methods[action.write]; charged {
+				t.Errorf("the refused %s was charged: %+v", action.write, usage.Methods)
+			}
+			for method, m := range usage.Methods {
+				if m.Units != m.Calls {
+					t.Errorf("%s was charged %d units for %d lookups", method, m.Units, m.Calls)
+				}
+			}
+		})
+	}
+}
+
+func TestOwnerOnlyActionsWriteOwnResources(t *testing.T) {
+	store := &InMemoryTokenStore{}
+	store.SetToken("alice", userToken("alice", ScopeReadOnly, ScopeManage))
+	s := newTestYouTubeService(t, store, "")
+	writes := newOwnershipStub(t, s)
+
+	if _, err := s.AddVideoToPlaylist(asUser("alice"), "PLmine", "v"); err != nil {
+		t.Fatalf("AddVideoToPlaylist: %v", err)
+	}
+	if err := s.DeletePlaylist(asUser("alice"), "PLmine"); err != nil {
+		t.Fatalf("DeletePlaylist: %v", err)
+	}
+	want := []string{"playlistItems.insert", "playlists.delete"}
+	if strings.Join(*writes, ",") != strings.Join(want, ",") {
+		t.Errorf("sent writes %v, want %v", *writes, want)
+	}
+	if m := s.QuotaUsage().Methods["playlistItems.insert"]; m.Calls != 1 || m.Units != 50 {
+		t.Errorf("playlistItems.insert usage = %+v, want one call for 50 units", m)
+	}
+}